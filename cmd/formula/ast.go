@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/DTrader-store/formula-go/lexer"
+	"github.com/DTrader-store/formula-go/parser"
+	"github.com/DTrader-store/formula-go/parser/ast"
+)
+
+// astCommand prints the syntax tree of a formula as JSON
+func astCommand(args []string, s *streams) int {
+	flags := newFlags("ast", "file.fml", s)
+	withSpans := flags.Bool("spans", false, "include the source range of every statement and expression")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	src, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return failf(s, exitUsage, "%v", err)
+	}
+	tokens, err := lexer.NewLexer(string(src)).Tokenize()
+	if err != nil {
+		return failf(s, exitProblem, "%s: %v", flags.Arg(0), err)
+	}
+	p := parser.NewParser(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		return failf(s, exitProblem, "%s: %v", flags.Arg(0), err)
+	}
+
+	var spans map[ast.Node]ast.Span
+	if *withSpans {
+		spans = p.Spans()
+	}
+	encoder := json.NewEncoder(s.stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(tree(reflect.ValueOf(program), spans)); err != nil {
+		return failf(s, exitUsage, "%v", err)
+	}
+	return exitOK
+}
+
+// tree converts a syntax tree value to JSON values: nodes become objects with their type and
+// fields in lowerCamelCase, and a span when spans has one
+func tree(v reflect.Value, spans map[ast.Node]ast.Span) any {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		if node, ok := v.Interface().(ast.Node); ok {
+			fields := object(reflect.Indirect(v.Elem()), spans)
+			fields["type"] = string(node.Type())
+			if span, ok := spans[node]; ok {
+				fields["span"] = tree(reflect.ValueOf(span), nil)
+			}
+			return fields
+		}
+		return tree(v.Elem(), spans)
+	case reflect.Struct:
+		return object(v, spans)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = tree(v.Index(i), spans)
+		}
+		return items
+	}
+	return v.Interface()
+}
+
+// object converts the exported fields of a struct, leaving out nil pointers
+func object(v reflect.Value, spans map[ast.Node]ast.Span) map[string]any {
+	fields := make(map[string]any)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := v.Field(i)
+		if !field.IsExported() || value.Kind() == reflect.Pointer && value.IsNil() {
+			continue
+		}
+		fields[lowerFirst(field.Name)] = tree(value, spans)
+	}
+	return fields
+}
+
+// lowerFirst lower-cases the first letter of a field name
+func lowerFirst(name string) string {
+	r := []rune(name)
+	return strings.ToLower(string(r[0])) + string(r[1:])
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DTrader-store/formula-go/lexer"
+	"github.com/DTrader-store/formula-go/parser"
+)
+
+// benchCommand times the lexer, parser and interpreter phases of a formula on a dataset
+func benchCommand(args []string, s *streams) int {
+	flags := newFlags("bench", "file.fml", s)
+	data := flags.String("data", "", "CSV or TSV market data with a header row (required)")
+	n := flags.Int("n", 100, "number of iterations")
+	withLibrary := flags.Bool("library", true, "make the system indicators callable, e.g. MACD(12, 26, 9)")
+	var params list
+	flags.Var(&params, "p", "formula parameter NAME=VALUE; may be repeated")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() != 1 || *data == "" || *n <= 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	values, err := parseParams(params)
+	if err != nil {
+		return failf(s, exitUsage, "%v", err)
+	}
+	src, series, code := load(flags.Arg(0), *data, s)
+	if code != exitOK {
+		return code
+	}
+	e := newEngine(*withLibrary)
+
+	var lexing, parsing, executing time.Duration
+	for i := 0; i < *n; i++ {
+		start := time.Now()
+		tokens, err := lexer.NewLexer(src).Tokenize()
+		if err != nil {
+			return failf(s, exitProblem, "%s: %v", flags.Arg(0), err)
+		}
+		lexed := time.Now()
+		program, err := parser.NewParser(tokens).Parse()
+		if err != nil {
+			return failf(s, exitProblem, "%s: %v", flags.Arg(0), err)
+		}
+		parsed := time.Now()
+		if _, err := e.ExecuteSeriesWithParams(program, series, values); err != nil {
+			return failf(s, exitProblem, "%s: %v", flags.Arg(0), err)
+		}
+		executed := time.Now()
+
+		lexing += lexed.Sub(start)
+		parsing += parsed.Sub(lexed)
+		executing += executed.Sub(parsed)
+	}
+
+	perOp := func(total time.Duration) time.Duration { return total / time.Duration(*n) }
+	fmt.Fprintf(s.stdout, "bars         %d\n", series.Len())
+	fmt.Fprintf(s.stdout, "iterations   %d\n", *n)
+	fmt.Fprintf(s.stdout, "lexer        %v/op\n", perOp(lexing))
+	fmt.Fprintf(s.stdout, "parser       %v/op\n", perOp(parsing))
+	fmt.Fprintf(s.stdout, "interpreter  %v/op\n", perOp(executing))
+	fmt.Fprintf(s.stdout, "total        %v/op\n", perOp(lexing+parsing+executing))
+	return exitOK
+}
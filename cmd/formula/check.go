@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/DTrader-store/formula-go/format"
+	"github.com/DTrader-store/formula-go/lint"
+)
+
+// checkCommand parses and lints formula files, printing one line per diagnostic
+func checkCommand(args []string, s *streams) int {
+	flags := newFlags("check", "file.fml|dir ...", s)
+	var define list
+	flags.Var(&define, "define", "comma-separated variables supplied at run time, e.g. N,M")
+	strict := flags.Bool("strict", false, "fail on warnings as well as errors")
+	withLibrary := flags.Bool("library", true, "resolve calls to the system indicators such as MACD(12, 26, 9)")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	paths, err := formulaFiles(flags.Args())
+	if err != nil {
+		return failf(s, exitUsage, "%v", err)
+	}
+	linter := lint.New(functions(*withLibrary))
+	linter.Define(define...)
+
+	code := exitOK
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return failf(s, exitUsage, "%v", err)
+		}
+		for _, d := range linter.Source(string(src)) {
+			fmt.Fprintf(s.stdout, "%s:%s\n", path, d)
+			if d.Severity == lint.Error || *strict {
+				code = exitProblem
+			}
+		}
+	}
+	return code
+}
+
+// formulaFiles expands directories in args to the formula files (*.fml) they contain
+func formulaFiles(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && filepath.Ext(path) == format.Ext {
+				paths = append(paths, path)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
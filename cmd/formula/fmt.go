@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/DTrader-store/formula-go/format"
+)
+
+// fmtCommand formats formula files in place, or standard input to standard output without arguments
+func fmtCommand(args []string, s *streams) int {
+	flags := newFlags("fmt", "[file.fml|dir ...]", s)
+	listOnly := flags.Bool("l", false, "list files that are not formatted instead of rewriting them, and fail if any")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	if flags.NArg() == 0 {
+		src, err := io.ReadAll(s.stdin)
+		if err != nil {
+			return failf(s, exitUsage, "%v", err)
+		}
+		formatted, err := format.Source(string(src))
+		if err != nil {
+			return failf(s, exitProblem, "<stdin>: %v", err)
+		}
+		io.WriteString(s.stdout, formatted)
+		return exitOK
+	}
+
+	paths, err := formulaFiles(flags.Args())
+	if err != nil {
+		return failf(s, exitUsage, "%v", err)
+	}
+	code := exitOK
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return failf(s, exitUsage, "%v", err)
+		}
+		formatted, err := format.Source(string(src))
+		if err != nil {
+			fmt.Fprintf(s.stderr, "%s: %v\n", path, err)
+			code = exitProblem
+			continue
+		}
+		if formatted == string(src) {
+			continue
+		}
+		if *listOnly {
+			fmt.Fprintln(s.stdout, path)
+			code = exitProblem
+			continue
+		}
+		if err := os.WriteFile(path, []byte(formatted), 0o644); err != nil {
+			return failf(s, exitUsage, "%v", err)
+		}
+	}
+	return code
+}
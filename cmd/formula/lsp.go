@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/DTrader-store/formula-go/lsp"
+)
+
+// lspCommand serves the Language Server Protocol over standard input and output
+func lspCommand(args []string, s *streams) int {
+	flags := newFlags("lsp", "", s)
+	var define list
+	flags.Var(&define, "define", "comma-separated variables supplied at run time, e.g. N,M")
+	withLibrary := flags.Bool("library", true, "resolve calls to the system indicators such as MACD(12, 26, 9)")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	server := lsp.NewServer(functions(*withLibrary))
+	server.Linter().Define(define...)
+	if err := server.Serve(s.stdin, s.stdout); err != nil {
+		return failf(s, exitProblem, "%v", err)
+	}
+	return exitOK
+}
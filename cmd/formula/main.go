@@ -0,0 +1,147 @@
+// Command formula runs, checks, formats and benchmarks formula files, and explores them interactively.
+//
+// Usage:
+//
+//	formula run -data bars.csv [-format csv|json] [-o out] [-p NAME=VALUE] file.fml
+//	formula check [-define N,M] [-strict] file.fml|dir ...
+//	formula fmt [-l] [file.fml|dir ...]
+//	formula ast [-spans] file.fml
+//	formula bench -data bars.csv [-n 100] [-p NAME=VALUE] file.fml
+//	formula repl [-data bars.csv] [-n 10] [-history file]
+//	formula lsp
+//
+// Exit codes: 0 on success, 1 when a formula has problems (syntax or lint errors, unformatted
+// files, runtime errors), 2 on usage and I/O errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/library"
+)
+
+// Exit codes
+const (
+	exitOK      = 0
+	exitProblem = 1 // the formula has problems
+	exitUsage   = 2 // bad arguments, unreadable files
+)
+
+// streams are the standard input and outputs of a command
+type streams struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// command is a subcommand; it returns the exit code
+type command struct {
+	summary string
+	run     func(args []string, s *streams) int
+}
+
+var commands = map[string]command{
+	"run":   {"run a formula on CSV market data and write its outputs as CSV or JSON", runCommand},
+	"check": {"parse and lint formula files", checkCommand},
+	"fmt":   {"format formula files in place", fmtCommand},
+	"ast":   {"print the syntax tree of a formula as JSON", astCommand},
+	"bench": {"time the lexer, parser and interpreter on a dataset", benchCommand},
+	"repl":  {"try statements interactively against a loaded dataset", replCommand},
+	"lsp":   {"serve the Language Server Protocol over stdin and stdout", lspCommand},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &streams{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}))
+}
+
+// run dispatches to the subcommand named by the first argument
+func run(args []string, s *streams) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(s.stderr)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(s.stderr, "formula: unknown command %q\n", args[0])
+		usage(s.stderr)
+		return exitUsage
+	}
+	return cmd.run(args[1:], s)
+}
+
+// usage lists the subcommands
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: formula <command> [flags] [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-6s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w, "\nRun 'formula <command> -h' for the flags of a command.")
+}
+
+// newFlags creates the flag set of a subcommand, reporting errors to s.stderr
+func newFlags(name, arguments string, s *streams) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(s.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(s.stderr, "Usage: formula %s [flags] %s\n", name, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args, returning the exit code to stop with when parsing fails or help was asked for
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// functions returns the built-in functions, with the system indicators when withLibrary is set
+func functions(withLibrary bool) *interpreter.FunctionRegistry {
+	registry := interpreter.NewFunctionRegistry()
+	if withLibrary {
+		library.Default().RegisterFunctions(registry)
+	}
+	return registry
+}
+
+// failf reports an error and returns code
+func failf(s *streams, code int, format string, args ...any) int {
+	fmt.Fprintf(s.stderr, "formula: "+format+"\n", args...)
+	return code
+}
+
+// list is a flag that may be repeated or given comma-separated values
+type list []string
+
+func (l *list) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *list) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
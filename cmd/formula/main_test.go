@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	formula = "testdata/ma.fml"
+	bars    = "testdata/bars.csv"
+)
+
+// execute runs the command line args with stdin and returns the exit code and outputs
+func execute(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &streams{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunCSV(t *testing.T) {
+	code, out, errOut := execute("", "run", "-data", bars, "-p", "N=3", formula)
+	if code != exitOK {
+		t.Fatalf("Expected exit 0, got %d: %s", code, errOut)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if lines[0] != "time,MA3,DIFF" || len(lines) != 6 {
+		t.Fatalf("Unexpected CSV output:\n%s", out)
+	}
+	if !strings.HasPrefix(lines[3], "2024-01-04 00:00:00,11.1,") {
+		t.Errorf("Expected MA3 of 11.1 on the third bar, got %s", lines[3])
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.json")
+	code, _, errOut := execute("", "run", "-data", bars, "-p", "N=3", "-o", path, formula)
+	if code != exitOK {
+		t.Fatalf("Expected exit 0, got %d: %s", code, errOut)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Outputs []struct {
+			Name string     `json:"name"`
+			Data []*float64 `json:"data"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Expected JSON output, got %v:\n%s", err, data)
+	}
+	if len(result.Outputs) != 2 || result.Outputs[0].Name != "MA3" || result.Outputs[0].Data[0] != nil {
+		t.Errorf("Unexpected outputs: %s", data)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	if code, _, errOut := execute("", "run", "-data", bars, formula); code != exitProblem || !strings.Contains(errOut, "undefined variable: N") {
+		t.Errorf("Expected exit 1 for a missing parameter, got %d: %s", code, errOut)
+	}
+	if code, _, _ := execute("", "run", formula); code != exitUsage {
+		t.Errorf("Expected exit 2 without -data, got %d", code)
+	}
+	if code, _, _ := execute("", "run", "-data", bars, "-p", "N", formula); code != exitUsage {
+		t.Errorf("Expected exit 2 for a malformed parameter, got %d", code)
+	}
+	if code, _, _ := execute("", "run", "-data", "missing.csv", formula); code != exitUsage {
+		t.Errorf("Expected exit 2 for missing data, got %d", code)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	code, out, _ := execute("", "check", "testdata")
+	if code != exitProblem || !strings.Contains(out, "testdata/ma.fml:2:17: error: undefined variable N") {
+		t.Errorf("Expected the undefined variable reported with exit 1, got %d:\n%s", code, out)
+	}
+	if code, out, _ := execute("", "check", "-define", "N", formula); code != exitOK || out != "" {
+		t.Errorf("Expected a clean check with -define N, got %d:\n%s", code, out)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "unused.fml")
+	os.WriteFile(path, []byte("X := CLOSE;\nY : CLOSE;\n"), 0o644)
+	if code, out, _ := execute("", "check", path); code != exitOK || !strings.Contains(out, "warning") {
+		t.Errorf("Expected a warning with exit 0, got %d:\n%s", code, out)
+	}
+	if code, _, _ := execute("", "check", "-strict", path); code != exitProblem {
+		t.Errorf("Expected exit 1 with -strict, got %d", code)
+	}
+}
+
+func TestFmt(t *testing.T) {
+	code, out, _ := execute("A:=MA( CLOSE,5 )", "fmt")
+	if code != exitOK || out != "A := MA(CLOSE, 5);\n" {
+		t.Errorf("Expected formatted stdin, got %d: %q", code, out)
+	}
+
+	path := filepath.Join(t.TempDir(), "a.fml")
+	os.WriteFile(path, []byte("A:=MA( CLOSE,5 )"), 0o644)
+	if code, out, _ := execute("", "fmt", "-l", path); code != exitProblem || strings.TrimSpace(out) != path {
+		t.Errorf("Expected -l to list the file with exit 1, got %d: %q", code, out)
+	}
+	if code, _, _ := execute("", "fmt", path); code != exitOK {
+		t.Fatalf("Expected exit 0, got %d", code)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "A := MA(CLOSE, 5);\n" {
+		t.Errorf("Expected the file rewritten, got %q", data)
+	}
+	if code, out, _ := execute("", "fmt", "-l", path, formula); code != exitOK || out != "" {
+		t.Errorf("Expected formatted files to pass -l, got %d: %q", code, out)
+	}
+}
+
+func TestAST(t *testing.T) {
+	code, out, errOut := execute("", "ast", "-spans", formula)
+	if code != exitOK {
+		t.Fatalf("Expected exit 0, got %d: %s", code, errOut)
+	}
+	var program struct {
+		Type string `json:"type"`
+		Body []struct {
+			Type string `json:"type"`
+			Name string `json:"name"`
+			Span struct {
+				Start struct{ Line, Column int }
+			} `json:"span"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(out), &program); err != nil {
+		t.Fatalf("Expected JSON, got %v", err)
+	}
+	if program.Type != "Program" || len(program.Body) != 2 {
+		t.Fatalf("Unexpected tree:\n%s", out)
+	}
+	first := program.Body[0]
+	if first.Type != "OutputDeclaration" || first.Name != "MA3" || first.Span.Start.Line != 2 || first.Span.Start.Column != 1 {
+		t.Errorf("Unexpected first statement: %+v", first)
+	}
+}
+
+func TestBench(t *testing.T) {
+	code, out, errOut := execute("", "bench", "-n", "2", "-data", bars, "-p", "N=3", formula)
+	if code != exitOK {
+		t.Fatalf("Expected exit 0, got %d: %s", code, errOut)
+	}
+	for _, want := range []string{"bars         5", "lexer", "parser", "interpreter"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if code, _, errOut := execute(""); code != exitUsage || !strings.Contains(errOut, "Commands:") {
+		t.Errorf("Expected usage with exit 2, got %d: %s", code, errOut)
+	}
+	if code, _, _ := execute("", "help"); code != exitOK {
+		t.Errorf("Expected exit 0 for help, got %d", code)
+	}
+	if code, _, _ := execute("", "nope"); code != exitUsage {
+		t.Errorf("Expected exit 2 for an unknown command, got %d", code)
+	}
+	if code, _, _ := execute("", "run", "-bogus"); code != exitUsage {
+		t.Errorf("Expected exit 2 for an unknown flag, got %d", code)
+	}
+}
+
+func TestREPL(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history")
+	input := strings.Join([]string{
+		"CLOSE",
+		":load " + bars,
+		"MA3 := MA(CLOSE, 3); DIFF : CLOSE - MA3",
+		"MA3 * 2",
+		":vars",
+		":plot CLOSE",
+		":plot MA3",
+		":explain",
+		"X := NOPE(1)",
+		":history",
+		"!3",
+		":quit",
+		"NEVER := 1",
+	}, "\n")
+	code, out, errOut := execute(input, "repl", "-n", "2", "-history", history)
+	if code != exitOK {
+		t.Fatalf("Expected exit 0, got %d: %s", code, errOut)
+	}
+	for _, want := range []string{
+		"no market data loaded",
+		"error: Runtime error: undefined variable: CLOSE",
+		"loaded 5 bars from " + bars,
+		"MA3 = … [11.3333 11.4667] (5 bars)",
+		"DIFF = … [-0.133333 -0.0666667] (5 bars)",
+		"MA3 * 2 = … [22.6667 22.9333] (5 bars)",
+		"DIFF         … [-0.133333 -0.0666667] (5 bars)",
+		"▁▄█▅▆  min 10.5  max 11.8",
+		"  ▁▅█  min 11.1  max 11.4667",
+		`"type": "BinaryExpression"`,
+		"error: Runtime error: undefined function: NOPE",
+		"   3  MA3 := MA(CLOSE, 3); DIFF : CLOSE - MA3",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "NEVER") || strings.Contains(out, "__expr__") {
+		t.Errorf("Unexpected output after :quit or of internal names:\n%s", out)
+	}
+
+	data, _ := os.ReadFile(history)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 12 || lines[10] != lines[2] {
+		t.Errorf("Expected 12 history lines with !3 expanded, got:\n%s", data)
+	}
+	if _, out, _ := execute(":history\n", "repl", "-history", history); !strings.Contains(out, "  12  :quit") {
+		t.Errorf("Expected the saved history read back, got:\n%s", out)
+	}
+}
+
+func TestREPLReplaysStatementsOnLoad(t *testing.T) {
+	dir := t.TempDir()
+	short := filepath.Join(dir, "short.csv")
+	os.WriteFile(short, []byte("date,open,high,low,close,volume\n2024-01-02,1,2,1,2,10\n2024-01-03,2,3,2,3,10\n"), 0o644)
+
+	input := "SPREAD := HIGH - LOW\nX := PEAK(1)\n:load " + short + "\n:vars\n"
+	_, out, _ := execute(input, "repl", "-data", bars)
+	if !strings.Contains(out, "loaded 2 bars from "+short) || !strings.Contains(out, "SPREAD       [1 1] (2 bars)") {
+		t.Errorf("Expected SPREAD recomputed on the new data, got:\n%s", out)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		values []float64
+		want   string
+	}{
+		{[]float64{1, 2, 3}, "▁▅█  min 1  max 3"},
+		{[]float64{nan, 5, 5}, " ▅▅  min 5  max 5"},
+		{[]float64{nan, nan}, "    (no values)"},
+	}
+	for _, tt := range tests {
+		if got := sparkline(tt.values); got != tt.want {
+			t.Errorf("sparkline(%v) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/DTrader-store/formula-go/engine"
+	"github.com/DTrader-store/formula-go/format"
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/parser/ast"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+const replHelp = `Enter statements such as MA5 := MA(CLOSE, 5) or expressions such as CLOSE - OPEN.
+Commands:
+  :load FILE       load CSV or TSV market data and re-run the statements entered so far
+  :vars            list the variables defined in this session
+  :plot NAME       draw the last bars of a series as a sparkline
+  :explain [EXPR]  print the syntax tree of EXPR, or of the last line entered
+  :history         list the lines entered; !N runs line N again
+  :help            show this help
+  :quit            leave the session
+`
+
+// sparks are the levels of a sparkline, lowest first
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// replCommand starts an interactive session that runs statements against a loaded dataset
+func replCommand(args []string, s *streams) int {
+	flags := newFlags("repl", "", s)
+	data := flags.String("data", "", "CSV or TSV market data to load at start")
+	values := flags.Int("n", 10, "number of trailing values shown for a series")
+	width := flags.Int("width", 60, "number of bars drawn by :plot")
+	historyPath := flags.String("history", "", "file entered lines are appended to, and read back from at start")
+	withLibrary := flags.Bool("library", true, "make the system indicators callable, e.g. MACD(12, 26, 9)")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() != 0 || *values <= 0 || *width <= 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	r := &repl{engine: newEngine(*withLibrary), out: s.stdout, values: *values, width: *width}
+	r.reset(types.NewSeries(0))
+	if *data != "" {
+		if err := r.load(*data); err != nil {
+			return failf(s, exitUsage, "%v", err)
+		}
+	} else {
+		fmt.Fprintln(s.stdout, "no market data loaded; :load FILE reads it, :help lists the commands")
+	}
+	if *historyPath != "" {
+		history, err := openHistory(*historyPath, &r.history)
+		if err != nil {
+			return failf(s, exitUsage, "%v", err)
+		}
+		defer history.Close()
+		r.historyFile = history
+	}
+
+	scanner := bufio.NewScanner(s.stdin)
+	for {
+		fmt.Fprint(s.stdout, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(s.stdout)
+			break
+		}
+		if !r.line(scanner.Text()) {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return failf(s, exitUsage, "%v", err)
+	}
+	return exitOK
+}
+
+// repl is an interactive session; statements run one at a time in an interpreter that keeps their variables
+type repl struct {
+	engine      *engine.FormulaEngine
+	session     *interpreter.Interpreter
+	statements  []ast.Statement // statements run so far, replayed when other data is loaded
+	program     *ast.Program    // last program entered, shown by :explain
+	history     []string
+	historyFile io.Writer // nil when history is not saved
+	out         io.Writer
+	values      int // trailing values shown for a series
+	width       int // bars drawn by :plot
+}
+
+// openHistory reads the lines of a history file into history and opens it for appending
+func openHistory(path string, history *[]string) (*os.File, error) {
+	if data, err := os.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line != "" {
+				*history = append(*history, line)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+}
+
+// line handles one line of input and reports whether the session goes on
+func (r *repl) line(text string) bool {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return true
+	}
+	if strings.HasPrefix(text, "!") {
+		n, err := strconv.Atoi(text[1:])
+		if err != nil || n < 1 || n > len(r.history) {
+			fmt.Fprintf(r.out, "no history entry %s\n", text[1:])
+			return true
+		}
+		text = r.history[n-1]
+		fmt.Fprintln(r.out, text)
+	}
+	r.history = append(r.history, text)
+	if r.historyFile != nil {
+		fmt.Fprintln(r.historyFile, text)
+	}
+
+	if !strings.HasPrefix(text, ":") {
+		r.eval(text)
+		return true
+	}
+	name, arg, _ := strings.Cut(text[1:], " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "load":
+		if arg == "" {
+			fmt.Fprintln(r.out, "usage: :load FILE")
+		} else if err := r.load(arg); err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+		}
+	case "vars":
+		r.vars()
+	case "plot":
+		r.plot(arg)
+	case "explain":
+		r.explain(arg)
+	case "history":
+		for i, line := range r.history {
+			fmt.Fprintf(r.out, "%4d  %s\n", i+1, line)
+		}
+	case "help":
+		fmt.Fprint(r.out, replHelp)
+	case "quit", "q", "exit":
+		return false
+	default:
+		fmt.Fprintf(r.out, "unknown command :%s; :help lists the commands\n", name)
+	}
+	return true
+}
+
+// reset starts a new session over series
+func (r *repl) reset(series *types.Series) {
+	r.session = r.engine.NewSession(series)
+}
+
+// load reads market data and re-runs the statements of the session on it, dropping those that fail
+func (r *repl) load(path string) error {
+	series, err := readSeries(path)
+	if err != nil {
+		return err
+	}
+	r.reset(series)
+	statements := r.statements[:0]
+	for _, stmt := range r.statements {
+		if _, err := r.session.ExecuteStatement(stmt); err != nil {
+			fmt.Fprintf(r.out, "dropped %s: %v\n", statementName(stmt), err)
+			continue
+		}
+		statements = append(statements, stmt)
+	}
+	r.statements = statements
+	fmt.Fprintf(r.out, "loaded %d bars from %s\n", series.Len(), path)
+	return nil
+}
+
+// eval compiles a line and runs its statements, printing the value of each
+func (r *repl) eval(text string) {
+	program, err := r.engine.Compile(text)
+	if err != nil {
+		fmt.Fprintf(r.out, "error: %v\n", err)
+		return
+	}
+	r.program = program
+	for _, stmt := range program.Body {
+		value, err := r.session.ExecuteStatement(stmt)
+		if err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+			return
+		}
+		r.statements = append(r.statements, stmt)
+		fmt.Fprintf(r.out, "%s = %s\n", statementName(stmt), r.format(value))
+	}
+}
+
+// vars lists the variables defined in the session with their last value
+func (r *repl) vars() {
+	for _, name := range r.session.UserVariables() {
+		if name == "__expr__" {
+			continue
+		}
+		value, _ := r.session.Variable(name)
+		fmt.Fprintf(r.out, "%-12s %s\n", name, r.format(value))
+	}
+}
+
+// plot draws the last bars of a series as a sparkline scaled between their minimum and maximum
+func (r *repl) plot(name string) {
+	if name == "" {
+		fmt.Fprintln(r.out, "usage: :plot NAME")
+		return
+	}
+	value, ok := r.session.Variable(name)
+	if !ok {
+		fmt.Fprintf(r.out, "undefined variable %s\n", name)
+		return
+	}
+	if !value.IsArray || len(value.Array) == 0 {
+		fmt.Fprintf(r.out, "%s is not a series\n", name)
+		return
+	}
+	fmt.Fprintf(r.out, "%s\n", sparkline(value.Array[max(0, len(value.Array)-r.width):]))
+}
+
+// sparkline draws values with one level per value; NaN values are left blank. The line is
+// followed by the minimum and maximum it is scaled to.
+func sparkline(values []float64) string {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			low, high = math.Min(low, v), math.Max(high, v)
+		}
+	}
+	if math.IsInf(low, 1) {
+		return strings.Repeat(" ", len(values)) + "  (no values)"
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+			b.WriteRune(' ')
+		case high == low:
+			b.WriteRune(sparks[len(sparks)/2])
+		default:
+			b.WriteRune(sparks[int((v-low)/(high-low)*float64(len(sparks)-1)+0.5)])
+		}
+	}
+	fmt.Fprintf(&b, "  min %s  max %s", formatNumber(low), formatNumber(high))
+	return b.String()
+}
+
+// explain prints the syntax tree of src, or of the last program entered when src is empty
+func (r *repl) explain(src string) {
+	program := r.program
+	if src != "" {
+		var err error
+		if program, err = r.engine.Compile(src); err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+			return
+		}
+	}
+	if program == nil {
+		fmt.Fprintln(r.out, "nothing to explain yet")
+		return
+	}
+	encoded, err := json.MarshalIndent(tree(reflect.ValueOf(program), nil), "", "  ")
+	if err != nil {
+		fmt.Fprintf(r.out, "error: %v\n", err)
+		return
+	}
+	fmt.Fprintf(r.out, "%s\n", encoded)
+}
+
+// format describes a value: text, a number, or the last values of a series
+func (r *repl) format(value *interpreter.Value) string {
+	switch {
+	case value.IsText:
+		return strconv.Quote(value.Text)
+	case !value.IsArray:
+		return formatNumber(value.Single)
+	}
+	tail := value.Array[max(0, len(value.Array)-r.values):]
+	numbers := make([]string, len(tail))
+	for i, v := range tail {
+		numbers[i] = formatNumber(v)
+	}
+	prefix := ""
+	if len(tail) < len(value.Array) {
+		prefix = "… "
+	}
+	return fmt.Sprintf("%s[%s] (%d bars)", prefix, strings.Join(numbers, " "), len(value.Array))
+}
+
+// formatNumber prints a number with up to six significant digits
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
+
+// statementName names the variable a statement assigns, or shows the expression it evaluates
+func statementName(stmt ast.Statement) string {
+	switch s := stmt.(type) {
+	case *ast.VariableDeclaration:
+		return s.Name
+	case *ast.OutputDeclaration:
+		return s.Name
+	case *ast.ExpressionStatement:
+		if src, err := format.Expression(s.Expr); err == nil {
+			return src
+		}
+	}
+	return string(stmt.Type())
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/DTrader-store/formula-go/dataio"
+	"github.com/DTrader-store/formula-go/engine"
+	"github.com/DTrader-store/formula-go/library"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// runCommand runs a formula on CSV market data and writes its outputs
+func runCommand(args []string, s *streams) int {
+	flags := newFlags("run", "file.fml", s)
+	data := flags.String("data", "", "CSV or TSV market data with a header row (required)")
+	format := flags.String("format", "", "output format, csv or json; taken from the -o extension, csv by default")
+	output := flags.String("o", "", "output file; standard output when empty")
+	withLibrary := flags.Bool("library", true, "make the system indicators callable, e.g. MACD(12, 26, 9)")
+	var params list
+	flags.Var(&params, "p", "formula parameter NAME=VALUE; may be repeated")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() != 1 || *data == "" {
+		flags.Usage()
+		return exitUsage
+	}
+
+	values, err := parseParams(params)
+	if err != nil {
+		return failf(s, exitUsage, "%v", err)
+	}
+	if *format == "" {
+		*format = "csv"
+		if strings.EqualFold(filepath.Ext(*output), ".json") {
+			*format = "json"
+		}
+	}
+	if *format != "csv" && *format != "json" {
+		return failf(s, exitUsage, "unknown format %q, want csv or json", *format)
+	}
+
+	src, series, code := load(flags.Arg(0), *data, s)
+	if code != exitOK {
+		return code
+	}
+	e := newEngine(*withLibrary)
+	program, err := e.Compile(src)
+	if err != nil {
+		return failf(s, exitProblem, "%s: %v", flags.Arg(0), err)
+	}
+	result, err := e.ExecuteSeriesWithParams(program, series, values)
+	if err != nil {
+		return failf(s, exitProblem, "%s: %v", flags.Arg(0), err)
+	}
+
+	var out bytes.Buffer
+	if *format == "json" {
+		encoded, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return failf(s, exitProblem, "%v", err)
+		}
+		out.Write(append(encoded, '\n'))
+	} else if err := dataio.WriteResultCSV(&out, result, result.Times, nil); err != nil {
+		return failf(s, exitProblem, "%v", err)
+	}
+
+	if *output == "" {
+		s.stdout.Write(out.Bytes())
+		return exitOK
+	}
+	if err := os.WriteFile(*output, out.Bytes(), 0o644); err != nil {
+		return failf(s, exitUsage, "%v", err)
+	}
+	return exitOK
+}
+
+// newEngine creates an engine, whose formulas can call the system indicators when withLibrary is set
+func newEngine(withLibrary bool) *engine.FormulaEngine {
+	e := engine.NewFormulaEngine()
+	if withLibrary {
+		library.Default().RegisterFunctions(e.Functions())
+	}
+	return e
+}
+
+// parseParams parses NAME=VALUE pairs
+func parseParams(pairs []string) (map[string]float64, error) {
+	values := make(map[string]float64, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid parameter %q, want NAME=VALUE", pair)
+		}
+		values[strings.TrimSpace(name)] = number
+	}
+	return values, nil
+}
+
+// load reads a formula file and its market data
+func load(path, dataPath string, s *streams) (string, *types.Series, int) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, failf(s, exitUsage, "%v", err)
+	}
+	series, err := readSeries(dataPath)
+	if err != nil {
+		return "", nil, failf(s, exitUsage, "%v", err)
+	}
+	return string(src), series, exitOK
+}
+
+// readSeries reads market data from a CSV file, or a tab-separated one with the .tsv extension
+func readSeries(path string) (*types.Series, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	opts := &dataio.CSVOptions{}
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		opts.Comma = '\t'
+	}
+	series, err := dataio.ReadCSVSeries(f, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return series, nil
+}
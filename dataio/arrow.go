@@ -0,0 +1,392 @@
+package dataio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// ArrowOptions configures mapping Arrow and Parquet columns to market data fields. Columns are
+// matched by name like CSV headers.
+type ArrowOptions struct {
+	Columns   map[Field]string // Column name per field, overriding the default names
+	Location  *time.Location   // Timezone of timestamps without a zone, dates and string times; time.Local when nil
+	KeepExtra bool             // Read unmapped numeric columns into Series.Extra
+}
+
+func (o *ArrowOptions) location() *time.Location {
+	if o.Location == nil {
+		return time.Local
+	}
+	return o.Location
+}
+
+// SeriesFromRecord reads market data from an Arrow record batch. Non-null float64 columns are not
+// copied: the series shares the record's buffers, so the record must not be released while the
+// series is in use. Other numeric columns are converted; nulls are errors in price columns, 0 in
+// volume, amount, open interest and settle, and NaN in extra columns. The time is taken from the
+// date column, or from the time column when there is none, and may be a timestamp, date or string.
+func SeriesFromRecord(rec arrow.RecordBatch, opts *ArrowOptions) (*types.Series, error) {
+	columns := make([]arrow.Array, rec.NumCols())
+	for i := range columns {
+		columns[i] = rec.Column(i)
+	}
+	return seriesFromArrays(rec.Schema(), columns, opts)
+}
+
+// SeriesFromTable reads market data from an Arrow table, see SeriesFromRecord. Columns made of
+// more than one chunk are concatenated, which copies them.
+func SeriesFromTable(table arrow.Table, opts *ArrowOptions) (*types.Series, error) {
+	columns := make([]arrow.Array, table.NumCols())
+	for i := range columns {
+		chunks := table.Column(i).Data().Chunks()
+		switch len(chunks) {
+		case 0:
+			columns[i] = array.MakeArrayOfNull(memory.DefaultAllocator, table.Column(i).DataType(), 0)
+		case 1:
+			columns[i] = chunks[0]
+		default:
+			column, err := array.Concatenate(chunks, memory.DefaultAllocator)
+			if err != nil {
+				return nil, fmt.Errorf("arrow: column %s: %w", table.Schema().Field(i).Name, err)
+			}
+			columns[i] = column
+		}
+	}
+	return seriesFromArrays(table.Schema(), columns, opts)
+}
+
+// ReadArrowIPC reads market data from an Arrow IPC stream
+func ReadArrowIPC(r io.Reader, opts *ArrowOptions) (*types.Series, error) {
+	reader, err := ipc.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("arrow: %w", err)
+	}
+	defer reader.Release()
+
+	var records []arrow.RecordBatch
+	for reader.Next() {
+		rec := reader.RecordBatch()
+		rec.Retain()
+		records = append(records, rec)
+	}
+	if err := reader.Err(); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("arrow: %w", err)
+	}
+	if len(records) == 1 {
+		return SeriesFromRecord(records[0], opts)
+	}
+	return SeriesFromTable(array.NewTableFromRecords(reader.Schema(), records), opts)
+}
+
+// ReadArrowFile reads market data from an Arrow IPC file (Feather v2)
+func ReadArrowFile(path string, opts *ArrowOptions) (*types.Series, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader, err := ipc.NewFileReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("arrow: %w", err)
+	}
+	defer reader.Close()
+
+	records := make([]arrow.RecordBatch, reader.NumRecords())
+	for i := range records {
+		if records[i], err = reader.RecordBatchAt(i); err != nil {
+			return nil, fmt.Errorf("arrow: %w", err)
+		}
+	}
+	return SeriesFromTable(array.NewTableFromRecords(reader.Schema(), records), opts)
+}
+
+// ReadParquet reads market data from a Parquet file
+func ReadParquet(r parquet.ReaderAtSeeker, opts *ArrowOptions) (*types.Series, error) {
+	table, err := pqarrow.ReadTable(context.Background(), r, nil, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		return nil, fmt.Errorf("parquet: %w", err)
+	}
+	return SeriesFromTable(table, opts)
+}
+
+// ReadParquetFile reads market data from a Parquet file on disk
+func ReadParquetFile(path string, opts *ArrowOptions) (*types.Series, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadParquet(f, opts)
+}
+
+// seriesFromArrays maps the columns of a schema to series fields
+func seriesFromArrays(schema *arrow.Schema, columns []arrow.Array, opts *ArrowOptions) (*types.Series, error) {
+	if opts == nil {
+		opts = &ArrowOptions{}
+	}
+	names := make([]string, len(columns))
+	for i := range names {
+		names[i] = schema.Field(i).Name
+	}
+	fields, extra, err := mapColumns(names, opts.Columns, opts.KeepExtra)
+	if err != nil {
+		return nil, fmt.Errorf("arrow: %w", err)
+	}
+
+	column := func(field Field, required bool) ([]float64, error) {
+		i, ok := fields[field]
+		if !ok {
+			return nil, nil
+		}
+		values, err := float64Values(columns[i], 0, !required)
+		if err != nil {
+			return nil, fmt.Errorf("arrow: %s column: %w", field, err)
+		}
+		return values, nil
+	}
+
+	series := &types.Series{}
+	targets := []struct {
+		field    Field
+		values   *[]float64
+		required bool
+	}{
+		{FieldOpen, &series.Open, true},
+		{FieldHigh, &series.High, true},
+		{FieldLow, &series.Low, true},
+		{FieldClose, &series.Close, true},
+		{FieldVolume, &series.Volume, false},
+		{FieldAmount, &series.Amount, false},
+		{FieldOpenInterest, &series.OpenInterest, false},
+		{FieldSettle, &series.Settle, false},
+	}
+	for _, target := range targets {
+		if *target.values, err = column(target.field, target.required); err != nil {
+			return nil, err
+		}
+	}
+
+	n := series.Len()
+	if series.Volume == nil {
+		series.Volume = make([]float64, n)
+	}
+	if series.Amount == nil {
+		series.Amount = make([]float64, n)
+	}
+
+	timeColumn, ok := fields[FieldDate]
+	if !ok {
+		timeColumn = fields[FieldTime]
+	}
+	if series.Time, err = timeValues(columns[timeColumn], opts.location()); err != nil {
+		return nil, fmt.Errorf("arrow: %s column: %w", schema.Field(timeColumn).Name, err)
+	}
+
+	for name, i := range extra {
+		values, err := float64Values(columns[i], math.NaN(), true)
+		if err != nil {
+			// Non-numeric columns such as a symbol code are skipped
+			continue
+		}
+		if series.Extra == nil {
+			series.Extra = make(map[string][]float64, len(extra))
+		}
+		series.Extra[name] = values
+	}
+
+	if err := series.Validate(); err != nil {
+		return nil, fmt.Errorf("arrow: %w", err)
+	}
+	for i := 0; i < n; i++ {
+		bar := types.MarketData{High: series.High[i], Low: series.Low[i], Volume: series.Volume[i], Amount: series.Amount[i]}
+		if series.OpenInterest != nil {
+			bar.OpenInterest = series.OpenInterest[i]
+		}
+		if math.IsNaN(series.Open[i]) || math.IsNaN(series.High[i]) || math.IsNaN(series.Low[i]) || math.IsNaN(series.Close[i]) {
+			return nil, fmt.Errorf("arrow: row %d: missing price", i)
+		}
+		if err := bar.Validate(); err != nil {
+			return nil, fmt.Errorf("arrow: row %d: %w", i, err)
+		}
+	}
+	return series, nil
+}
+
+// float64Values returns the values of a numeric column, sharing the buffer of float64 columns
+// without nulls. Nulls become fill when allowed.
+func float64Values(column arrow.Array, fill float64, allowNull bool) ([]float64, error) {
+	if values, ok := column.(*array.Float64); ok && values.NullN() == 0 {
+		return values.Float64Values(), nil
+	}
+	if column.NullN() > 0 && !allowNull {
+		return nil, fmt.Errorf("%d null values", column.NullN())
+	}
+
+	var values []float64
+	switch c := column.(type) {
+	case *array.Float64:
+		values = convert(c.Float64Values())
+	case *array.Float32:
+		values = convert(c.Float32Values())
+	case *array.Int64:
+		values = convert(c.Int64Values())
+	case *array.Int32:
+		values = convert(c.Int32Values())
+	case *array.Int16:
+		values = convert(c.Int16Values())
+	case *array.Int8:
+		values = convert(c.Int8Values())
+	case *array.Uint64:
+		values = convert(c.Uint64Values())
+	case *array.Uint32:
+		values = convert(c.Uint32Values())
+	case *array.Uint16:
+		values = convert(c.Uint16Values())
+	case *array.Uint8:
+		values = convert(c.Uint8Values())
+	default:
+		return nil, fmt.Errorf("unsupported type %s", column.DataType())
+	}
+	for i := range values {
+		if column.IsNull(i) {
+			values[i] = fill
+		}
+	}
+	return values, nil
+}
+
+func convert[T int8 | int16 | int32 | int64 | uint8 | uint16 | uint32 | uint64 | float32 | float64](in []T) []float64 {
+	out := make([]float64, len(in))
+	for i, v := range in {
+		out[i] = float64(v)
+	}
+	return out
+}
+
+// timeValues converts a timestamp, date or string column to times. Timestamps without a zone and
+// dates are read as wall clock times in loc.
+func timeValues(column arrow.Array, loc *time.Location) ([]time.Time, error) {
+	if column.NullN() > 0 {
+		return nil, fmt.Errorf("%d null values", column.NullN())
+	}
+
+	inLocation := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+	}
+	times := make([]time.Time, column.Len())
+	switch c := column.(type) {
+	case *array.Timestamp:
+		kind := c.DataType().(*arrow.TimestampType)
+		toTime, err := kind.GetToTimeFunc()
+		if err != nil {
+			return nil, err
+		}
+		for i := range times {
+			times[i] = toTime(c.Value(i))
+			if kind.TimeZone == "" {
+				times[i] = inLocation(times[i])
+			}
+		}
+	case *array.Date32:
+		for i := range times {
+			times[i] = inLocation(c.Value(i).ToTime())
+		}
+	case *array.Date64:
+		for i := range times {
+			times[i] = inLocation(c.Value(i).ToTime())
+		}
+	case *array.String:
+		for i := range times {
+			t, err := parseTime(c.Value(i), defaultLayouts, loc)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", i, err)
+			}
+			times[i] = t
+		}
+	default:
+		return nil, fmt.Errorf("unsupported time type %s", column.DataType())
+	}
+	return times, nil
+}
+
+// ResultRecord converts the result's output lines to an Arrow record batch with one float64 column
+// per output, NaN stored as null. When times is not nil, a leading "time" timestamp column holds
+// the bar times and every output must have one value per time. The caller releases the record.
+func ResultRecord(result *types.FormulaResult, times []time.Time) (arrow.RecordBatch, error) {
+	n := len(times)
+	if times == nil {
+		for _, out := range result.Outputs {
+			n = max(n, len(out.Data))
+		}
+	}
+
+	mem := memory.DefaultAllocator
+	fields := make([]arrow.Field, 0, len(result.Outputs)+1)
+	columns := make([]arrow.Array, 0, len(result.Outputs)+1)
+	defer func() {
+		for _, column := range columns {
+			column.Release()
+		}
+	}()
+
+	if times != nil {
+		kind := &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}
+		builder := array.NewTimestampBuilder(mem, kind)
+		defer builder.Release()
+		for _, t := range times {
+			builder.Append(arrow.Timestamp(t.UnixNano()))
+		}
+		fields = append(fields, arrow.Field{Name: "time", Type: kind})
+		columns = append(columns, builder.NewArray())
+	}
+
+	builder := array.NewFloat64Builder(mem)
+	defer builder.Release()
+	for _, out := range result.Outputs {
+		if times != nil && len(out.Data) != n {
+			return nil, fmt.Errorf("arrow: output %s has %d values for %d bars", out.Name, len(out.Data), n)
+		}
+		builder.Reserve(n)
+		for i := 0; i < n; i++ {
+			if i < len(out.Data) && !math.IsNaN(out.Data[i]) {
+				builder.Append(out.Data[i])
+			} else {
+				builder.AppendNull()
+			}
+		}
+		fields = append(fields, arrow.Field{Name: out.Name, Type: arrow.PrimitiveTypes.Float64, Nullable: true})
+		columns = append(columns, builder.NewArray())
+	}
+
+	return array.NewRecordBatch(arrow.NewSchema(fields, nil), columns, int64(n)), nil
+}
+
+// WriteResultArrow writes the result as an Arrow IPC stream holding one record batch, see ResultRecord
+func WriteResultArrow(w io.Writer, result *types.FormulaResult, times []time.Time) error {
+	rec, err := ResultRecord(result, times)
+	if err != nil {
+		return err
+	}
+	defer rec.Release()
+
+	writer := ipc.NewWriter(w, ipc.WithSchema(rec.Schema()))
+	if err := writer.Write(rec); err != nil {
+		writer.Close()
+		return fmt.Errorf("arrow: %w", err)
+	}
+	return writer.Close()
+}
@@ -0,0 +1,178 @@
+package dataio
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+
+	"github.com/DTrader-store/formula-go/engine"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// createBarRecord builds a record with a zoneless timestamp column, float64 prices and int64 volume
+func createBarRecord(t *testing.T) arrow.RecordBatch {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "datetime", Type: &arrow.TimestampType{Unit: arrow.Millisecond}},
+		{Name: "Open", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "High", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "Low", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "Close", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "Volume", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "symbol", Type: arrow.BinaryTypes.String},
+	}, nil)
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	start := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		builder.Field(0).(*array.TimestampBuilder).Append(arrow.Timestamp(start.AddDate(0, 0, i).UnixMilli()))
+	}
+	builder.Field(1).(*array.Float64Builder).AppendValues([]float64{10, 10.2, 10.6}, nil)
+	builder.Field(2).(*array.Float64Builder).AppendValues([]float64{10.5, 10.8, 10.7}, nil)
+	builder.Field(3).(*array.Float64Builder).AppendValues([]float64{9.8, 10.1, 10}, nil)
+	builder.Field(4).(*array.Float64Builder).AppendValues([]float64{10.2, 10.6, 10.1}, nil)
+	builder.Field(5).(*array.Int64Builder).AppendValues([]int64{1000, 1200, 900}, nil)
+	builder.Field(6).(*array.StringBuilder).AppendValues([]string{"sh600000", "sh600000", "sh600000"}, nil)
+	return builder.NewRecordBatch()
+}
+
+func TestSeriesFromRecord(t *testing.T) {
+	rec := createBarRecord(t)
+	defer rec.Release()
+	shanghai := time.FixedZone("CST", 8*3600)
+
+	series, err := SeriesFromRecord(rec, &ArrowOptions{Location: shanghai, KeepExtra: true})
+	if err != nil {
+		t.Fatalf("SeriesFromRecord error: %v", err)
+	}
+	if series.Len() != 3 || series.Close[1] != 10.6 || series.Volume[2] != 900 {
+		t.Errorf("Unexpected series: %+v", series)
+	}
+	if &series.Close[0] != &rec.Column(4).(*array.Float64).Float64Values()[0] {
+		t.Error("Expected the close column to share the record's buffer")
+	}
+	if expected := time.Date(2024, 1, 3, 15, 0, 0, 0, shanghai); !series.Time[1].Equal(expected) {
+		t.Errorf("Expected zoneless timestamp read as %v, got %v", expected, series.Time[1])
+	}
+	if _, ok := series.Extra["symbol"]; ok {
+		t.Error("Expected non-numeric extra column to be skipped")
+	}
+
+	result := runSeries(t, "MA2 := MA(C, 2)", series)
+	if got := result.Outputs[0].Data[2]; math.Abs(got-10.35) > 1e-9 {
+		t.Errorf("Expected MA2 10.35, got %v", got)
+	}
+}
+
+// runSeries compiles and executes a formula on a series
+func runSeries(t *testing.T, formula string, series *types.Series) *types.FormulaResult {
+	t.Helper()
+	e := engine.NewFormulaEngine()
+	program, err := e.Compile(formula)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	result, err := e.ExecuteSeries(program, series)
+	if err != nil {
+		t.Fatalf("ExecuteSeries error: %v", err)
+	}
+	return result
+}
+
+func TestSeriesFromRecordNullPrice(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "date", Type: arrow.FixedWidthTypes.Date32},
+		{Name: "open", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "high", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "low", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "close", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.Date32Builder).Append(arrow.Date32FromTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+	builder.Field(1).(*array.Float64Builder).AppendNull()
+	for i := 2; i < 5; i++ {
+		builder.Field(i).(*array.Float64Builder).Append(1)
+	}
+	rec := builder.NewRecordBatch()
+	defer rec.Release()
+
+	_, err := SeriesFromRecord(rec, nil)
+	if err == nil || !strings.Contains(err.Error(), "open column") {
+		t.Errorf("Expected null open error, got %v", err)
+	}
+}
+
+func TestReadParquet(t *testing.T) {
+	rec := createBarRecord(t)
+	defer rec.Release()
+	table := array.NewTableFromRecords(rec.Schema(), []arrow.RecordBatch{rec})
+	defer table.Release()
+
+	var buf bytes.Buffer
+	if err := pqarrow.WriteTable(table, &buf, 2, nil, pqarrow.DefaultWriterProps()); err != nil {
+		t.Fatalf("WriteTable error: %v", err)
+	}
+
+	series, err := ReadParquet(bytes.NewReader(buf.Bytes()), &ArrowOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("ReadParquet error: %v", err)
+	}
+	if series.Len() != 3 || series.High[2] != 10.7 || series.Volume[1] != 1200 {
+		t.Errorf("Unexpected series: %+v", series)
+	}
+	if !series.Time[0].Equal(time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected time: %v", series.Time[0])
+	}
+}
+
+func TestWriteResultArrow(t *testing.T) {
+	rec := createBarRecord(t)
+	defer rec.Release()
+	series, err := SeriesFromRecord(rec, &ArrowOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("SeriesFromRecord error: %v", err)
+	}
+	result := runSeries(t, "MA2 := MA(CLOSE, 2); UP := C > O", series)
+
+	var buf bytes.Buffer
+	if err := WriteResultArrow(&buf, result, series.Time); err != nil {
+		t.Fatalf("WriteResultArrow error: %v", err)
+	}
+	out, err := ReadArrowIPC(&buf, &ArrowOptions{
+		Columns: map[Field]string{FieldOpen: "UP", FieldHigh: "UP", FieldLow: "UP", FieldClose: "UP"},
+	})
+	if err != nil {
+		t.Fatalf("ReadArrowIPC error: %v", err)
+	}
+	if out.Len() != 3 || out.Close[0] != 1 || out.Close[2] != 0 {
+		t.Errorf("Unexpected UP column: %v", out.Close)
+	}
+	if !out.Time[2].Equal(series.Time[2]) {
+		t.Errorf("Expected time %v, got %v", series.Time[2], out.Time[2])
+	}
+
+	record, err := ResultRecord(result, nil)
+	if err != nil {
+		t.Fatalf("ResultRecord error: %v", err)
+	}
+	defer record.Release()
+	if record.NumCols() != 2 || record.ColumnName(0) != "MA2" {
+		t.Errorf("Unexpected schema: %v", record.Schema())
+	}
+	if ma := record.Column(0); !ma.IsNull(0) || ma.IsNull(1) {
+		t.Error("Expected NaN warm-up value to be written as null")
+	}
+
+	if _, err := ResultRecord(result, series.Time[:2]); err == nil {
+		t.Error("Expected error for output length mismatch")
+	}
+}
@@ -0,0 +1,389 @@
+// Package dataio reads market data from files and writes formula results back out
+package dataio
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// Field identifies a market data field a CSV column can be mapped to
+type Field string
+
+// Market data fields
+const (
+	FieldDate         Field = "date"   // Date, or date and time in one column
+	FieldTime         Field = "time"   // Time of day, combined with the date column when both are present
+	FieldOpen         Field = "open"   // Opening price
+	FieldHigh         Field = "high"   // Highest price
+	FieldLow          Field = "low"    // Lowest price
+	FieldClose        Field = "close"  // Closing price
+	FieldVolume       Field = "volume" // Volume, optional
+	FieldAmount       Field = "amount" // Amount, optional
+	FieldOpenInterest Field = "openinterest"
+	FieldSettle       Field = "settle"
+)
+
+// defaultHeaders lists the header names recognized for each field when no mapping is given (case-insensitive)
+var defaultHeaders = map[Field][]string{
+	FieldDate:         {"date", "datetime", "trade_date", "timestamp", "日期"},
+	FieldTime:         {"time", "时间"},
+	FieldOpen:         {"open", "开盘", "开盘价"},
+	FieldHigh:         {"high", "最高", "最高价"},
+	FieldLow:          {"low", "最低", "最低价"},
+	FieldClose:        {"close", "收盘", "收盘价"},
+	FieldVolume:       {"volume", "vol", "成交量"},
+	FieldAmount:       {"amount", "amo", "成交额"},
+	FieldOpenInterest: {"openinterest", "open_interest", "oi", "持仓量"},
+	FieldSettle:       {"settle", "结算价"},
+}
+
+// defaultLayouts are tried in order when parsing the date column
+var defaultLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	"20060102150405",
+	"20060102",
+	time.RFC3339,
+}
+
+// timeOfDayLayouts are tried in order when parsing a separate time column
+var timeOfDayLayouts = []string{"15:04:05", "15:04", "150405", "1504"}
+
+// CSVOptions configures reading and writing CSV market data. The zero value reads comma-separated
+// files with the default header names and date layouts in the local timezone.
+type CSVOptions struct {
+	Comma      rune             // Field separator; ',' when zero, '\t' for TSV
+	Columns    map[Field]string // Header name per field, overriding the default names
+	TimeLayout string           // Layout of the date column, tried before the defaults; also used when writing
+	Location   *time.Location   // Timezone of times without an explicit zone; time.Local when nil
+	KeepExtra  bool             // Read unmapped numeric columns into MarketData.Extra; columns with text in the first row are skipped
+}
+
+func (o *CSVOptions) comma() rune {
+	if o.Comma == 0 {
+		return ','
+	}
+	return o.Comma
+}
+
+func (o *CSVOptions) location() *time.Location {
+	if o.Location == nil {
+		return time.Local
+	}
+	return o.Location
+}
+
+// ReadCSVFile reads market data from a CSV file
+func ReadCSVFile(path string, opts *CSVOptions) ([]*types.MarketData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCSV(f, opts)
+}
+
+// ReadCSV reads market data from CSV with a header row. Every row is checked with MarketData.Validate.
+func ReadCSV(r io.Reader, opts *CSVOptions) ([]*types.MarketData, error) {
+	series, err := ReadCSVSeries(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	return series.MarketData(), nil
+}
+
+// ReadCSVSeries reads market data from CSV into columnar form, see ReadCSV
+func ReadCSVSeries(r io.Reader, opts *CSVOptions) (*types.Series, error) {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	reader := csv.NewReader(r)
+	reader.Comma = opts.comma()
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("csv: cannot read header: %w", err)
+	}
+	columns, extra, err := mapColumns(header, opts.Columns, opts.KeepExtra)
+	if err != nil {
+		return nil, fmt.Errorf("csv: %w", err)
+	}
+
+	series := types.NewSeries(0)
+	series.Time = make([]time.Time, 0)
+	_, hasOI := columns[FieldOpenInterest]
+	_, hasSettle := columns[FieldSettle]
+	if hasOI {
+		series.OpenInterest = make([]float64, 0)
+	}
+	if hasSettle {
+		series.Settle = make([]float64, 0)
+	}
+	if len(extra) > 0 {
+		series.Extra = make(map[string][]float64, len(extra))
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("csv line %d: %w", line, err)
+		}
+
+		if line == 2 {
+			dropTextColumns(extra, record)
+			if len(extra) == 0 {
+				series.Extra = nil
+			}
+		}
+
+		row := rowReader{record: record, columns: columns}
+		bar := types.MarketData{
+			Open:         row.number(FieldOpen, true),
+			High:         row.number(FieldHigh, true),
+			Low:          row.number(FieldLow, true),
+			Close:        row.number(FieldClose, true),
+			Volume:       row.number(FieldVolume, false),
+			Amount:       row.number(FieldAmount, false),
+			OpenInterest: row.number(FieldOpenInterest, false),
+			Settle:       row.number(FieldSettle, false),
+		}
+		bar.Time = row.time(opts)
+		if row.err != nil {
+			return nil, fmt.Errorf("csv line %d: %w", line, row.err)
+		}
+		if err := bar.Validate(); err != nil {
+			return nil, fmt.Errorf("csv line %d: %w", line, err)
+		}
+
+		series.Time = append(series.Time, bar.Time)
+		series.Open = append(series.Open, bar.Open)
+		series.High = append(series.High, bar.High)
+		series.Low = append(series.Low, bar.Low)
+		series.Close = append(series.Close, bar.Close)
+		series.Volume = append(series.Volume, bar.Volume)
+		series.Amount = append(series.Amount, bar.Amount)
+		if hasOI {
+			series.OpenInterest = append(series.OpenInterest, bar.OpenInterest)
+		}
+		if hasSettle {
+			series.Settle = append(series.Settle, bar.Settle)
+		}
+		for name, index := range extra {
+			value := math.NaN()
+			if cell := strings.TrimSpace(record[index]); cell != "" {
+				if value, err = strconv.ParseFloat(cell, 64); err != nil {
+					return nil, fmt.Errorf("csv line %d: column %s: invalid number %q", line, name, cell)
+				}
+			}
+			series.Extra[name] = append(series.Extra[name], value)
+		}
+	}
+	return series, nil
+}
+
+// mapColumns finds the index of each field's column, by the given names or the default ones,
+// and with keepExtra the index of every other column
+func mapColumns(header []string, names map[Field]string, keepExtra bool) (map[Field]int, map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	columns := make(map[Field]int)
+	for field, candidates := range defaultHeaders {
+		if name, ok := names[field]; ok {
+			candidates = []string{name}
+		}
+		for _, name := range candidates {
+			if i, ok := index[strings.ToLower(name)]; ok {
+				columns[field] = i
+				break
+			}
+		}
+	}
+
+	for _, field := range []Field{FieldOpen, FieldHigh, FieldLow, FieldClose} {
+		if _, ok := columns[field]; !ok {
+			return nil, nil, fmt.Errorf("missing %s column", field)
+		}
+	}
+	_, hasDate := columns[FieldDate]
+	_, hasTime := columns[FieldTime]
+	if !hasDate && !hasTime {
+		return nil, nil, fmt.Errorf("missing %s column", FieldDate)
+	}
+
+	extra := make(map[string]int)
+	if keepExtra {
+		used := make(map[int]bool, len(columns))
+		for _, i := range columns {
+			used[i] = true
+		}
+		for i, name := range header {
+			if !used[i] {
+				extra[strings.TrimSpace(name)] = i
+			}
+		}
+	}
+	return columns, extra, nil
+}
+
+// dropTextColumns removes the extra columns whose cell in the first record is not a number, such as a
+// symbol code or name. Later cells of the kept columns must be numbers.
+func dropTextColumns(extra map[string]int, record []string) {
+	for name, i := range extra {
+		cell := strings.TrimSpace(record[i])
+		if _, err := strconv.ParseFloat(cell, 64); cell != "" && err != nil {
+			delete(extra, name)
+		}
+	}
+}
+
+// rowReader parses the fields of one record, keeping the first error
+type rowReader struct {
+	record  []string
+	columns map[Field]int
+	err     error
+}
+
+func (r *rowReader) cell(field Field) (string, bool) {
+	i, ok := r.columns[field]
+	if !ok || i >= len(r.record) {
+		return "", false
+	}
+	return strings.TrimSpace(r.record[i]), true
+}
+
+// number parses a numeric field; missing or empty optional fields are 0
+func (r *rowReader) number(field Field, required bool) float64 {
+	cell, ok := r.cell(field)
+	if !ok || cell == "" {
+		if required && r.err == nil {
+			r.err = fmt.Errorf("missing %s", field)
+		}
+		return 0
+	}
+	v, err := strconv.ParseFloat(cell, 64)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("%s: invalid number %q", field, cell)
+	}
+	return v
+}
+
+// time parses the date column, combined with the time column when both exist
+func (r *rowReader) time(opts *CSVOptions) time.Time {
+	date, hasDate := r.cell(FieldDate)
+	clock, hasTime := r.cell(FieldTime)
+	if !hasDate {
+		date, clock, hasTime = clock, "", false
+	}
+
+	layouts := defaultLayouts
+	if opts.TimeLayout != "" {
+		layouts = append([]string{opts.TimeLayout}, layouts...)
+	}
+	t, err := parseTime(date, layouts, opts.location())
+	if err != nil {
+		if r.err == nil {
+			r.err = err
+		}
+		return time.Time{}
+	}
+
+	if hasTime && clock != "" {
+		ofDay, err := parseTime(clock, timeOfDayLayouts, time.UTC)
+		if err != nil {
+			if r.err == nil {
+				r.err = err
+			}
+			return time.Time{}
+		}
+		t = time.Date(t.Year(), t.Month(), t.Day(), ofDay.Hour(), ofDay.Minute(), ofDay.Second(), 0, t.Location())
+	}
+	return t
+}
+
+// parseTime tries each layout in turn
+func parseTime(value string, layouts []string, loc *time.Location) (time.Time, error) {
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse time %q", value)
+}
+
+// WriteResultCSV writes the result's output lines as CSV columns, one row per bar. When times is not nil,
+// the first column holds the bar time and every output must have one value per time. NaN is written
+// as an empty cell; scalar variables are not written.
+func WriteResultCSV(w io.Writer, result *types.FormulaResult, times []time.Time, opts *CSVOptions) error {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	layout := opts.TimeLayout
+	if layout == "" {
+		layout = "2006-01-02 15:04:05"
+	}
+
+	n := len(times)
+	if times == nil {
+		for _, out := range result.Outputs {
+			n = max(n, len(out.Data))
+		}
+	}
+	for _, out := range result.Outputs {
+		if times != nil && len(out.Data) != n {
+			return fmt.Errorf("csv: output %s has %d values for %d bars", out.Name, len(out.Data), n)
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = opts.comma()
+
+	header := make([]string, 0, len(result.Outputs)+1)
+	if times != nil {
+		header = append(header, "time")
+	}
+	for _, out := range result.Outputs {
+		header = append(header, out.Name)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(header))
+	for i := 0; i < n; i++ {
+		record = record[:0]
+		if times != nil {
+			record = append(record, times[i].In(opts.location()).Format(layout))
+		}
+		for _, out := range result.Outputs {
+			cell := ""
+			if i < len(out.Data) && !math.IsNaN(out.Data[i]) {
+				cell = strconv.FormatFloat(out.Data[i], 'f', -1, 64)
+			}
+			record = append(record, cell)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,147 @@
+package dataio
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DTrader-store/formula-go/engine"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+const dailyCSV = `date,open,high,low,close,volume,amount
+2024-01-02,10.0,10.5,9.8,10.2,1000,10200
+2024-01-03,10.2,10.8,10.1,10.6,1200,12720
+2024-01-04,10.6,10.7,10.0,10.1,900,9090
+`
+
+func TestReadCSV(t *testing.T) {
+	data, err := ReadCSV(strings.NewReader(dailyCSV), &CSVOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("ReadCSV error: %v", err)
+	}
+	if len(data) != 3 {
+		t.Fatalf("Expected 3 bars, got %d", len(data))
+	}
+	if data[1].Close != 10.6 || data[1].Volume != 1200 {
+		t.Errorf("Unexpected bar: %+v", data[1])
+	}
+	if !data[0].Time.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected time: %v", data[0].Time)
+	}
+}
+
+func TestReadCSVChineseHeadersAndTSV(t *testing.T) {
+	input := "日期\t时间\t开盘\t最高\t最低\t收盘\t成交量\n" +
+		"2024/01/02\t0935\t10\t10.2\t9.9\t10.1\t500\n" +
+		"2024/01/02\t0940\t10.1\t10.3\t10\t10.2\t600\n"
+	shanghai := time.FixedZone("CST", 8*3600)
+
+	data, err := ReadCSV(strings.NewReader(input), &CSVOptions{Comma: '\t', Location: shanghai})
+	if err != nil {
+		t.Fatalf("ReadCSV error: %v", err)
+	}
+	expected := time.Date(2024, 1, 2, 9, 40, 0, 0, shanghai)
+	if !data[1].Time.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, data[1].Time)
+	}
+	if data[1].Amount != 0 {
+		t.Errorf("Expected missing amount column to read as 0, got %v", data[1].Amount)
+	}
+}
+
+func TestReadCSVColumnMappingAndExtra(t *testing.T) {
+	input := `ts,o,h,l,c,pe
+20240102,1,2,0.5,1.5,12.5
+20240103,1.5,2,1,1.8,
+`
+	opts := &CSVOptions{
+		Columns: map[Field]string{
+			FieldDate: "ts", FieldOpen: "o", FieldHigh: "h", FieldLow: "l", FieldClose: "c",
+		},
+		TimeLayout: "20060102",
+		KeepExtra:  true,
+	}
+
+	series, err := ReadCSVSeries(strings.NewReader(input), opts)
+	if err != nil {
+		t.Fatalf("ReadCSVSeries error: %v", err)
+	}
+	if series.Len() != 2 || series.Close[1] != 1.8 {
+		t.Errorf("Unexpected series: %+v", series)
+	}
+	if series.Extra["pe"][0] != 12.5 || !math.IsNaN(series.Extra["pe"][1]) {
+		t.Errorf("Expected pe column [12.5 NaN], got %v", series.Extra["pe"])
+	}
+}
+
+func TestReadCSVExtraSkipsTextColumns(t *testing.T) {
+	input := `code,name,date,open,high,low,close,turnover
+SH600000,浦发银行,2024-01-02,1,2,0.5,1.5,0.8
+SH600000,浦发银行,2024-01-03,1.5,2,1,1.8,1.2
+`
+	series, err := ReadCSVSeries(strings.NewReader(input), &CSVOptions{KeepExtra: true})
+	if err != nil {
+		t.Fatalf("ReadCSVSeries error: %v", err)
+	}
+	if len(series.Extra) != 1 || series.Extra["turnover"][1] != 1.2 {
+		t.Errorf("Expected only the turnover column kept, got %v", series.Extra)
+	}
+
+	if _, err := ReadCSVSeries(strings.NewReader(input+"SH600000,浦发银行,2024-01-04,1,2,1,1,x\n"), &CSVOptions{KeepExtra: true}); err == nil {
+		t.Error("Expected error for text in a numeric extra column")
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"missing column", "date,open,high,low\n2024-01-02,1,2,1\n", "missing close column"},
+		{"invalid number", "date,open,high,low,close\n2024-01-02,1,x,1,1\n", "line 2"},
+		{"invalid row", "date,open,high,low,close\n2024-01-02,1,2,1,1\n2024-01-03,1,1,2,1\n", "line 3"},
+		{"invalid date", "date,open,high,low,close\nyesterday,1,2,1,1\n", "cannot parse time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadCSV(strings.NewReader(tt.input), nil)
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestWriteResultCSV(t *testing.T) {
+	data, err := ReadCSV(strings.NewReader(dailyCSV), &CSVOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("ReadCSV error: %v", err)
+	}
+	result, err := engine.NewFormulaEngine().Run("MA2 := MA(CLOSE, 2)", data)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	times := types.SeriesFromMarketData(data).Time
+	var buf bytes.Buffer
+	if err := WriteResultCSV(&buf, result, times, &CSVOptions{TimeLayout: "2006-01-02", Location: time.UTC}); err != nil {
+		t.Fatalf("WriteResultCSV error: %v", err)
+	}
+
+	expected := "time,MA2\n2024-01-02,\n2024-01-03,10.399999999999999\n2024-01-04,10.35\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+
+	if err := WriteResultCSV(&buf, result, times[:2], nil); err == nil {
+		t.Error("Expected error for output length mismatch")
+	}
+}
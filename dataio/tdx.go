@@ -0,0 +1,228 @@
+package dataio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// tdxRecordSize is the size of one bar in .day, .lc1 and .lc5 files
+const tdxRecordSize = 32
+
+// ChinaTime is the exchange timezone, used for TDX files unless TDXOptions.Location is set
+var ChinaTime = time.FixedZone("CST", 8*3600)
+
+// TDXOptions configures decoding of local TDX files
+type TDXOptions struct {
+	PriceScale float64        // Multiplier for the integer prices of .day files; 0 picks 0.01 or 0.001 from the symbol
+	Location   *time.Location // Timezone of the bar times; ChinaTime when nil
+}
+
+func (o *TDXOptions) location() *time.Location {
+	if o == nil || o.Location == nil {
+		return ChinaTime
+	}
+	return o.Location
+}
+
+// TDXFile is a bar file found in a TDX vipdoc directory
+type TDXFile struct {
+	Market string       // "sh", "sz" or "bj"
+	Code   string       // Six-digit symbol code
+	Period types.Period // PeriodDay, PeriodMin1 or PeriodMin5
+	Path   string
+}
+
+// Symbol returns the market-prefixed code, e.g. "sh600000"
+func (f TDXFile) Symbol() string {
+	return f.Market + f.Code
+}
+
+// ReadTDXDay decodes a .day file. Each 32-byte record holds the date as YYYYMMDD, open, high, low and
+// close as integers scaled by 1/scale, amount as float32 and volume in shares. Daily bars are stamped
+// at the 15:00 close.
+func ReadTDXDay(r io.Reader, scale float64, loc *time.Location) ([]*types.MarketData, error) {
+	var bars []*types.MarketData
+	err := readTDXRecords(r, func(i int, rec []byte) error {
+		date := binary.LittleEndian.Uint32(rec[0:4])
+		year, month, day := int(date/10000), time.Month(date/100%100), int(date%100)
+		if month < 1 || month > 12 || day < 1 || day > 31 {
+			return fmt.Errorf("record %d: invalid date %d", i, date)
+		}
+
+		price := func(offset int) float64 {
+			return float64(binary.LittleEndian.Uint32(rec[offset:offset+4])) * scale
+		}
+		bars = append(bars, &types.MarketData{
+			Time:   time.Date(year, month, day, 15, 0, 0, 0, loc),
+			Open:   price(4),
+			High:   price(8),
+			Low:    price(12),
+			Close:  price(16),
+			Amount: float64(math.Float32frombits(binary.LittleEndian.Uint32(rec[20:24]))),
+			Volume: float64(binary.LittleEndian.Uint32(rec[24:28])),
+		})
+		return nil
+	})
+	return bars, err
+}
+
+// ReadTDXMinute decodes a .lc1 or .lc5 file. Each 32-byte record holds the date packed as
+// (year-2004)*2048 + month*100 + day, the minutes since midnight of the bar's close, open, high, low,
+// close and amount as float32 and volume in shares.
+func ReadTDXMinute(r io.Reader, loc *time.Location) ([]*types.MarketData, error) {
+	var bars []*types.MarketData
+	err := readTDXRecords(r, func(i int, rec []byte) error {
+		date := int(binary.LittleEndian.Uint16(rec[0:2]))
+		minutes := int(binary.LittleEndian.Uint16(rec[2:4]))
+		year, month, day := date/2048+2004, time.Month(date%2048/100), date%2048%100
+		if month < 1 || month > 12 || day < 1 || day > 31 || minutes >= 24*60 {
+			return fmt.Errorf("record %d: invalid date %d or time %d", i, date, minutes)
+		}
+
+		float := func(offset int) float64 {
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(rec[offset : offset+4])))
+		}
+		bars = append(bars, &types.MarketData{
+			Time:   time.Date(year, month, day, minutes/60, minutes%60, 0, 0, loc),
+			Open:   float(4),
+			High:   float(8),
+			Low:    float(12),
+			Close:  float(16),
+			Amount: float(20),
+			Volume: float64(binary.LittleEndian.Uint32(rec[24:28])),
+		})
+		return nil
+	})
+	return bars, err
+}
+
+// readTDXRecords calls fn for every 32-byte record in r
+func readTDXRecords(r io.Reader, fn func(i int, rec []byte) error) error {
+	rec := make([]byte, tdxRecordSize)
+	for i := 0; ; i++ {
+		_, err := io.ReadFull(r, rec)
+		if err == io.EOF {
+			return nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("tdx: truncated record %d", i)
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(i, rec); err != nil {
+			return fmt.Errorf("tdx: %w", err)
+		}
+	}
+}
+
+// ReadTDXFile reads a .day, .lc1 or .lc5 file, choosing the decoder from the extension and the
+// price scale of .day files from the symbol in the file name
+func ReadTDXFile(path string, opts *TDXOptions) ([]*types.MarketData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	name := strings.ToLower(filepath.Base(path))
+	switch filepath.Ext(name) {
+	case ".day":
+		scale := 0.0
+		if opts != nil {
+			scale = opts.PriceScale
+		}
+		if scale == 0 {
+			stem := strings.TrimSuffix(name, ".day")
+			if len(stem) < 3 {
+				return nil, fmt.Errorf("tdx: cannot tell symbol from file name %s", path)
+			}
+			scale = DayPriceScale(stem[:2], stem[2:])
+		}
+		return ReadTDXDay(f, scale, opts.location())
+	case ".lc1", ".lc5":
+		return ReadTDXMinute(f, opts.location())
+	}
+	return nil, fmt.Errorf("tdx: unsupported file %s", path)
+}
+
+// DayPriceScale returns the price multiplier of .day files: funds, ETFs and bonds store prices
+// in thousandths, everything else in hundredths
+func DayPriceScale(market, code string) float64 {
+	prefixes := map[string][]string{
+		"sh": {"11", "12", "13", "50", "51", "52", "56", "58"},
+		"sz": {"10", "11", "12", "13", "15", "16", "18"},
+	}
+	for _, prefix := range prefixes[strings.ToLower(market)] {
+		if strings.HasPrefix(code, prefix) {
+			return 0.001
+		}
+	}
+	return 0.01
+}
+
+// tdxDirs maps the bar directories under vipdoc/<market> to their file extension and period
+var tdxDirs = map[string]struct {
+	ext    string
+	period types.Period
+}{
+	"lday":    {".day", types.PeriodDay},
+	"minline": {".lc1", types.PeriodMin1},
+	"fzline":  {".lc5", types.PeriodMin5},
+}
+
+// ScanTDXDir lists the bar files in a TDX vipdoc directory (vipdoc/sh/lday/sh600000.day, ...),
+// sorted by symbol and period
+func ScanTDXDir(root string) ([]TDXFile, error) {
+	var files []TDXFile
+	for _, market := range []string{"sh", "sz", "bj"} {
+		for dir, kind := range tdxDirs {
+			entries, err := os.ReadDir(filepath.Join(root, market, dir))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			for _, entry := range entries {
+				name := strings.ToLower(entry.Name())
+				if entry.IsDir() || filepath.Ext(name) != kind.ext {
+					continue
+				}
+				stem := strings.TrimSuffix(name, kind.ext)
+				code := strings.TrimPrefix(stem, market)
+				if code == stem || len(code) != 6 {
+					continue
+				}
+				if _, err := strconv.Atoi(code); err != nil {
+					continue
+				}
+				files = append(files, TDXFile{
+					Market: market,
+					Code:   code,
+					Period: kind.period,
+					Path:   filepath.Join(root, market, dir, entry.Name()),
+				})
+			}
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Symbol() != files[j].Symbol() {
+			return files[i].Symbol() < files[j].Symbol()
+		}
+		return files[i].Period < files[j].Period
+	})
+	return files, nil
+}
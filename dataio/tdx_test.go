@@ -0,0 +1,132 @@
+package dataio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// dayRecord encodes one .day record with prices in hundredths
+func dayRecord(date uint32, open, high, low, close uint32, amount float32, volume uint32) []byte {
+	rec := make([]byte, tdxRecordSize)
+	binary.LittleEndian.PutUint32(rec[0:], date)
+	binary.LittleEndian.PutUint32(rec[4:], open)
+	binary.LittleEndian.PutUint32(rec[8:], high)
+	binary.LittleEndian.PutUint32(rec[12:], low)
+	binary.LittleEndian.PutUint32(rec[16:], close)
+	binary.LittleEndian.PutUint32(rec[20:], math.Float32bits(amount))
+	binary.LittleEndian.PutUint32(rec[24:], volume)
+	return rec
+}
+
+// minuteRecord encodes one .lc1/.lc5 record
+func minuteRecord(year, month, day, minutes int, open, high, low, close, amount float32, volume uint32) []byte {
+	rec := make([]byte, tdxRecordSize)
+	binary.LittleEndian.PutUint16(rec[0:], uint16((year-2004)*2048+month*100+day))
+	binary.LittleEndian.PutUint16(rec[2:], uint16(minutes))
+	for i, v := range []float32{open, high, low, close, amount} {
+		binary.LittleEndian.PutUint32(rec[4+4*i:], math.Float32bits(v))
+	}
+	binary.LittleEndian.PutUint32(rec[24:], volume)
+	return rec
+}
+
+func TestReadTDXDay(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(dayRecord(20240102, 1050, 1080, 1040, 1072, 5.5e7, 5200000))
+	buf.Write(dayRecord(20240103, 1072, 1100, 1060, 1095, 6e7, 5500000))
+
+	bars, err := ReadTDXDay(&buf, 0.01, ChinaTime)
+	if err != nil {
+		t.Fatalf("ReadTDXDay error: %v", err)
+	}
+	if len(bars) != 2 {
+		t.Fatalf("Expected 2 bars, got %d", len(bars))
+	}
+	if math.Abs(bars[0].Close-10.72) > 1e-9 || bars[0].Volume != 5200000 || bars[0].Amount != 5.5e7 {
+		t.Errorf("Unexpected bar: %+v", bars[0])
+	}
+	if !bars[1].Time.Equal(time.Date(2024, 1, 3, 15, 0, 0, 0, ChinaTime)) {
+		t.Errorf("Unexpected time: %v", bars[1].Time)
+	}
+
+	if _, err := ReadTDXDay(bytes.NewReader(make([]byte, 20)), 0.01, ChinaTime); err == nil {
+		t.Error("Expected error for truncated record")
+	}
+	if _, err := ReadTDXDay(bytes.NewReader(dayRecord(20241399, 1, 1, 1, 1, 0, 0)), 0.01, ChinaTime); err == nil {
+		t.Error("Expected error for invalid date")
+	}
+}
+
+func TestReadTDXMinute(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(minuteRecord(2024, 3, 15, 9*60+35, 10.5, 10.6, 10.4, 10.55, 1e6, 95000))
+
+	bars, err := ReadTDXMinute(&buf, ChinaTime)
+	if err != nil {
+		t.Fatalf("ReadTDXMinute error: %v", err)
+	}
+	if !bars[0].Time.Equal(time.Date(2024, 3, 15, 9, 35, 0, 0, ChinaTime)) {
+		t.Errorf("Unexpected time: %v", bars[0].Time)
+	}
+	if float32(bars[0].Close) != 10.55 || bars[0].Volume != 95000 {
+		t.Errorf("Unexpected bar: %+v", bars[0])
+	}
+}
+
+func TestScanAndReadTDXDir(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel string, data []byte) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("sh/lday/sh600000.day", dayRecord(20240102, 1050, 1080, 1040, 1072, 0, 100))
+	write("sh/lday/sh510300.day", dayRecord(20240102, 3501, 3520, 3490, 3510, 0, 100))
+	write("sh/fzline/sh600000.lc5", minuteRecord(2024, 1, 2, 9*60+35, 10.5, 10.6, 10.4, 10.55, 0, 100))
+	write("sz/lday/sz000001.day", dayRecord(20240102, 900, 910, 890, 905, 0, 100))
+	write("sz/lday/readme.txt", []byte("ignored"))
+
+	files, err := ScanTDXDir(root)
+	if err != nil {
+		t.Fatalf("ScanTDXDir error: %v", err)
+	}
+	symbols := []string{"sh510300", "sh600000", "sh600000", "sz000001"}
+	if len(files) != len(symbols) {
+		t.Fatalf("Expected %d files, got %+v", len(symbols), files)
+	}
+	for i, f := range files {
+		if f.Symbol() != symbols[i] {
+			t.Errorf("File %d: expected %s, got %s", i, symbols[i], f.Symbol())
+		}
+	}
+	if files[1].Period != types.PeriodDay || files[2].Period != types.PeriodMin5 {
+		t.Errorf("Unexpected periods: %s, %s", files[1].Period, files[2].Period)
+	}
+
+	etf, err := ReadTDXFile(files[0].Path, nil)
+	if err != nil {
+		t.Fatalf("ReadTDXFile error: %v", err)
+	}
+	if math.Abs(etf[0].Close-3.51) > 1e-9 {
+		t.Errorf("Expected ETF prices in thousandths, got %v", etf[0].Close)
+	}
+
+	stock, err := ReadTDXFile(files[1].Path, nil)
+	if err != nil {
+		t.Fatalf("ReadTDXFile error: %v", err)
+	}
+	if math.Abs(stock[0].Close-10.72) > 1e-9 {
+		t.Errorf("Expected stock prices in hundredths, got %v", stock[0].Close)
+	}
+}
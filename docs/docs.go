@@ -0,0 +1,212 @@
+// Package docs renders the function reference from the descriptors in a function registry.
+// The reference in this directory is generated with go generate and checked by the tests.
+package docs
+
+//go:generate go run ./gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DTrader-store/formula-go/interpreter"
+)
+
+// Files the reference is written to, relative to this directory
+const (
+	MarkdownFile = "functions.md"
+	JSONFile     = "functions.json"
+)
+
+// Check reports the functions whose descriptors lack a category, a description in either
+// language or an example
+func Check(descriptors []*interpreter.Descriptor) error {
+	var missing []string
+	for _, desc := range descriptors {
+		var fields []string
+		if desc.Category == "" {
+			fields = append(fields, "category")
+		}
+		if desc.Description.Zh == "" {
+			fields = append(fields, "Chinese description")
+		}
+		if desc.Description.En == "" {
+			fields = append(fields, "English description")
+		}
+		if desc.Example == "" {
+			fields = append(fields, "example")
+		}
+		if len(fields) > 0 {
+			missing = append(missing, fmt.Sprintf("%s (%s)", desc.Name, strings.Join(fields, ", ")))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("functions without docs: %s", strings.Join(missing, "; "))
+	}
+	return nil
+}
+
+// Function is the JSON form of a function's descriptor
+type Function struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Usage       string   `json:"usage"`
+	Params      []Param  `json:"params"`
+	Variadic    bool     `json:"variadic,omitempty"`
+	Returns     string   `json:"returns"` // "float", "bool", "int", or "args" when it follows the arguments
+	Category    string   `json:"category"`
+	Zh          string   `json:"zh"`
+	En          string   `json:"en"`
+	Example     string   `json:"example"`
+	Future      bool     `json:"future"`
+	WarmUp      int      `json:"warmUp,omitempty"`
+	WarmUpParam string   `json:"warmUpParam,omitempty"`
+}
+
+// Param is the JSON form of a function parameter
+type Param struct {
+	Name     string   `json:"name"`
+	Kind     string   `json:"kind"`
+	Optional bool     `json:"optional,omitempty"`
+	Default  *float64 `json:"default,omitempty"`
+}
+
+// Functions converts descriptors to their JSON form
+func Functions(descriptors []*interpreter.Descriptor) []Function {
+	functions := make([]Function, len(descriptors))
+	for i, desc := range descriptors {
+		params := make([]Param, len(desc.Params))
+		for j, p := range desc.Params {
+			params[j] = Param{Name: p.Name, Kind: p.Kind.String(), Optional: p.Optional || p.Default != nil, Default: p.Default}
+		}
+		functions[i] = Function{
+			Name:        desc.Name,
+			Aliases:     desc.Aliases,
+			Usage:       desc.Usage(),
+			Params:      params,
+			Variadic:    desc.Variadic,
+			Returns:     returns(desc),
+			Category:    desc.Category,
+			Zh:          desc.Description.Zh,
+			En:          desc.Description.En,
+			Example:     desc.Example,
+			Future:      desc.Future,
+			WarmUp:      desc.WarmUp,
+			WarmUpParam: desc.WarmUpParam,
+		}
+	}
+	return functions
+}
+
+// returns names the kind of a function's result
+func returns(desc *interpreter.Descriptor) string {
+	if kind, fixed := desc.ResultKind(); fixed {
+		return kind.String()
+	}
+	return "args"
+}
+
+// JSON renders the reference as indented JSON
+func JSON(descriptors []*interpreter.Descriptor) ([]byte, error) {
+	if err := Check(descriptors); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(struct {
+		Functions []Function `json:"functions"`
+	}{Functions(descriptors)}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Markdown renders the reference as Markdown, grouped by category
+func Markdown(descriptors []*interpreter.Descriptor) ([]byte, error) {
+	if err := Check(descriptors); err != nil {
+		return nil, err
+	}
+
+	byCategory := make(map[string][]*interpreter.Descriptor)
+	var categories []string
+	for _, desc := range descriptors {
+		if _, ok := byCategory[desc.Category]; !ok {
+			categories = append(categories, desc.Category)
+		}
+		byCategory[desc.Category] = append(byCategory[desc.Category], desc)
+	}
+	sort.Strings(categories)
+
+	var b bytes.Buffer
+	b.WriteString("<!-- Code generated by go generate ./docs; DO NOT EDIT. -->\n\n")
+	b.WriteString("# 函数参考\n\n")
+	fmt.Fprintf(&b, "共 %d 个内置函数。\n\n", len(descriptors))
+	for _, category := range categories {
+		fmt.Fprintf(&b, "- **%s**:", category)
+		for _, desc := range byCategory[category] {
+			fmt.Fprintf(&b, " [%s](#%s)", desc.Name, strings.ToLower(desc.Name))
+		}
+		b.WriteString("\n")
+	}
+
+	for _, category := range categories {
+		fmt.Fprintf(&b, "\n## %s\n", category)
+		for _, desc := range byCategory[category] {
+			function(&b, desc)
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// function writes the Markdown section of one function
+func function(b *bytes.Buffer, desc *interpreter.Descriptor) {
+	fmt.Fprintf(b, "\n### %s\n\n", desc.Name)
+	fmt.Fprintf(b, "`%s`\n\n", desc.Usage())
+	fmt.Fprintf(b, "%s\n\n%s\n\n", desc.Description.Zh, desc.Description.En)
+
+	if len(desc.Params) > 0 {
+		b.WriteString("| 参数 | 类型 | 默认值 |\n|------|------|--------|\n")
+		for _, p := range desc.Params {
+			value := ""
+			switch {
+			case p.Default != nil:
+				value = strconv.FormatFloat(*p.Default, 'f', -1, 64)
+			case p.Optional:
+				value = "可省略"
+			}
+			fmt.Fprintf(b, "| `%s` | %s | %s |\n", p.Name, p.Kind, value)
+		}
+		b.WriteString("\n")
+	}
+
+	kind := returns(desc)
+	if kind == "args" {
+		kind = "同参数"
+	}
+	fmt.Fprintf(b, "- 返回: %s\n", kind)
+	if len(desc.Aliases) > 0 {
+		fmt.Fprintf(b, "- 别名: %s\n", strings.Join(desc.Aliases, ", "))
+	}
+	if warmUp := warmUp(desc); warmUp != "" {
+		fmt.Fprintf(b, "- 预热: %s 根 K 线\n", warmUp)
+	}
+	if desc.Future {
+		b.WriteString("- ⚠️ 未来函数：会引用之后的 K 线，历史信号可能随新数据改变\n")
+	}
+	fmt.Fprintf(b, "- 示例: `%s`\n", desc.Example)
+}
+
+// warmUp describes the bars a function needs before its first valid value, e.g. "period + 1"
+func warmUp(desc *interpreter.Descriptor) string {
+	switch {
+	case desc.WarmUpParam != "" && desc.WarmUp != 0:
+		return fmt.Sprintf("`%s` + %d", desc.WarmUpParam, desc.WarmUp)
+	case desc.WarmUpParam != "":
+		return "`" + desc.WarmUpParam + "`"
+	case desc.WarmUp != 0:
+		return strconv.Itoa(desc.WarmUp)
+	}
+	return ""
+}
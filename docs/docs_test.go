@@ -0,0 +1,86 @@
+package docs
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+func TestGeneratedReferenceIsCurrent(t *testing.T) {
+	descriptors := interpreter.NewFunctionRegistry().Descriptors()
+	for file, render := range map[string]func([]*interpreter.Descriptor) ([]byte, error){
+		MarkdownFile: Markdown,
+		JSONFile:     JSON,
+	} {
+		expected, err := render(descriptors)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		actual, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(expected, actual) {
+			t.Errorf("%s is out of date; run go generate ./docs", file)
+		}
+	}
+}
+
+func TestReferenceContents(t *testing.T) {
+	data, err := JSON(interpreter.NewFunctionRegistry().Descriptors())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reference struct {
+		Functions []Function `json:"functions"`
+	}
+	if err := json.Unmarshal(data, &reference); err != nil {
+		t.Fatal(err)
+	}
+
+	byName := make(map[string]Function)
+	for _, f := range reference.Functions {
+		byName[f.Name] = f
+	}
+	if sma := byName["SMA"]; sma.Usage != "SMA(data, period[, weight])" || !sma.Params[2].Optional || sma.WarmUpParam != "period" {
+		t.Errorf("Unexpected SMA entry %+v", sma)
+	}
+	if cross := byName["CROSS"]; cross.Returns != "bool" {
+		t.Errorf("Expected CROSS to return bool, got %q", cross.Returns)
+	}
+	if iff := byName["IF"]; iff.Returns != "args" || len(iff.Aliases) != 1 {
+		t.Errorf("Unexpected IF entry %+v", iff)
+	}
+}
+
+func TestMissingDocs(t *testing.T) {
+	registry := interpreter.NewFunctionRegistry()
+	registry.Register("ZIG", func(args []*interpreter.Value, _ []*types.MarketData) (*interpreter.Value, error) {
+		return args[0], nil
+	})
+	registry.RegisterDescriptor(&interpreter.Descriptor{
+		Name:        "PEAK",
+		Category:    "引用函数",
+		Description: interpreter.Localized{Zh: "波峰"},
+		Example:     "PEAK(C)",
+		Future:      true,
+		Fn: func(args []*interpreter.Value, _ []*types.MarketData) (*interpreter.Value, error) {
+			return args[0], nil
+		},
+	})
+
+	_, err := Markdown(registry.Descriptors())
+	if err == nil {
+		t.Fatal("Expected error for functions without docs")
+	}
+	for _, want := range []string{"ZIG (category, Chinese description, English description, example)", "PEAK (English description)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+}
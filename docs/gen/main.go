@@ -0,0 +1,40 @@
+// Command gen writes the function reference for the built-in functions. It is run by
+// go generate in the docs directory and fails when a function lacks docs.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DTrader-store/formula-go/docs"
+	"github.com/DTrader-store/formula-go/interpreter"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory to write the reference to")
+	flag.Parse()
+
+	if err := generate(*dir); err != nil {
+		fmt.Fprintln(os.Stderr, "gen:", err)
+		os.Exit(1)
+	}
+}
+
+// generate writes the Markdown and JSON reference to dir
+func generate(dir string) error {
+	descriptors := interpreter.NewFunctionRegistry().Descriptors()
+	markdown, err := docs.Markdown(descriptors)
+	if err != nil {
+		return err
+	}
+	data, err := docs.JSON(descriptors)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, docs.MarkdownFile), markdown, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, docs.JSONFile), data, 0o644)
+}
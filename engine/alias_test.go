@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+func TestEngineShortAliases(t *testing.T) {
+	marketData := createTestData()
+
+	result, err := NewFormulaEngine().Run(`
+		A := C - CLOSE
+		B := O + H + L - OPEN - HIGH - LOW
+		D := V + VOL - 2 * VOLUME
+		E := AMO - AMOUNT
+	`, marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	for _, out := range result.Outputs {
+		for i, v := range out.Data {
+			if v != 0 {
+				t.Errorf("%s[%d]: expected alias to match built-in, got %v", out.Name, i, v)
+			}
+		}
+	}
+}
+
+func TestEngineCaseInsensitiveNames(t *testing.T) {
+	marketData := createTestData()
+
+	result, err := NewFormulaEngine().Run(`
+		ma5 := ma(close, 5)
+		Diff := Close - MA5
+	`, marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if result.Outputs[0].Name != "ma5" || result.Outputs[1].Name != "Diff" {
+		t.Errorf("Expected outputs to keep their spelling, got %s and %s", result.Outputs[0].Name, result.Outputs[1].Name)
+	}
+	if result.Outputs[1].Data[4] != marketData[4].Close-result.Outputs[0].Data[4] {
+		t.Errorf("Unexpected Diff[4]: %v", result.Outputs[1].Data[4])
+	}
+}
+
+func TestEngineUserVariableShadowsAlias(t *testing.T) {
+	result, err := NewFormulaEngine().Run(`
+		C := 1
+		X := C + 1
+	`, createTestData())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if result.Variables["X"] != 2 {
+		t.Errorf("Expected user C to shadow the alias, got X = %v", result.Variables["X"])
+	}
+}
+
+func TestEngineConstants(t *testing.T) {
+	engine := NewFormulaEngine()
+	marketData := createTestData()
+
+	program, err := engine.Compile(`
+		X := IF(CLOSE > 105, CLOSE, DRAWNULL)
+		TURNOVER := VOL / CAPITAL
+		RATIO := CAPITAL / TOTALCAPITAL
+	`)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+
+	result, err := engine.ExecuteWithSymbol(program, marketData, &types.SymbolInfo{Code: "600000", Capital: 5000, TotalCapital: 20000})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !math.IsNaN(result.Outputs[0].Data[0]) {
+		t.Errorf("Expected DRAWNULL to be NaN, got %v", result.Outputs[0].Data[0])
+	}
+	if result.Outputs[1].Data[0] != marketData[0].Volume/5000 {
+		t.Errorf("Unexpected turnover %v", result.Outputs[1].Data[0])
+	}
+	if result.Variables["RATIO"] != 0.25 {
+		t.Errorf("Expected RATIO 0.25, got %v", result.Variables["RATIO"])
+	}
+
+	result, err = engine.Execute(program, marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !math.IsNaN(result.Variables["RATIO"]) {
+		t.Errorf("Expected NaN without symbol info, got %v", result.Variables["RATIO"])
+	}
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+func TestEngineCalendarVariables(t *testing.T) {
+	engine := NewFormulaEngine()
+	marketData := createDailyData(10) // 2024-01-01 (Mon) .. 2024-01-12 (Fri)
+
+	result, err := engine.Run(`
+		D := DATE
+		AFTER := DATE > 1240103
+		FRIDAY := WEEKDAY = 5
+		Y := YEAR * 100 + MONTH
+		DD := DAY
+		T := TIME
+		H := HOUR * 100 + MINUTE
+		AGO := DAYSTOTODAY
+		SINCE := DATETODAY(DATE)
+	`, marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	outputs := make(map[string][]float64)
+	for _, output := range result.Outputs {
+		outputs[output.Name] = output.Data
+	}
+
+	if outputs["D"][0] != 1240101 || outputs["D"][9] != 1240112 {
+		t.Errorf("Unexpected DATE values: %v", outputs["D"])
+	}
+	if outputs["AFTER"][2] != 0 || outputs["AFTER"][3] != 1 {
+		t.Errorf("Unexpected DATE comparison: %v", outputs["AFTER"])
+	}
+	if outputs["FRIDAY"][4] != 1 || outputs["FRIDAY"][3] != 0 {
+		t.Errorf("Unexpected WEEKDAY values: %v", outputs["FRIDAY"])
+	}
+	if outputs["Y"][0] != 202401 || outputs["DD"][9] != 12 {
+		t.Errorf("Unexpected YEAR/MONTH/DAY values: %v %v", outputs["Y"], outputs["DD"])
+	}
+	if outputs["T"][0] != 150000 || outputs["H"][0] != 1500 {
+		t.Errorf("Unexpected TIME/HOUR/MINUTE values: %v %v", outputs["T"], outputs["H"])
+	}
+
+	expected := float64(types.DaysBetween(marketData[0].Time, time.Now()))
+	if outputs["AGO"][0] != expected || outputs["SINCE"][0] != expected {
+		t.Errorf("Expected %v days to today, got %v and %v", expected, outputs["AGO"][0], outputs["SINCE"][0])
+	}
+}
+
+func TestEngineCalendarRequiresTime(t *testing.T) {
+	engine := NewFormulaEngine()
+	if _, err := engine.Run("D := DATE", createTestData()); err == nil {
+		t.Error("Expected error for DATE without timestamps")
+	}
+}
@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// MemoryDataProvider is an in-memory data provider holding bars per symbol, e.g. for tests
+type MemoryDataProvider struct {
+	mu   sync.RWMutex
+	bars map[string][]*types.MarketData
+}
+
+// NewMemoryDataProvider creates an empty provider
+func NewMemoryDataProvider() *MemoryDataProvider {
+	return &MemoryDataProvider{bars: make(map[string][]*types.MarketData)}
+}
+
+// Add stores the bars of a symbol, replacing any previous bars. Bars must carry times in ascending order.
+func (p *MemoryDataProvider) Add(code string, bars []*types.MarketData) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bars[strings.ToUpper(code)] = bars
+}
+
+// LoadMarketData returns the bars of code within [from, to]
+func (p *MemoryDataProvider) LoadMarketData(code string, from, to time.Time) ([]*types.MarketData, error) {
+	p.mu.RLock()
+	bars, ok := p.bars[strings.ToUpper(code)]
+	p.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown symbol %s", code)
+	}
+
+	result := make([]*types.MarketData, 0, len(bars))
+	for _, bar := range bars {
+		if !bar.Time.Before(from) && !bar.Time.After(to) {
+			result = append(result, bar)
+		}
+	}
+	return result, nil
+}
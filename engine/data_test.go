@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// createIndexData returns daily index bars on the dates of createDailyData(n), with close = 1000 + i
+// and the bar at skip missing
+func createIndexData(n, skip int) []*types.MarketData {
+	data := make([]*types.MarketData, 0, n)
+	for i, bar := range createDailyData(n) {
+		if i == skip {
+			continue
+		}
+		price := 1000 + float64(i)
+		index := types.NewMarketData(price, price, price+5, price-5, 1e6, 1e9)
+		index.Time = bar.Time
+		data = append(data, index)
+	}
+	return data
+}
+
+func TestEngineIndexVariables(t *testing.T) {
+	provider := NewMemoryDataProvider()
+	provider.Add("SH000001", createIndexData(10, 4))
+
+	engine := NewFormulaEngine()
+	engine.SetDataProvider(provider)
+
+	result, err := engine.Run(`
+		RS := CLOSE / INDEXC
+		IV := INDEXV
+	`, createDailyData(10))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	rs := result.Outputs[0].Data
+	if rs[0] != 1.0/1000 || rs[9] != 10.0/1009 {
+		t.Errorf("Unexpected relative strength: %v", rs)
+	}
+	// Bar 4 is missing on the index: forward fill repeats the previous close with no volume
+	if rs[4] != 5.0/1003 {
+		t.Errorf("Expected forward-filled index close on bar 4, got %v", rs[4])
+	}
+	if result.Outputs[1].Data[4] != 0 {
+		t.Errorf("Expected no index volume on bar 4, got %v", result.Outputs[1].Data[4])
+	}
+}
+
+func TestEngineIndexFillsFirstBar(t *testing.T) {
+	// The index has bars before the data but is suspended on the data's first day
+	index := createIndexData(12, 2)
+	provider := NewMemoryDataProvider()
+	provider.Add("SH000001", index)
+
+	engine := NewFormulaEngine()
+	engine.SetDataProvider(provider)
+
+	result, err := engine.Run("IC := INDEXC", createDailyData(12)[2:])
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if ic := result.Outputs[0].Data; ic[0] != 1001 || ic[1] != 1003 {
+		t.Errorf("Expected the previous index close on the first bar, got %v", ic)
+	}
+}
+
+func TestEngineIndexDifferentTimeOfDay(t *testing.T) {
+	// The data is stamped at midnight and the index at the 15:00 close
+	data := createDailyData(5)
+	for _, bar := range data {
+		bar.Time = bar.Time.Add(-15 * time.Hour)
+	}
+	provider := NewMemoryDataProvider()
+	provider.Add("SH000001", createIndexData(5, -1))
+
+	engine := NewFormulaEngine()
+	engine.SetDataProvider(provider)
+
+	result, err := engine.Run("IC := INDEXC", data)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if ic := result.Outputs[0].Data; ic[3] != 1003 || ic[4] != 1004 {
+		t.Errorf("Expected the index close on every day including the last, got %v", ic)
+	}
+}
+
+func TestEngineSymbolReference(t *testing.T) {
+	provider := NewMemoryDataProvider()
+	provider.Add("SZ399001", createIndexData(10, 4))
+
+	engine := NewFormulaEngine()
+	engine.SetDataProvider(provider)
+	engine.SetFillPolicy(types.FillNaN)
+
+	result, err := engine.Run(`X := "SZ399001$CLOSE" - "sz399001$C"`, createDailyData(10))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if result.Outputs[0].Data[0] != 0 || !math.IsNaN(result.Outputs[0].Data[4]) {
+		t.Errorf("Expected 0 and NaN for the missing bar, got %v", result.Outputs[0].Data)
+	}
+
+	engine.SetBenchmark("SZ399001")
+	if _, err := engine.Run("X := INDEXC", createDailyData(10)); err != nil {
+		t.Errorf("Expected custom benchmark to resolve, got %v", err)
+	}
+}
+
+func TestEngineSymbolReferenceErrors(t *testing.T) {
+	data := createDailyData(5)
+
+	if _, err := NewFormulaEngine().Run("X := INDEXC", data); err == nil {
+		t.Error("Expected error without a data provider")
+	}
+
+	engine := NewFormulaEngine()
+	engine.SetDataProvider(NewMemoryDataProvider())
+	if _, err := engine.Run("X := INDEXC", data); err == nil {
+		t.Error("Expected error for unknown benchmark symbol")
+	}
+	if _, err := engine.Run("X := INDEXC", createTestData()); err == nil {
+		t.Error("Expected error for bars without time")
+	}
+
+	provider := NewMemoryDataProvider()
+	provider.Add("SH000001", createIndexData(5, -1))
+	engine.SetDataProvider(provider)
+	if _, err := engine.Run(`X := "SH000001$PE"`, data); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+func TestEngineDescriptorValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		message string
+	}{
+		{"too few", "X := MA(C)", "MA takes 2 arguments, got 1"},
+		{"too many", "X := SMA(C, 5, 1, 2)", "SMA takes 2 to 3 arguments, got 4"},
+		{"series", "X := HHV(5, 2)", "HHV argument 1 (data) must be a series"},
+		{"number", "X := REF(C, C)", "REF argument 2 (period) must be a single number"},
+		{"text", "X := ABS('银行')", "ABS argument 1 (x) must be a number, got text"},
+		{"alias", "X := IFF(C > O, 1)", "IF takes 3 arguments, got 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFormulaEngine().Run(tt.input, createTestData())
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestEngineRegisterDescriptor(t *testing.T) {
+	engine := NewFormulaEngine()
+	three := 3.0
+	engine.Functions().RegisterDescriptor(&interpreter.Descriptor{
+		Name:    "Shift",
+		Aliases: []string{"back"},
+		Params: []interpreter.Param{
+			{Name: "data", Kind: interpreter.ArgSeries},
+			{Name: "n", Kind: interpreter.ArgNumber, Default: &three},
+		},
+		Returns:  types.KindInt,
+		Category: "引用函数",
+		Fn: func(args []*interpreter.Value, _ []*types.MarketData) (*interpreter.Value, error) {
+			n := int(args[1].Single)
+			result := make([]float64, len(args[0].Array))
+			for i := n; i < len(result); i++ {
+				result[i] = args[0].Array[i-n]
+			}
+			return interpreter.NewArrayValue(result), nil
+		},
+	})
+
+	data := createDailyData(10)
+	result, err := engine.Run("A := SHIFT(C);\nB := BACK(C, 3)", data)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	a, b := result.Output("A"), result.Output("B")
+	if a.Kind != types.KindInt {
+		t.Errorf("Expected the declared result kind, got %v", a.Kind)
+	}
+	for i := range data {
+		if a.Data[i] != b.Data[i] {
+			t.Fatalf("Expected the default period at bar %d: %v vs %v", i, a.Data[i], b.Data[i])
+		}
+	}
+	if _, err := engine.Run("A := SHIFT(C, C)", data); err == nil {
+		t.Error("Expected error for a series period")
+	}
+
+	desc, ok := engine.Functions().Lookup("back")
+	if !ok || desc.Name != "SHIFT" || desc.Usage() != "SHIFT(data[, n])" || desc.Arity() != "1 to 2 arguments" {
+		t.Errorf("Unexpected descriptor %+v", desc)
+	}
+}
+
+func TestEngineDescriptors(t *testing.T) {
+	descriptors := NewFormulaEngine().Functions().Descriptors()
+	seen := make(map[string]bool)
+	for i, desc := range descriptors {
+		if i > 0 && descriptors[i-1].Name >= desc.Name {
+			t.Errorf("Descriptors not sorted at %s", desc.Name)
+		}
+		if desc.Category == "" || desc.Description.Zh == "" || desc.Description.En == "" {
+			t.Errorf("%s: missing category or description", desc.Name)
+		}
+		seen[desc.Name] = true
+	}
+	for _, name := range []string{"MA", "IF", "FINANCE", "INBLOCK"} {
+		if !seen[name] {
+			t.Errorf("Missing descriptor for %s", name)
+		}
+	}
+	if seen["IFF"] {
+		t.Error("Aliases should not be listed as functions")
+	}
+
+	if kind, fixed := mustLookup(t, "CROSS").ResultKind(); !fixed || kind != types.KindBool {
+		t.Errorf("Expected CROSS to return bool, got %v", kind)
+	}
+	if _, fixed := mustLookup(t, "IF").ResultKind(); fixed {
+		t.Error("Expected the kind of IF to depend on its arguments")
+	}
+}
+
+// mustLookup returns the descriptor of a built-in function
+func mustLookup(t *testing.T, name string) *interpreter.Descriptor {
+	t.Helper()
+	desc, ok := NewFormulaEngine().Functions().Lookup(name)
+	if !ok {
+		t.Fatalf("Missing function %s", name)
+	}
+	return desc
+}
@@ -2,6 +2,7 @@
 package engine
 
 import (
+	"github.com/DTrader-store/formula-go/errors"
 	"github.com/DTrader-store/formula-go/interpreter"
 	"github.com/DTrader-store/formula-go/lexer"
 	"github.com/DTrader-store/formula-go/parser"
@@ -11,12 +12,87 @@ import (
 
 // FormulaEngine is the main engine for compiling and executing formulas
 type FormulaEngine struct {
-	// You can add caching or other optimization features here
+	functions   *interpreter.FunctionRegistry  // shared by every execution of this engine
+	repository  interpreter.FormulaRepository  // resolves references to other formulas
+	symbols     interpreter.SymbolInfoProvider // supplies symbol info for ExecuteSymbol
+	data        interpreter.DataProvider       // loads other symbols' bars, e.g. the benchmark index
+	benchmark   string                         // symbol read by INDEXC, INDEXO, ...
+	fill        types.FillPolicy               // fill policy for bars missing on other symbols
+	adjustment  types.Adjustment               // price adjustment applied when symbol info is known
+	hideForming bool                           // whether #PERIOD leaves out the period bar still forming
 }
 
+// DefaultBenchmark is the benchmark index used by INDEXC and the other INDEX* variables unless changed
+const DefaultBenchmark = "SH000001"
+
 // NewFormulaEngine creates a new formula engine
 func NewFormulaEngine() *FormulaEngine {
-	return &FormulaEngine{}
+	return &FormulaEngine{
+		functions: interpreter.NewFunctionRegistry(),
+		benchmark: DefaultBenchmark,
+	}
+}
+
+// Functions returns the function registry used by this engine, so callers can register extra functions
+func (e *FormulaEngine) Functions() *interpreter.FunctionRegistry {
+	return e.functions
+}
+
+// SetRepository sets the repository that formula references such as "MACD.DIF" resolve against
+func (e *FormulaEngine) SetRepository(repo interpreter.FormulaRepository) {
+	e.repository = repo
+}
+
+// SetSymbolProvider sets the provider ExecuteSymbol looks symbol info up in
+func (e *FormulaEngine) SetSymbolProvider(provider interpreter.SymbolInfoProvider) {
+	e.symbols = provider
+}
+
+// SetDataProvider sets the provider that other symbols' data, such as INDEXC or "SZ399001$CLOSE", is loaded from
+func (e *FormulaEngine) SetDataProvider(provider interpreter.DataProvider) {
+	e.data = provider
+}
+
+// SetBenchmark sets the symbol read by INDEXC, INDEXO, INDEXH, INDEXL, INDEXV and INDEXA
+func (e *FormulaEngine) SetBenchmark(code string) {
+	e.benchmark = code
+}
+
+// SetFillPolicy sets how bars missing on another symbol, e.g. suspended days, are filled when aligning it
+func (e *FormulaEngine) SetFillPolicy(policy types.FillPolicy) {
+	e.fill = policy
+}
+
+// SetAdjustment sets how prices are adjusted for corporate actions before execution. The actions come from
+// the symbol info, so with a mode set, executions without a symbol (Execute, ExecuteWithParams,
+// ExecuteSeries, Run, ...) fail instead of running on unadjusted bars; pass those bars already adjusted
+// with types.Adjust and leave the mode at AdjustNone. Sessions from NewSession are never adjusted.
+func (e *FormulaEngine) SetAdjustment(mode types.Adjustment) {
+	e.adjustment = mode
+}
+
+// SetIncludeFormingBar sets whether expression#PERIOD shows the period bar still forming on the last
+// bar of the data. It is included by default, as TDX does, so that value changes as bars of the same
+// period are appended; exclude it for backtests that must not repaint.
+func (e *FormulaEngine) SetIncludeFormingBar(include bool) {
+	e.hideForming = !include
+}
+
+// configure applies the engine's functions, repository and data provider to an interpreter
+func (e *FormulaEngine) configure(interp *interpreter.Interpreter) *interpreter.Interpreter {
+	interp.SetFunctionRegistry(e.functions)
+	interp.SetRepository(e.repository)
+	interp.SetIncludeFormingBar(!e.hideForming)
+	if e.data != nil {
+		interp.SetDataProvider(e.data, e.benchmark, e.fill)
+	}
+	return interp
+}
+
+// NewSession creates an interpreter configured like the engine's executions, for running a formula a
+// statement at a time with ExecuteStatement while keeping its variables, e.g. in a REPL
+func (e *FormulaEngine) NewSession(series *types.Series) *interpreter.Interpreter {
+	return e.configure(interpreter.NewSeriesInterpreter(series))
 }
 
 // Compile compiles a formula string into an AST
@@ -38,10 +114,107 @@ func (e *FormulaEngine) Compile(formula string) (*ast.Program, error) {
 	return program, nil
 }
 
+// Input is what a compiled program runs on. Bars or Series gives the market data; the other fields
+// are optional and can be combined freely.
+type Input struct {
+	Bars    []*types.MarketData  // Market data as rows; ignored when Series is set
+	Series  *types.Series        // Columnar market data, bound to formula variables without copying
+	Params  map[string]float64   // Formula parameters, bound as scalar variables
+	Columns map[string][]float64 // Extra per-bar columns, bound as formula variables
+	Symbol  *types.SymbolInfo    // Symbol constants (CAPITAL, FINANCE, ...) and corporate actions
+	Code    string               // Symbol looked up in the symbol provider when Symbol is nil
+}
+
+// ExecuteInput executes a compiled program on in. When an adjustment mode is set, prices are adjusted for
+// the symbol's corporate actions first, and in must name a symbol. Symbol fields evaluate to NaN when
+// no symbol is given or the provider has no entry for Code.
+func (e *FormulaEngine) ExecuteInput(program *ast.Program, in Input) (*types.FormulaResult, error) {
+	if e.adjustment != types.AdjustNone && in.Symbol == nil && in.Code == "" {
+		return nil, errors.NewRuntimeError("price adjustment needs symbol info; pass a symbol or adjust the bars with types.Adjust")
+	}
+	info := in.Symbol
+	if info == nil && in.Code != "" {
+		if e.symbols == nil {
+			return nil, errors.NewRuntimeError("no symbol provider set")
+		}
+		info, _ = e.symbols.LookupSymbol(in.Code)
+	}
+
+	series, bars := in.Series, in.Bars
+	if series != nil {
+		if err := series.Validate(); err != nil {
+			return nil, errors.NewRuntimeError(err.Error())
+		}
+	}
+	if info != nil && len(info.Actions) > 0 && e.adjustment != types.AdjustNone {
+		if series != nil {
+			bars = series.MarketData()
+		}
+		adjusted, err := types.Adjust(bars, info.Actions, e.adjustment)
+		if err != nil {
+			return nil, errors.NewRuntimeError(err.Error())
+		}
+		series, bars = nil, adjusted
+	}
+
+	var interp *interpreter.Interpreter
+	if series != nil {
+		interp = e.configure(interpreter.NewSeriesInterpreter(series))
+	} else {
+		interp = e.configure(interpreter.NewInterpreter(bars))
+	}
+	for name, value := range in.Params {
+		interp.SetVariable(name, interpreter.NewSingleValue(value))
+	}
+	for name, values := range in.Columns {
+		if err := interp.BindColumn(name, values); err != nil {
+			return nil, err
+		}
+	}
+	interp.SetSymbolInfo(info)
+	return interp.Execute(program)
+}
+
 // Execute executes a compiled program with market data
 func (e *FormulaEngine) Execute(program *ast.Program, marketData []*types.MarketData) (*types.FormulaResult, error) {
-	interp := interpreter.NewInterpreter(marketData)
-	return interp.Execute(program)
+	return e.ExecuteInput(program, Input{Bars: marketData})
+}
+
+// ExecuteSeries executes a compiled program on columnar market data. Formula variables are bound to
+// the series columns without copying them.
+func (e *FormulaEngine) ExecuteSeries(program *ast.Program, series *types.Series) (*types.FormulaResult, error) {
+	return e.ExecuteInput(program, Input{Series: series})
+}
+
+// ExecuteSeriesWithParams executes a compiled program on columnar market data with formula parameters
+// bound as scalar variables
+func (e *FormulaEngine) ExecuteSeriesWithParams(program *ast.Program, series *types.Series, params map[string]float64) (*types.FormulaResult, error) {
+	return e.ExecuteInput(program, Input{Series: series, Params: params})
+}
+
+// ExecuteWithParams executes a compiled program with formula parameters bound as scalar variables
+func (e *FormulaEngine) ExecuteWithParams(program *ast.Program, marketData []*types.MarketData, params map[string]float64) (*types.FormulaResult, error) {
+	return e.ExecuteInput(program, Input{Bars: marketData, Params: params})
+}
+
+// ExecuteWithColumns executes a compiled program with extra per-bar columns bound as formula variables
+func (e *FormulaEngine) ExecuteWithColumns(program *ast.Program, marketData []*types.MarketData, columns map[string][]float64) (*types.FormulaResult, error) {
+	return e.ExecuteInput(program, Input{Bars: marketData, Columns: columns})
+}
+
+// ExecuteWithSymbol executes a compiled program with the symbol's constants (CAPITAL, TOTALCAPITAL, FINANCE, ...)
+// available, after adjusting prices for the symbol's corporate actions when an adjustment mode is set
+func (e *FormulaEngine) ExecuteWithSymbol(program *ast.Program, marketData []*types.MarketData, info *types.SymbolInfo) (*types.FormulaResult, error) {
+	return e.ExecuteInput(program, Input{Bars: marketData, Symbol: info})
+}
+
+// ExecuteSymbol executes a compiled program on a symbol's market data, with its info taken from the
+// symbol provider. Symbol fields evaluate to NaN when the provider has no entry for code.
+func (e *FormulaEngine) ExecuteSymbol(program *ast.Program, code string, marketData []*types.MarketData) (*types.FormulaResult, error) {
+	if e.symbols == nil {
+		return nil, errors.NewRuntimeError("no symbol provider set")
+	}
+	return e.ExecuteInput(program, Input{Bars: marketData, Code: code})
 }
 
 // Run compiles and executes a formula in one step
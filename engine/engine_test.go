@@ -1,7 +1,9 @@
 package engine
 
 import (
+	"fmt"
 	"math"
+	"strings"
 	"testing"
 
 	"github.com/DTrader-store/formula-go/types"
@@ -207,6 +209,116 @@ func TestEngineIF(t *testing.T) {
 	}
 }
 
+func TestEngineSMAWeighted(t *testing.T) {
+	result, err := NewFormulaEngine().Run("S := SMA(CLOSE, 3, 1)", createTestData())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Y = (1*X + 2*Y') / 3, seeded with the first close
+	expected := []float64{105, (103 + 2*105.0) / 3}
+	expected = append(expected, (107+2*expected[1])/3)
+	for i, want := range expected {
+		if got := result.Outputs[0].Data[i]; math.Abs(got-want) > 1e-9 {
+			t.Errorf("Index %d: expected %f, got %f", i, want, got)
+		}
+	}
+}
+
+func TestEngineSUMCumulative(t *testing.T) {
+	result, err := NewFormulaEngine().Run("TOTAL := SUM(CLOSE, 0)", createTestData())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// SUM(X, 0) keeps a running total from the first bar
+	for i, want := range []float64{105, 208, 315, 425} {
+		if got := result.Outputs[0].Data[i]; got != want {
+			t.Errorf("Index %d: expected %v, got %v", i, want, got)
+		}
+	}
+}
+
+func TestEngineScalarBranches(t *testing.T) {
+	// MAX(X, 0) and IF(cond, X, 0) are used by RSI, DMI and OBV
+	result, err := NewFormulaEngine().Run(`
+		UP := MAX(CLOSE - OPEN, 0)
+		VA := IF(CLOSE > OPEN, VOLUME, 0)
+	`, createTestData())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	for i, want := range []float64{5, 0, 4, 3, 0} {
+		if got := result.Outputs[0].Data[i]; got != want {
+			t.Errorf("UP[%d]: expected %v, got %v", i, want, got)
+		}
+	}
+	for i, want := range []float64{1000, 0, 1200, 1300, 0} {
+		if got := result.Outputs[1].Data[i]; got != want {
+			t.Errorf("VA[%d]: expected %v, got %v", i, want, got)
+		}
+	}
+
+}
+
+func TestEngineScalarSeriesMixes(t *testing.T) {
+	result, err := NewFormulaEngine().Run(`
+		LO := MIN(CLOSE, 108)
+		HI := MIN(108, CLOSE)
+		UP := CROSS(CLOSE, 110)
+		DOWN := CROSS(110, CLOSE)
+		BOTH := CROSS(CLOSE, OPEN)
+	`, createTestData())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	for i, want := range []float64{105, 103, 107, 108, 108, 108} {
+		if result.Outputs[0].Data[i] != want || result.Outputs[1].Data[i] != want {
+			t.Errorf("MIN[%d]: expected %v, got %v and %v", i, want, result.Outputs[0].Data[i], result.Outputs[1].Data[i])
+		}
+	}
+	crosses := func(data []float64) []int {
+		var bars []int
+		for i, v := range data {
+			if v == 1 {
+				bars = append(bars, i)
+			}
+		}
+		return bars
+	}
+	if got := crosses(result.Outputs[2].Data); fmt.Sprint(got) != "[5 7]" {
+		t.Errorf("Expected CROSS(CLOSE, 110) on bars 5 and 7, got %v", got)
+	}
+	if got := crosses(result.Outputs[3].Data); fmt.Sprint(got) != "[4 6]" {
+		t.Errorf("Expected CROSS(110, CLOSE) on bars 4 and 6, got %v", got)
+	}
+	if result.Outputs[4].Kind != types.KindBool {
+		t.Errorf("Expected CROSS to stay a signal, got %v", result.Outputs[4].Kind)
+	}
+
+	if _, err := NewFormulaEngine().Run("X := CROSS(1, 2)", createTestData()); err == nil {
+		t.Error("Expected CROSS to require a series")
+	}
+}
+
+func TestEngineDivisionByZero(t *testing.T) {
+	// A flat bar only invalidates itself
+	result, err := NewFormulaEngine().Run("X := CLOSE / (CLOSE - OPEN); Y := 1 / (CLOSE - CLOSE)", createTestData())
+	if err != nil {
+		t.Fatalf("Expected series division by zero to give NaN, got %v", err)
+	}
+	if x := result.Outputs[0].Data; x[0] != 105.0/5 || !math.IsNaN(result.Outputs[1].Data[0]) {
+		t.Errorf("Unexpected quotients %v, %v", x, result.Outputs[1].Data)
+	}
+
+	_, err = NewFormulaEngine().Run("X := 1 / 0", createTestData())
+	if err == nil || !strings.Contains(err.Error(), "division by zero") {
+		t.Errorf("Expected a division by zero error for scalars, got %v", err)
+	}
+}
+
 func TestEngineHHVLLV(t *testing.T) {
 	engine := NewFormulaEngine()
 	marketData := createTestData()
@@ -294,3 +406,27 @@ func TestEngineComplexFormula(t *testing.T) {
 		}
 	}
 }
+
+func TestEngineOutputDeclarations(t *testing.T) {
+	formula := `{布林线}
+MID := MA(CLOSE, 3);
+UPPER : MID + 2, COLORRED, LINETHICK2;
+LOWER : MID - 2; // 下轨`
+	result, err := NewFormulaEngine().Run(formula, createTestData())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Declared outputs are reported along with the intermediate variables, in order
+	if len(result.Outputs) != 3 || result.Outputs[0].Name != "MID" || result.Outputs[1].Name != "UPPER" || result.Outputs[2].Name != "LOWER" {
+		t.Fatalf("Expected MID, UPPER and LOWER, got %v", result.Columns())
+	}
+	for _, out := range result.Outputs {
+		if out.Style != nil {
+			t.Errorf("Expected drawing attributes to leave %s unstyled, got %+v", out.Name, out.Style)
+		}
+	}
+	if got := result.Outputs[1].Data[2] - result.Outputs[2].Data[2]; math.Abs(got-4) > 1e-9 {
+		t.Errorf("Expected UPPER - LOWER = 4, got %f", got)
+	}
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+func TestEngineExtendedFields(t *testing.T) {
+	marketData := createTestData()
+	for i, bar := range marketData {
+		bar.OpenInterest = 5000 + float64(i)*100
+		bar.Settle = bar.Close - 1
+		bar.Advance = 300
+		bar.Decline = 200
+		if i >= 2 {
+			bar.Extra = map[string]float64{"SENTIMENT": float64(i)}
+		}
+	}
+
+	engine := NewFormulaEngine()
+	result, err := engine.Run(`
+		OI_UP := OPI > REF(OPI, 1)
+		BASIS := CLOSE - SETTLE
+		BREADTH := ADVANCE / (ADVANCE + DECLINE)
+		MOOD := SENTIMENT * 2
+	`, marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if result.Outputs[0].Data[1] != 1 {
+		t.Errorf("Expected rising open interest, got %v", result.Outputs[0].Data[1])
+	}
+	if result.Outputs[1].Data[3] != 1 {
+		t.Errorf("Expected basis 1, got %v", result.Outputs[1].Data[3])
+	}
+	if result.Outputs[2].Data[0] != 0.6 {
+		t.Errorf("Expected breadth 0.6, got %v", result.Outputs[2].Data[0])
+	}
+
+	mood := result.Outputs[3].Data
+	if !math.IsNaN(mood[0]) || !math.IsNaN(mood[1]) {
+		t.Errorf("Expected NaN for bars without the extra column, got %v", mood[:2])
+	}
+	if mood[5] != 10 {
+		t.Errorf("Expected MOOD[5] = 10, got %v", mood[5])
+	}
+}
+
+func TestEngineExtraDoesNotReplaceBuiltins(t *testing.T) {
+	marketData := createTestData()
+	marketData[0].Extra = map[string]float64{"CLOSE": -1}
+
+	result, err := NewFormulaEngine().Run("C0 := CLOSE", marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if result.Outputs[0].Data[0] != marketData[0].Close {
+		t.Errorf("Expected built-in CLOSE, got %v", result.Outputs[0].Data[0])
+	}
+
+	// Short aliases are resolved after the extra columns are bound, and must not be hidden by them
+	marketData[0].Extra = map[string]float64{"C": -1, "vol": -2, "V": -3, "SENTIMENT": 1}
+	result, err = NewFormulaEngine().Run("C0 := C\nV0 := VOL\nV1 := V", marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if result.Outputs[0].Data[0] != marketData[0].Close {
+		t.Errorf("Expected C to be CLOSE, got %v", result.Outputs[0].Data[0])
+	}
+	for _, out := range result.Outputs[1:] {
+		if out.Data[0] != marketData[0].Volume {
+			t.Errorf("Expected %s to be VOLUME, got %v", out.Name, out.Data[0])
+		}
+	}
+}
+
+func TestEngineExecuteWithColumns(t *testing.T) {
+	engine := NewFormulaEngine()
+	marketData := createTestData()
+
+	program, err := engine.Compile("RATIO := CLOSE / FLOAT_SHARES")
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+
+	shares := make([]float64, len(marketData))
+	for i := range shares {
+		shares[i] = 100
+	}
+	result, err := engine.ExecuteWithColumns(program, marketData, map[string][]float64{"FLOAT_SHARES": shares})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(result.Outputs) != 1 {
+		t.Fatalf("Expected only the formula variable as output, got %d outputs", len(result.Outputs))
+	}
+	if result.Outputs[0].Data[0] != marketData[0].Close/100 {
+		t.Errorf("Unexpected ratio %v", result.Outputs[0].Data[0])
+	}
+
+	_, err = engine.ExecuteWithColumns(program, marketData, map[string][]float64{"FLOAT_SHARES": shares[:3]})
+	if err == nil {
+		t.Error("Expected error for column length mismatch")
+	}
+}
+
+func TestResampleKeepsExtendedFields(t *testing.T) {
+	marketData := createDailyData(5)
+	for i, bar := range marketData {
+		bar.OpenInterest = float64(i)
+	}
+	weeks, _, err := types.Resample(marketData, types.PeriodWeek)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if weeks[0].OpenInterest != 4 {
+		t.Errorf("Expected weekly open interest from the last bar, got %v", weeks[0].OpenInterest)
+	}
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+func TestEngineOutputKinds(t *testing.T) {
+	formula := `MA2 := MA(CLOSE, 2);
+UP := C > O;
+X := CROSS(C, MA2);
+N := BARSLAST(UP);
+M := IF(UP, N, 0);
+P := IF(UP, C, 0);
+S := SUM(UP, 3);
+D := -N;
+Y := YEAR;
+H := HHV(UP, 2)`
+	result, err := NewFormulaEngine().Run(formula, createDailyData(10))
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	expected := map[string]types.ValueKind{
+		"MA2": types.KindFloat,
+		"UP":  types.KindBool,
+		"X":   types.KindBool,
+		"N":   types.KindInt,
+		"M":   types.KindInt,
+		"P":   types.KindFloat,
+		"S":   types.KindInt,
+		"D":   types.KindInt,
+		"Y":   types.KindInt,
+		"H":   types.KindBool,
+	}
+	for name, kind := range expected {
+		out := result.Output(name)
+		if out == nil {
+			t.Fatalf("Missing output %s", name)
+		}
+		if out.Kind != kind {
+			t.Errorf("Expected %s to be %v, got %v", name, kind, out.Kind)
+		}
+	}
+}
+
+func TestEngineCustomFunctionKind(t *testing.T) {
+	engine := NewFormulaEngine()
+	engine.Functions().Register("CROSS", func(args []*interpreter.Value, _ []*types.MarketData) (*interpreter.Value, error) {
+		return args[0], nil
+	})
+	result, err := engine.Run("X := CROSS(C, O)", createTestData())
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if result.Outputs[0].Kind != types.KindFloat {
+		t.Errorf("Expected replaced built-in to keep its own kind, got %v", result.Outputs[0].Kind)
+	}
+}
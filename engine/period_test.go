@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// createDailyData creates weekday bars starting on Monday 2024-01-01 with close = 1, 2, 3, ...
+func createDailyData(n int) []*types.MarketData {
+	data := make([]*types.MarketData, 0, n)
+	day := time.Date(2024, 1, 1, 15, 0, 0, 0, time.Local)
+	for len(data) < n {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			price := float64(len(data) + 1)
+			bar := types.NewMarketData(price, price, price+0.5, price-0.5, 100, 100*price)
+			bar.Time = day
+			data = append(data, bar)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return data
+}
+
+func TestEnginePeriodReferenceAlignment(t *testing.T) {
+	engine := NewFormulaEngine()
+	marketData := createDailyData(13) // weeks of 5, 5 and 3 bars
+
+	result, err := engine.Run("WC := CLOSE#WEEK", marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Weekly closes are 5, 10, 13. A week's value appears on the bar that completes it;
+	// earlier bars of the week still see the previous week.
+	nan := math.NaN()
+	expected := []float64{nan, nan, nan, nan, 5, 5, 5, 5, 5, 10, 10, 10, 13}
+	data := result.Outputs[0].Data
+	for i := range expected {
+		if math.IsNaN(expected[i]) != math.IsNaN(data[i]) || (!math.IsNaN(expected[i]) && data[i] != expected[i]) {
+			t.Errorf("Index %d: expected %v, got %v", i, expected[i], data[i])
+		}
+	}
+}
+
+func TestEnginePeriodExpressionFunctions(t *testing.T) {
+	engine := NewFormulaEngine()
+	marketData := createDailyData(20) // four full weeks
+
+	result, err := engine.Run(`
+		N := 2
+		WMA := MA(CLOSE, N)#WEEK
+		UP := CLOSE#WEEK > MA(CLOSE, 2)#WEEK
+	`, marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Weekly closes: 5, 10, 15, 20 -> MA2: NaN, 7.5, 12.5, 17.5
+	wma := result.Outputs[0].Data
+	if wma[9] != 7.5 || wma[14] != 12.5 || wma[19] != 17.5 {
+		t.Errorf("Unexpected weekly MA values: %v", wma)
+	}
+	if wma[12] != 7.5 {
+		t.Errorf("Expected mid-week bar to see the previous week, got %v", wma[12])
+	}
+
+	up := result.Outputs[1].Data
+	if up[19] != 1 {
+		t.Errorf("Expected weekly close above its MA, got %v", up[19])
+	}
+}
+
+func TestEnginePeriodRequiresTime(t *testing.T) {
+	engine := NewFormulaEngine()
+	if _, err := engine.Run("W := CLOSE#WEEK", createTestData()); err == nil {
+		t.Error("Expected error for data without timestamps")
+	}
+}
+
+func TestEnginePeriodMustBeLonger(t *testing.T) {
+	engine := NewFormulaEngine()
+	_, err := engine.Run("X := CLOSE#MIN5", createDailyData(10))
+	if err == nil || !strings.Contains(err.Error(), "not a longer period") {
+		t.Errorf("Expected an error for a period shorter than the data, got %v", err)
+	}
+}
+
+func TestEnginePeriodFormingBar(t *testing.T) {
+	marketData := createDailyData(14) // weeks of 5, 5 and 4 bars
+
+	run := func(engine *FormulaEngine, bars []*types.MarketData) []float64 {
+		t.Helper()
+		result, err := engine.Run("WC := MA(CLOSE, 2)#WEEK", bars)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		return result.Outputs[0].Data
+	}
+
+	// Included by default: the last bar sees the forming week, which repaints as the week goes on
+	engine := NewFormulaEngine()
+	if before, after := run(engine, marketData[:13]), run(engine, marketData); before[12] != 11.5 || after[12] != 7.5 {
+		t.Errorf("Expected the forming week on the last bar, got %v then %v", before[12], after[12])
+	}
+
+	// Excluded: the last bar sees the last completed week, so appending a bar changes nothing
+	engine.SetIncludeFormingBar(false)
+	before, after := run(engine, marketData[:13]), run(engine, marketData)
+	for i := range before {
+		if before[i] != after[i] && !(math.IsNaN(before[i]) && math.IsNaN(after[i])) {
+			t.Errorf("Index %d changed from %v to %v after appending a bar", i, before[i], after[i])
+		}
+	}
+	if after[13] != 7.5 {
+		t.Errorf("Expected the last completed week on the last bar, got %v", after[13])
+	}
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/DTrader-store/formula-go/interpreter"
+)
+
+// MemoryRepository is an in-memory formula repository for user formulas
+type MemoryRepository struct {
+	mu       sync.RWMutex
+	engine   *FormulaEngine
+	formulas map[string]*interpreter.FormulaDefinition
+}
+
+// NewMemoryRepository creates an empty repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		engine:   NewFormulaEngine(),
+		formulas: make(map[string]*interpreter.FormulaDefinition),
+	}
+}
+
+// Add compiles a formula and stores it under name, replacing any previous definition
+func (r *MemoryRepository) Add(name, source string, params ...interpreter.FormulaParam) error {
+	program, err := r.engine.Compile(source)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.formulas[strings.ToUpper(name)] = &interpreter.FormulaDefinition{
+		Name:    name,
+		Program: program,
+		Params:  params,
+	}
+	return nil
+}
+
+// LookupFormula returns the formula stored under name (case-insensitive)
+func (r *MemoryRepository) LookupFormula(name string) (*interpreter.FormulaDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.formulas[strings.ToUpper(name)]
+	return def, ok
+}
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+func TestEngineFormulaReference(t *testing.T) {
+	repo := NewMemoryRepository()
+	err := repo.Add("MYMA", `
+		FAST := MA(CLOSE, N)
+		SLOW := MA(CLOSE, N * 2)
+	`, interpreter.FormulaParam{Name: "N", Default: 2})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	engine := NewFormulaEngine()
+	engine.SetRepository(repo)
+	marketData := createTestData()
+
+	result, err := engine.Run(`
+		A := "MYMA.SLOW"
+		B := MYMA.SLOW(3)
+		C := myma(3)
+	`, marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	checks := []struct {
+		output string
+		period int
+	}{
+		{"A", 4}, // default N=2 -> SLOW uses 4
+		{"B", 6}, // N=3 -> SLOW uses 6
+		{"C", 3}, // call without output selects the first output, FAST
+	}
+	for i, check := range checks {
+		data := result.Outputs[i].Data
+		last := len(marketData) - 1
+		expected := 0.0
+		for j := 0; j < check.period; j++ {
+			expected += marketData[last-j].Close
+		}
+		expected /= float64(check.period)
+		if math.Abs(data[last]-expected) > 1e-9 {
+			t.Errorf("%s: expected %.4f, got %.4f", check.output, expected, data[last])
+		}
+	}
+}
+
+func TestEngineFormulaReferenceRunsOnce(t *testing.T) {
+	calls := 0
+	engine := NewFormulaEngine()
+	engine.Functions().Register("TICK", func(args []*interpreter.Value, _ []*types.MarketData) (*interpreter.Value, error) {
+		calls++
+		return args[0], nil
+	})
+
+	repo := NewMemoryRepository()
+	if err := repo.Add("BASE", "X := TICK(CLOSE)"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	engine.SetRepository(repo)
+
+	_, err := engine.Run(`
+		A := BASE.X
+		B := "BASE.X" + 1
+		C := BASE()
+	`, createTestData())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected referenced formula to run once, ran %d times", calls)
+	}
+}
+
+func TestEngineFormulaReferenceErrors(t *testing.T) {
+	repo := NewMemoryRepository()
+	_ = repo.Add("PING", "P := PONG.Q + 1")
+	_ = repo.Add("PONG", "Q := PING.P")
+	_ = repo.Add("ONE", "X := CLOSE", interpreter.FormulaParam{Name: "N", Default: 1})
+
+	engine := NewFormulaEngine()
+	engine.SetRepository(repo)
+	marketData := createTestData()
+
+	tests := []struct {
+		name    string
+		formula string
+		message string
+	}{
+		{"cycle", "A := PING.P", "circular formula reference: PING -> PONG -> PING"},
+		{"unknown formula", "A := NOPE.X", "undefined formula: NOPE"},
+		{"unknown output", "A := ONE.Y", "ONE has no output Y"},
+		{"too many params", "A := ONE.X(1, 2)", "at most 1 parameters"},
+		{"array param", "A := ONE.X(CLOSE)", "must be a number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := engine.Run(tt.formula, marketData)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %q", tt.message, err.Error())
+			}
+		})
+	}
+}
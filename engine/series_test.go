@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/parser/ast"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+func TestExecuteSeriesMatchesRows(t *testing.T) {
+	engine := NewFormulaEngine()
+	marketData := createTestData()
+
+	program, err := engine.Compile(`
+		MA3 := MA(CLOSE, 3)
+		SPREAD := HIGH - LOW
+		OI := OPI
+	`)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+
+	rows, err := engine.Execute(program, marketData)
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	columns, err := engine.ExecuteSeries(program, types.SeriesFromMarketData(marketData))
+	if err != nil {
+		t.Fatalf("ExecuteSeries error: %v", err)
+	}
+
+	for k, out := range rows.Outputs {
+		for i, v := range out.Data {
+			got := columns.Outputs[k].Data[i]
+			if v != got && !(math.IsNaN(v) && math.IsNaN(got)) {
+				t.Errorf("%s[%d]: rows %v, series %v", out.Name, i, v, got)
+			}
+		}
+	}
+}
+
+func TestExecuteSeriesBindsColumnsWithoutCopy(t *testing.T) {
+	series := types.NewSeries(3)
+	copy(series.Close, []float64{1, 2, 3})
+
+	result, err := NewFormulaEngine().ExecuteSeries(mustCompile(t, "X := C"), series)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if &result.Outputs[0].Data[0] != &series.Close[0] {
+		t.Error("Expected CLOSE to be bound to the series column without copying")
+	}
+}
+
+func TestExecuteSeriesWithParams(t *testing.T) {
+	series := types.NewSeries(3)
+	copy(series.Close, []float64{1, 2, 3})
+
+	result, err := NewFormulaEngine().ExecuteSeriesWithParams(mustCompile(t, "X := C * K"), series, map[string]float64{"K": 2})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if data := result.Outputs[0].Data; data[0] != 2 || data[2] != 6 {
+		t.Errorf("Expected closes doubled, got %v", data)
+	}
+	if _, err := NewFormulaEngine().ExecuteSeriesWithParams(mustCompile(t, "X := C * K"), series, nil); err == nil {
+		t.Error("Expected error without the parameter")
+	}
+}
+
+func TestExecuteSeriesCustomFunctionGetsRows(t *testing.T) {
+	engine := NewFormulaEngine()
+	engine.Functions().Register("LASTCLOSE", func(_ []*interpreter.Value, marketData []*types.MarketData) (*interpreter.Value, error) {
+		return interpreter.NewSingleValue(marketData[len(marketData)-1].Close), nil
+	})
+
+	series := types.NewSeries(2)
+	copy(series.Close, []float64{5, 6})
+
+	result, err := engine.ExecuteSeries(mustCompile(t, "X := LASTCLOSE()"), series)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if result.Variables["X"] != 6 {
+		t.Errorf("Expected 6, got %v", result.Variables["X"])
+	}
+}
+
+func TestExecuteSeriesRejectsRaggedColumns(t *testing.T) {
+	series := types.NewSeries(3)
+	series.Volume = series.Volume[:2]
+
+	if _, err := NewFormulaEngine().ExecuteSeries(mustCompile(t, "X := C"), series); err == nil {
+		t.Error("Expected error for column length mismatch")
+	}
+}
+
+func TestExecuteInputCombinesInputs(t *testing.T) {
+	data := createDailyData(4) // closes 1, 2, 3, 4
+	info := &types.SymbolInfo{
+		Code:         "600000",
+		TotalCapital: 1000,
+		Actions:      []types.CorporateAction{{Date: data[2].Time.Add(-time.Hour), Bonus: 1}},
+	}
+
+	engine := NewFormulaEngine()
+	engine.SetAdjustment(types.AdjustForward)
+	result, err := engine.ExecuteInput(mustCompile(t, "X := C * K + FLOW; Y := TOTALCAPITAL"), Input{
+		Series:  types.SeriesFromMarketData(data),
+		Params:  map[string]float64{"K": 10},
+		Columns: map[string][]float64{"FLOW": {1, 2, 3, 4}},
+		Symbol:  info,
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if x := result.Outputs[0].Data; x[0] != 6 || x[3] != 44 {
+		t.Errorf("Expected adjusted closes scaled by K plus FLOW, got %v", x)
+	}
+	if result.Variables["Y"] != 1000 {
+		t.Errorf("Expected symbol constants, got %v", result.Variables["Y"])
+	}
+
+	if _, err := engine.ExecuteInput(mustCompile(t, "X := FLOW"), Input{
+		Series:  types.SeriesFromMarketData(data),
+		Columns: map[string][]float64{"FLOW": {1, 2}},
+	}); err == nil {
+		t.Error("Expected error for a column that does not match the series length")
+	}
+}
+
+func mustCompile(t *testing.T, formula string) *ast.Program {
+	t.Helper()
+	program, err := NewFormulaEngine().Compile(formula)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	return program
+}
+
+func TestResultTimesAndJSON(t *testing.T) {
+	marketData := createDailyData(3)
+	result, err := NewFormulaEngine().Run("MA2 := MA(CLOSE, 2); N := 2", marketData)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if len(result.Times) != 3 || !result.Times[2].Equal(marketData[2].Time) {
+		t.Errorf("Expected bar times in result, got %v", result.Times)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if !strings.Contains(string(data), `"data":[null,1.5,2.5]`) {
+		t.Errorf("Expected NaN warm-up value as null, got %s", data)
+	}
+
+	withoutTimes, err := NewFormulaEngine().Run("X := C", createTestData())
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if withoutTimes.Times != nil {
+		t.Errorf("Expected no times for bars without time, got %v", withoutTimes.Times)
+	}
+}
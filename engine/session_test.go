@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+func TestSessionExecutesStatementsIncrementally(t *testing.T) {
+	engine := NewFormulaEngine()
+	marketData := createTestData()
+	session := engine.NewSession(types.SeriesFromMarketData(marketData))
+
+	run := func(src string) {
+		t.Helper()
+		program, err := engine.Compile(src)
+		if err != nil {
+			t.Fatalf("Compile error: %v", err)
+		}
+		for _, stmt := range program.Body {
+			if _, err := session.ExecuteStatement(stmt); err != nil {
+				t.Fatalf("ExecuteStatement(%q) error: %v", src, err)
+			}
+		}
+	}
+	run("MA3 := MA(CLOSE, 3)")
+	run("DIFF : CLOSE - MA3")
+	run("MA3 := MA(CLOSE, 2)")
+
+	whole, err := engine.Run("MA3 := MA(CLOSE, 3)\nDIFF : CLOSE - MA3", marketData)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	diff, ok := session.Variable("diff")
+	if !ok || !diff.IsArray {
+		t.Fatalf("Expected DIFF to be a series, got %v", diff)
+	}
+	for i, v := range whole.Output("DIFF").Data {
+		if got := diff.Array[i]; v != got && !(v != v && got != got) {
+			t.Errorf("DIFF[%d]: expected %v, got %v", i, v, got)
+		}
+	}
+
+	names := session.UserVariables()
+	if len(names) != 2 || names[0] != "MA3" || names[1] != "DIFF" {
+		t.Errorf("Expected [MA3 DIFF] in order of first definition, got %v", names)
+	}
+	if _, ok := session.Variable("C"); !ok {
+		t.Error("Expected built-in aliases to resolve in a session")
+	}
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DTrader-store/formula-go/errors"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// MemorySymbolProvider is an in-memory symbol info provider, e.g. for tests or a daily snapshot
+type MemorySymbolProvider struct {
+	mu      sync.RWMutex
+	symbols map[string]*types.SymbolInfo
+}
+
+// NewMemorySymbolProvider creates an empty provider
+func NewMemorySymbolProvider() *MemorySymbolProvider {
+	return &MemorySymbolProvider{symbols: make(map[string]*types.SymbolInfo)}
+}
+
+// Add stores info under its code, replacing any previous entry
+func (p *MemorySymbolProvider) Add(info *types.SymbolInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.symbols[strings.ToUpper(info.Code)] = info
+}
+
+// LookupSymbol returns the info stored for code (case-insensitive)
+func (p *MemorySymbolProvider) LookupSymbol(code string) (*types.SymbolInfo, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	info, ok := p.symbols[strings.ToUpper(code)]
+	return info, ok
+}
+
+// LoadCSV adds one symbol per CSV row. The header names the columns (case-insensitive):
+// code (required), name, industry, blocks (separated by '|'), capital, totalcapital,
+// F<n> for FINANCE(n) and D<n> for DYNAINFO(n). Empty cells are left unset.
+func (p *MemorySymbolProvider) LoadCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return errors.NewFormulaError(fmt.Sprintf("symbol CSV: cannot read header: %v", err))
+	}
+	for i := range header {
+		header[i] = strings.ToUpper(strings.TrimSpace(header[i]))
+	}
+
+	codeColumn := -1
+	for i, name := range header {
+		if name == "CODE" {
+			codeColumn = i
+		}
+	}
+	if codeColumn < 0 {
+		return errors.NewFormulaError("symbol CSV: missing code column")
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.NewFormulaError(fmt.Sprintf("symbol CSV line %d: %v", line, err))
+		}
+
+		info := &types.SymbolInfo{Code: strings.TrimSpace(record[codeColumn])}
+		for i, cell := range record {
+			cell = strings.TrimSpace(cell)
+			if cell == "" || i == codeColumn {
+				continue
+			}
+			if err := setSymbolField(info, header[i], cell); err != nil {
+				return errors.NewFormulaError(fmt.Sprintf("symbol CSV line %d: %v", line, err))
+			}
+		}
+		p.Add(info)
+	}
+}
+
+// setSymbolField sets the field named by a CSV header from a cell value
+func setSymbolField(info *types.SymbolInfo, column, cell string) error {
+	switch column {
+	case "NAME":
+		info.Name = cell
+		return nil
+	case "INDUSTRY":
+		info.Industry = cell
+		return nil
+	case "BLOCKS":
+		info.Blocks = strings.Split(cell, "|")
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(cell, 64)
+	if err != nil {
+		return fmt.Errorf("column %s: invalid number %q", column, cell)
+	}
+
+	switch {
+	case column == "CAPITAL":
+		info.Capital = value
+	case column == "TOTALCAPITAL":
+		info.TotalCapital = value
+	case strings.HasPrefix(column, "F"):
+		n, err := strconv.Atoi(column[1:])
+		if err != nil {
+			return fmt.Errorf("unknown column %s", column)
+		}
+		if info.Finance == nil {
+			info.Finance = make(map[int]float64)
+		}
+		info.Finance[n] = value
+	case strings.HasPrefix(column, "D"):
+		n, err := strconv.Atoi(column[1:])
+		if err != nil {
+			return fmt.Errorf("unknown column %s", column)
+		}
+		if info.DynaInfo == nil {
+			info.DynaInfo = make(map[int]float64)
+		}
+		info.DynaInfo[n] = value
+	default:
+		return fmt.Errorf("unknown column %s", column)
+	}
+	return nil
+}
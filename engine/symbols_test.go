@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+const symbolCSV = `code,name,industry,blocks,capital,totalcapital,F7,D13
+600000,浦发银行,银行,沪深300|上证50,2935000,2935000,2935216.5,1.25
+000001,平安银行,银行,,1940000,1940600,,
+`
+
+func TestMemorySymbolProviderLoadCSV(t *testing.T) {
+	provider := NewMemorySymbolProvider()
+	if err := provider.LoadCSV(strings.NewReader(symbolCSV)); err != nil {
+		t.Fatalf("LoadCSV error: %v", err)
+	}
+
+	info, ok := provider.LookupSymbol("600000")
+	if !ok {
+		t.Fatal("Expected symbol 600000")
+	}
+	if info.Name != "浦发银行" || info.Industry != "银行" || len(info.Blocks) != 2 {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+	if info.Finance[7] != 2935216.5 || info.DynaInfo[13] != 1.25 {
+		t.Errorf("Unexpected finance/dynainfo fields: %v %v", info.Finance, info.DynaInfo)
+	}
+
+	info, _ = provider.LookupSymbol("000001")
+	if info.Finance != nil || info.Blocks != nil {
+		t.Errorf("Expected empty cells to stay unset, got %+v", info)
+	}
+
+	if err := NewMemorySymbolProvider().LoadCSV(strings.NewReader("name\nX\n")); err == nil {
+		t.Error("Expected error for missing code column")
+	}
+	if err := NewMemorySymbolProvider().LoadCSV(strings.NewReader("code,capital\n1,abc\n")); err == nil {
+		t.Error("Expected error for invalid number")
+	}
+	if err := NewMemorySymbolProvider().LoadCSV(strings.NewReader("code,pe\n1,10\n")); err == nil {
+		t.Error("Expected error for unknown column")
+	}
+}
+
+func TestEngineExecuteSymbol(t *testing.T) {
+	provider := NewMemorySymbolProvider()
+	if err := provider.LoadCSV(strings.NewReader(symbolCSV)); err != nil {
+		t.Fatalf("LoadCSV error: %v", err)
+	}
+
+	engine := NewFormulaEngine()
+	engine.SetSymbolProvider(provider)
+	program, err := engine.Compile(`
+		FLOAT := FINANCE(7)
+		CHG := DYNAINFO(13)
+		BANK := INBLOCK('银行')
+		SZ50 := inblock('上证50')
+		TURNOVER := VOL / CAPITAL
+	`)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+
+	marketData := createTestData()
+	result, err := engine.ExecuteSymbol(program, "600000", marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	vars := result.Variables
+	if vars["FLOAT"] != 2935216.5 || vars["CHG"] != 1.25 || vars["BANK"] != 1 || vars["SZ50"] != 1 {
+		t.Errorf("Unexpected symbol variables: %v", vars)
+	}
+	if result.Outputs[0].Data[0] != marketData[0].Volume/2935000 {
+		t.Errorf("Unexpected turnover %v", result.Outputs[0].Data[0])
+	}
+
+	result, err = engine.ExecuteSymbol(program, "000001", marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !math.IsNaN(result.Variables["FLOAT"]) || result.Variables["SZ50"] != 0 {
+		t.Errorf("Expected missing fields to be NaN and blocks to miss, got %v", result.Variables)
+	}
+
+	result, err = engine.ExecuteSymbol(program, "999999", marketData)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !math.IsNaN(result.Variables["CHG"]) || result.Variables["BANK"] != 0 {
+		t.Errorf("Expected unknown symbol to yield NaN and 0, got %v", result.Variables)
+	}
+}
+
+func TestEngineTextArguments(t *testing.T) {
+	engine := NewFormulaEngine()
+	data := createTestData()
+
+	if _, err := engine.Run("X := INBLOCK(1)", data); err == nil {
+		t.Error("Expected error for numeric INBLOCK argument")
+	}
+	if _, err := engine.Run("X := 'abc' + 1", data); err == nil {
+		t.Error("Expected error for arithmetic on text")
+	}
+	if _, err := engine.Run("X := MA('abc', 5)", data); err == nil {
+		t.Error("Expected error for text argument to MA")
+	}
+	if _, err := engine.ExecuteSymbol(mustCompile(t, "X := 1"), "600000", data); err == nil {
+		t.Error("Expected error without a symbol provider")
+	}
+}
+
+func TestEngineAdjustment(t *testing.T) {
+	data := createDailyData(4) // closes 1, 2, 3, 4
+	info := &types.SymbolInfo{
+		Code:    "600000",
+		Actions: []types.CorporateAction{{Date: data[2].Time.Add(-time.Hour), Bonus: 1}},
+	}
+
+	engine := NewFormulaEngine()
+	program := mustCompile(t, "X := CLOSE; V := VOL")
+
+	raw, err := engine.ExecuteWithSymbol(program, data, info)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if raw.Outputs[0].Data[0] != 1 {
+		t.Errorf("Expected raw prices by default, got %v", raw.Outputs[0].Data[0])
+	}
+
+	engine.SetAdjustment(types.AdjustForward)
+	adjusted, err := engine.ExecuteWithSymbol(program, data, info)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if adjusted.Outputs[0].Data[0] != 0.5 || adjusted.Outputs[0].Data[3] != 4 {
+		t.Errorf("Expected forward-adjusted closes, got %v", adjusted.Outputs[0].Data)
+	}
+	if adjusted.Outputs[1].Data[0] != 200 || adjusted.Outputs[1].Data[3] != 100 {
+		t.Errorf("Expected volume before the bonus issue doubled, got %v", adjusted.Outputs[1].Data)
+	}
+	if data[0].Close != 1 {
+		t.Error("Expected input bars to be left unchanged")
+	}
+
+	series, err := engine.ExecuteInput(program, Input{Series: types.SeriesFromMarketData(data), Symbol: info})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if series.Outputs[0].Data[0] != 0.5 {
+		t.Errorf("Expected forward-adjusted closes on series input, got %v", series.Outputs[0].Data)
+	}
+}
+
+func TestEngineAdjustmentNeedsSymbol(t *testing.T) {
+	data := createDailyData(4)
+	program := mustCompile(t, "X := CLOSE")
+
+	engine := NewFormulaEngine()
+	engine.SetAdjustment(types.AdjustBackward)
+	if _, err := engine.Execute(program, data); err == nil {
+		t.Error("Expected error for Execute with an adjustment mode set")
+	}
+	if _, err := engine.ExecuteSeries(program, types.SeriesFromMarketData(data)); err == nil {
+		t.Error("Expected error for ExecuteSeries with an adjustment mode set")
+	}
+	if _, err := engine.Run("X := CLOSE", data); err == nil {
+		t.Error("Expected error for Run with an adjustment mode set")
+	}
+
+	// A symbol without corporate actions needs no adjustment
+	if _, err := engine.ExecuteWithSymbol(program, data, &types.SymbolInfo{Code: "600000"}); err != nil {
+		t.Errorf("Expected symbol without actions to run, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package helpers
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/DTrader-store/formula-go/source"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// SourceOptions selects the market data source of an example
+type SourceOptions struct {
+	Offline bool   // use generated sample data instead of the TDX server
+	Replay  string // replay bars recorded earlier to this file
+	Record  string // save the bars fetched from the TDX server to this file
+	Server  string // TDX server address; the first reachable known server when empty
+}
+
+// RegisterFlags binds the options to the -offline, -replay, -record and -server flags of fs
+func (o *SourceOptions) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.Offline, "offline", o.Offline, "use generated sample data instead of the TDX server")
+	fs.StringVar(&o.Replay, "replay", o.Replay, "replay bars recorded earlier with -record")
+	fs.StringVar(&o.Record, "record", o.Record, "save the bars fetched from the TDX server to this file")
+	fs.StringVar(&o.Server, "server", o.Server, "TDX server address; the first reachable known server when empty")
+}
+
+// OpenSource returns the market data source selected by opts: generated sample data (Offline),
+// a recording (Replay) or the TDX server, optionally recorded (Record).
+// Call the returned close function when done; it also saves the recording.
+func OpenSource(opts SourceOptions) (source.MarketDataSource, func(), error) {
+	switch {
+	case opts.Offline:
+		return SampleSource(), func() {}, nil
+	case opts.Replay != "":
+		src, err := source.LoadMemory(opts.Replay)
+		return src, func() {}, err
+	}
+
+	client, err := source.DialTDX(opts.Server, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Record == "" {
+		return client, func() { client.Close() }, nil
+	}
+
+	recorder := source.NewRecorder(client)
+	return recorder, func() {
+		client.Close()
+		if err := recorder.Recorded().Save(opts.Record); err != nil {
+			fmt.Printf("Failed to save recording: %v\n", err)
+		}
+	}, nil
+}
+
+// SampleSource returns a source with a year of generated daily bars for sz000001 and the
+// sh000001 index, for running the examples offline
+func SampleSource() *source.Memory {
+	m := source.NewMemory()
+	m.Add("sz000001", types.PeriodDay, randomWalk(1, 10, 250))
+	m.Add("sh000001", types.PeriodDay, randomWalk(2, 3000, 250))
+	return m
+}
+
+// randomWalk generates n weekday bars ending today, starting around price
+func randomWalk(seed int64, price float64, n int) []*types.MarketData {
+	rng := rand.New(rand.NewSource(seed))
+	bars := make([]*types.MarketData, 0, n)
+	day := time.Now().AddDate(0, 0, -n*7/5)
+	for len(bars) < n {
+		day = day.AddDate(0, 0, 1)
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		open := price
+		price = math.Max(price*(1+rng.NormFloat64()*0.02), 0.01)
+		spread := math.Abs(rng.NormFloat64()) * 0.01 * price
+		volume := math.Round(1e6 * (1 + rng.Float64()))
+		bars = append(bars, &types.MarketData{
+			Time:   time.Date(day.Year(), day.Month(), day.Day(), 15, 0, 0, 0, time.Local),
+			Open:   open,
+			High:   math.Max(open, price) + spread,
+			Low:    math.Min(open, price) - spread,
+			Close:  price,
+			Volume: volume,
+			Amount: volume * price,
+		})
+	}
+	return bars
+}
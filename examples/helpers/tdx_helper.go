@@ -9,11 +9,15 @@ import (
 )
 
 // TDXClient wraps the TDX connection for easy data fetching
+//
+// Deprecated: use source.DialTDX, which fetches every period by time range, or OpenSource.
 type TDXClient struct {
 	client *tdx.Client
 }
 
 // NewTDXClient creates a new TDX client with auto-reconnect
+//
+// Deprecated: use source.DialTDX.
 func NewTDXClient(addr string) (*TDXClient, error) {
 	client, err := tdx.Dial(addr, tdx.WithRedial())
 	if err != nil {
@@ -45,8 +49,11 @@ func (c *TDXClient) GetMarketData(code string, start uint16, count uint16) ([]*t
 }
 
 // KlineToMarketData converts TDX Kline to formula MarketData
+//
+// Deprecated: source.TDX converts the bars it fetches.
 func KlineToMarketData(kline *protocol.Kline) *types.MarketData {
 	return &types.MarketData{
+		Time:   kline.Time,
 		Open:   float64(kline.Open),
 		High:   float64(kline.High),
 		Low:    float64(kline.Low),
@@ -57,6 +64,8 @@ func KlineToMarketData(kline *protocol.Kline) *types.MarketData {
 }
 
 // DefaultTDXServer returns a reliable TDX server address
+//
+// Deprecated: source.DialTDX with an empty address connects to the first reachable known server.
 func DefaultTDXServer() string {
 	return "124.71.187.122:7709" // Shanghai Huawei server
 }
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/DTrader-store/formula-go"
 	"github.com/DTrader-store/formula-go/examples/helpers"
+	"github.com/DTrader-store/formula-go/types"
 )
 
 // MA Cross Strategy - Golden Cross and Death Cross Detection
@@ -13,20 +16,25 @@ import (
 // Death Cross: Fast MA crosses below Slow MA (bearish signal)
 
 func main() {
+	var opts helpers.SourceOptions
+	opts.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
 	fmt.Println("=== MA Cross Strategy Example ===")
-	fmt.Println("Detecting Golden Cross and Death Cross signals using real market data\n")
+	fmt.Println("Detecting Golden Cross and Death Cross signals using real market data")
+	fmt.Println()
 
-	// Initialize TDX client
-	client, err := helpers.NewTDXClient(helpers.DefaultTDXServer())
+	// Connect to the TDX server, or use sample data with -offline
+	src, closeSource, err := helpers.OpenSource(opts)
 	if err != nil {
-		log.Fatalf("Failed to create TDX client: %v", err)
+		log.Fatalf("Failed to open market data source: %v", err)
 	}
-	defer client.Close()
+	defer closeSource()
 
-	// Fetch real market data for Ping An Bank (sz000001)
+	// Fetch about six months of daily bars for Ping An Bank (sz000001)
 	stockCode := "sz000001"
 	fmt.Printf("Fetching data for %s (Ping An Bank)...\n", stockCode)
-	marketData, err := client.GetMarketData(stockCode, 0, 100)
+	marketData, err := src.Bars(stockCode, types.PeriodDay, time.Now().AddDate(0, -6, 0), time.Time{})
 	if err != nil {
 		log.Fatalf("Failed to fetch market data: %v", err)
 	}
@@ -66,8 +74,10 @@ func runMACrossStrategy(data []*formula.MarketData, fastPeriod, slowPeriod int)
 	}
 
 	// Analyze signals
-	goldenCrosses := findSignals(getOutputData(result, "GOLDEN_CROSS"))
-	deathCrosses := findSignals(getOutputData(result, "DEATH_CROSS"))
+	goldenCrosses := result.SignalIndices("GOLDEN_CROSS")
+	deathCrosses := result.SignalIndices("DEATH_CROSS")
+	fastMA := result.Output("FAST_MA").Data
+	slowMA := result.Output("SLOW_MA").Data
 
 	fmt.Printf("Fast MA: %d, Slow MA: %d\n", fastPeriod, slowPeriod)
 	fmt.Printf("Golden Crosses detected: %d\n", len(goldenCrosses))
@@ -80,8 +90,8 @@ func runMACrossStrategy(data []*formula.MarketData, fastPeriod, slowPeriod int)
 				fmt.Printf("  Position %d: Price = %.2f, Fast MA = %.2f, Slow MA = %.2f\n",
 					pos,
 					data[pos].Close,
-					getOutputData(result, "FAST_MA")[pos],
-					getOutputData(result, "SLOW_MA")[pos])
+					fastMA[pos],
+					slowMA[pos])
 			}
 		}
 	}
@@ -93,8 +103,8 @@ func runMACrossStrategy(data []*formula.MarketData, fastPeriod, slowPeriod int)
 				fmt.Printf("  Position %d: Price = %.2f, Fast MA = %.2f, Slow MA = %.2f\n",
 					pos,
 					data[pos].Close,
-					getOutputData(result, "FAST_MA")[pos],
-					getOutputData(result, "SLOW_MA")[pos])
+					fastMA[pos],
+					slowMA[pos])
 			}
 		}
 	}
@@ -104,34 +114,15 @@ func runMACrossStrategy(data []*formula.MarketData, fastPeriod, slowPeriod int)
 	if lastIdx >= 0 {
 		fmt.Printf("\nCurrent Status (Last bar):\n")
 		fmt.Printf("  Close Price: %.2f\n", data[lastIdx].Close)
-		fmt.Printf("  Fast MA(%d): %.2f\n", fastPeriod, getOutputData(result, "FAST_MA")[lastIdx])
-		fmt.Printf("  Slow MA(%d): %.2f\n", slowPeriod, getOutputData(result, "SLOW_MA")[lastIdx])
+		fast, _ := result.Last("FAST_MA")
+		slow, _ := result.Last("SLOW_MA")
+		fmt.Printf("  Fast MA(%d): %.2f\n", fastPeriod, fast)
+		fmt.Printf("  Slow MA(%d): %.2f\n", slowPeriod, slow)
 
-		if getOutputData(result, "FAST_MA")[lastIdx] > getOutputData(result, "SLOW_MA")[lastIdx] {
+		if fast > slow {
 			fmt.Println("  Trend: BULLISH (Fast MA > Slow MA)")
 		} else {
 			fmt.Println("  Trend: BEARISH (Fast MA < Slow MA)")
 		}
 	}
 }
-
-// getOutputData gets output data by name from formula result
-func getOutputData(result *formula.FormulaResult, name string) []float64 {
-	for _, output := range result.Outputs {
-		if output.Name == name {
-			return output.Data
-		}
-	}
-	return nil
-}
-
-// findSignals finds all positions where signal is 1 (cross detected)
-func findSignals(data []float64) []int {
-	var positions []int
-	for i, val := range data {
-		if val == 1.0 {
-			positions = append(positions, i)
-		}
-	}
-	return positions
-}
@@ -0,0 +1,307 @@
+// Package format prints formulas in canonical form: upper-cased function names and keywords,
+// consistent spacing, and one statement per line ending in a semicolon. Formatting keeps
+// comments and drawing attributes, and the formatted source compiles to the same program.
+package format
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/DTrader-store/formula-go/lexer"
+	"github.com/DTrader-store/formula-go/parser"
+	"github.com/DTrader-store/formula-go/parser/ast"
+)
+
+// Ext is the file extension of formula source files
+const Ext = ".fml"
+
+// Source formats formula source
+func Source(src string) (string, error) {
+	tokens, err := lexer.NewLexer(src).Tokenize()
+	if err != nil {
+		return "", err
+	}
+	p := parser.NewParser(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		return "", err
+	}
+
+	var comments []*lexer.Token
+	for _, tok := range tokens {
+		if tok.Type == lexer.COMMENT {
+			comments = append(comments, tok)
+		}
+	}
+
+	var out writer
+	spans := p.Spans()
+	for i, stmt := range program.Body {
+		span := spans[stmt]
+		next := ast.Position{Line: math.MaxInt}
+		if i+1 < len(program.Body) {
+			next = spans[program.Body[i+1]].Start
+		}
+
+		// Comments before the statement go on lines of their own
+		for len(comments) > 0 && before(comments[0], span.Start) {
+			out.line(comments[0].Line, comments[0].Value)
+			comments = comments[1:]
+		}
+
+		text, err := statement(stmt)
+		if err != nil {
+			return "", err
+		}
+		out.line(span.Start.Line, text)
+		out.end = span.End.Line
+
+		// Comments within the statement, or after it on its last line before the next statement,
+		// follow it
+		for len(comments) > 0 && comments[0].Line <= out.end && before(comments[0], next) {
+			out.trailing(comments[0].Value)
+			comments = comments[1:]
+		}
+	}
+	for _, comment := range comments {
+		out.line(comment.Line, comment.Value)
+	}
+	return out.String(), nil
+}
+
+// Program prints a program in canonical form, one statement per line
+func Program(program *ast.Program) (string, error) {
+	var b strings.Builder
+	for _, stmt := range program.Body {
+		text, err := statement(stmt)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(text)
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// Check reports whether src is already formatted
+func Check(src string) (bool, error) {
+	formatted, err := Source(src)
+	if err != nil {
+		return false, err
+	}
+	return formatted == src, nil
+}
+
+// Unformatted walks dir and returns the formula files (*.fml) that are not formatted, like gofmt -l
+func Unformatted(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != Ext {
+			return err
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		formatted, err := Check(string(src))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if !formatted {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// before reports whether a comment starts before pos
+func before(comment *lexer.Token, pos ast.Position) bool {
+	return comment.Line < pos.Line || comment.Line == pos.Line && comment.Column < pos.Column
+}
+
+// writer collects output lines, keeping at most one blank line where the source had any
+type writer struct {
+	b   strings.Builder
+	end int // last source line of what was written
+}
+
+// line starts a new output line with text from source line at
+func (w *writer) line(at int, text string) {
+	if w.b.Len() > 0 {
+		w.b.WriteByte('\n')
+		if at > w.end+1 {
+			w.b.WriteByte('\n')
+		}
+	}
+	w.b.WriteString(text)
+	w.finish(at, text)
+}
+
+// trailing appends a comment to the current line. Statements cannot span lines except through
+// block comments, so a // comment is always the last one on its line.
+func (w *writer) trailing(comment string) {
+	w.b.WriteByte(' ')
+	w.b.WriteString(comment)
+	w.finish(w.end, comment)
+}
+
+// finish records the source line where text written from line at ends
+func (w *writer) finish(at int, text string) {
+	w.end = max(w.end, at+strings.Count(text, "\n"))
+}
+
+// String returns the output, ending in a newline unless empty
+func (w *writer) String() string {
+	if w.b.Len() == 0 {
+		return ""
+	}
+	return w.b.String() + "\n"
+}
+
+// statement prints a statement with its terminating semicolon
+func statement(stmt ast.Statement) (string, error) {
+	switch s := stmt.(type) {
+	case *ast.VariableDeclaration:
+		value, err := Expression(s.Value)
+		return s.Name + " := " + value + ";", err
+	case *ast.OutputDeclaration:
+		value, err := Expression(s.Value)
+		parts := append([]string{s.Name + " : " + value}, s.Attributes...)
+		return strings.Join(parts, ", ") + ";", err
+	case *ast.ExpressionStatement:
+		value, err := Expression(s.Expr)
+		return value + ";", err
+	default:
+		return "", fmt.Errorf("cannot format statement %T", stmt)
+	}
+}
+
+// Operator precedence levels, from loosest to tightest, as parsed by the parser
+const (
+	precOr = iota + 1
+	precAnd
+	precComparison
+	precAdditive
+	precMultiplicative
+	precUnary
+	precPrimary
+)
+
+// binaryOperators gives the source text and precedence of each binary operator with formula syntax
+var binaryOperators = map[ast.BinaryOperator]struct {
+	text string
+	prec int
+}{
+	ast.OpOr:                 {"OR", precOr},
+	ast.OpAnd:                {"AND", precAnd},
+	ast.OpEqual:              {"=", precComparison},
+	ast.OpNotEqual:           {"<>", precComparison},
+	ast.OpLessThan:           {"<", precComparison},
+	ast.OpLessThanOrEqual:    {"<=", precComparison},
+	ast.OpGreaterThan:        {">", precComparison},
+	ast.OpGreaterThanOrEqual: {">=", precComparison},
+	ast.OpPlus:               {"+", precAdditive},
+	ast.OpMinus:              {"-", precAdditive},
+	ast.OpMultiply:           {"*", precMultiplicative},
+	ast.OpDivide:             {"/", precMultiplicative},
+}
+
+// precedence returns how tightly an expression binds
+func precedence(expr ast.Expression) int {
+	switch e := expr.(type) {
+	case *ast.BinaryExpression:
+		return binaryOperators[e.Operator].prec
+	case *ast.UnaryExpression:
+		return precUnary
+	}
+	return precPrimary
+}
+
+// Expression prints an expression in canonical form
+func Expression(expr ast.Expression) (string, error) {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		if math.IsNaN(e.Value) || math.IsInf(e.Value, 0) {
+			return "", fmt.Errorf("cannot format number %v", e.Value)
+		}
+		return strconv.FormatFloat(e.Value, 'f', -1, 64), nil
+	case *ast.StringLiteral:
+		return "'" + e.Value + "'", nil
+	case *ast.Identifier:
+		return e.Name, nil
+	case *ast.BinaryExpression:
+		op, ok := binaryOperators[e.Operator]
+		if !ok {
+			return "", fmt.Errorf("operator %s has no formula syntax", e.Operator)
+		}
+		left, err := operand(e.Left, op.prec)
+		if err != nil {
+			return "", err
+		}
+		right, err := operand(e.Right, op.prec+1) // operators are left-associative
+		return left + " " + op.text + " " + right, err
+	case *ast.UnaryExpression:
+		if e.Operator != ast.OpUnaryMinus {
+			return "", fmt.Errorf("operator %s has no formula syntax", e.Operator)
+		}
+		value, err := operand(e.Operand, precUnary)
+		return "-" + value, err
+	case *ast.FunctionCall:
+		args, err := arguments(e.Arguments)
+		return strings.ToUpper(e.Name) + args, err
+	case *ast.FormulaReference:
+		name := `"` + e.Formula + `"`
+		if e.Output != "" {
+			name = `"` + e.Formula + "." + e.Output + `"`
+			if isName(e.Formula) && isName(e.Output) {
+				name = e.Formula + "." + e.Output
+			}
+		}
+		if e.Arguments == nil {
+			return name, nil
+		}
+		args, err := arguments(e.Arguments)
+		return name + args, err
+	case *ast.PeriodExpression:
+		value, err := operand(e.Expr, precPrimary)
+		return value + "#" + e.Period, err
+	case *ast.SymbolReference:
+		return `"` + e.Symbol + "$" + e.Field + `"`, nil
+	default:
+		return "", fmt.Errorf("cannot format expression %T", expr)
+	}
+}
+
+// operand prints an expression, in parentheses when it binds looser than prec
+func operand(expr ast.Expression, prec int) (string, error) {
+	text, err := Expression(expr)
+	if precedence(expr) < prec {
+		text = "(" + text + ")"
+	}
+	return text, err
+}
+
+// arguments prints a parenthesized argument list
+func arguments(args []ast.Expression) (string, error) {
+	texts := make([]string, len(args))
+	for i, arg := range args {
+		text, err := Expression(arg)
+		if err != nil {
+			return "", err
+		}
+		texts[i] = text
+	}
+	return "(" + strings.Join(texts, ", ") + ")", nil
+}
+
+// isName reports whether s is written as a plain identifier, so a reference needs no quotes
+func isName(s string) bool {
+	tokens, err := lexer.NewLexer(s).Tokenize()
+	return err == nil && len(tokens) == 2 && tokens[0].Type == lexer.IDENTIFIER && tokens[0].Value == s
+}
@@ -0,0 +1,129 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/DTrader-store/formula-go/lexer"
+	"github.com/DTrader-store/formula-go/library"
+	"github.com/DTrader-store/formula-go/parser"
+	"github.com/DTrader-store/formula-go/parser/ast"
+)
+
+// compile parses formula source
+func compile(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	tokens, err := lexer.NewLexer(src).Tokenize()
+	if err != nil {
+		t.Fatalf("Lexer error: %v", err)
+	}
+	program, err := parser.NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parser error: %v", err)
+	}
+	return program
+}
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"spacing", "ma5:=ma(close,5)", "ma5 := MA(close, 5);\n"},
+		{"statements per line", "A:=1;B:=2\n\n\n\nC:=A+B", "A := 1;\nB := 2;\n\nC := A + B;\n"},
+		{"keywords", "x := c>o and (c<ref(c,1) or v==0) and h!=l", "x := c > o AND (c < REF(c, 1) OR v = 0) AND h <> l;\n"},
+		{"parentheses", "X := ((a-b))-(c-d)*(-e)/(f*g)", "X := a - b - (c - d) * -e / (f * g);\n"},
+		{"period and references", `X := (C+O)#week + "MACD.DIF"(12,26,9) + "kdj"`, `X := (C + O)#WEEK + MACD.DIF(12, 26, 9) + "kdj";` + "\n"},
+		{"symbols and text", `X := "SH000001$CLOSE#WEEK" * inblock('银行')`, `X := "SH000001$CLOSE"#WEEK * INBLOCK('银行');` + "\n"},
+		{"attributes", "UP:MA(C,5),colorred , linethick2", "UP : MA(C, 5), COLORRED, LINETHICK2;\n"},
+		{"comments", "{均线}\nA := 1 {一} ; // 二\n// 三\nB := 2\n\n{尾}", "{均线}\nA := 1; {一} // 二\n// 三\nB := 2;\n\n{尾}\n"},
+		{"comments inside statement", "A := MA(C, {周期\n天数} 5) // 二\nB := 1", "A := MA(C, 5); {周期\n天数} // 二\nB := 1;\n"},
+		{"comments between statements on a line", "A := 1; {甲} B := 2 {乙}", "A := 1; {甲}\nB := 2; {乙}\n"},
+		{"empty", "  \n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted, err := Source(tt.input)
+			if err != nil {
+				t.Fatalf("Source error: %v", err)
+			}
+			if formatted != tt.expected {
+				t.Errorf("Expected\n%q\ngot\n%q", tt.expected, formatted)
+			}
+
+			again, err := Source(formatted)
+			if err != nil || again != formatted {
+				t.Errorf("Formatting is not stable: %q, %v", again, err)
+			}
+			if !reflect.DeepEqual(compile(t, tt.input), compile(t, formatted)) {
+				t.Error("Formatted source compiles to a different program")
+			}
+		})
+	}
+}
+
+func TestSourceRoundTripsLibrary(t *testing.T) {
+	lib := library.Default()
+	for _, name := range lib.Names() {
+		ind, _ := lib.Get(name)
+		formatted, err := Source(ind.Source)
+		if err != nil {
+			t.Fatalf("%s: Source error: %v", name, err)
+		}
+		if !reflect.DeepEqual(compile(t, ind.Source), compile(t, formatted)) {
+			t.Errorf("%s: formatted source compiles to a different program:\n%s", name, formatted)
+		}
+		if ok, err := Check(formatted); !ok || err != nil {
+			t.Errorf("%s: formatted source does not pass Check: %v", name, err)
+		}
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	for _, input := range []string{"X := (1", "{open", "X : C, COLORPINK"} {
+		if _, err := Source(input); err == nil {
+			t.Errorf("%s: expected error, got nil", input)
+		}
+	}
+
+	program := &ast.Program{Body: []ast.Statement{&ast.ExpressionStatement{Expr: &ast.ConditionalExpression{
+		Test: &ast.Identifier{Name: "A"}, Consequent: &ast.NumberLiteral{Value: 1}, Alternate: &ast.NumberLiteral{Value: 2},
+	}}}}
+	if _, err := Program(program); err == nil {
+		t.Error("Expected error for expression without formula syntax")
+	}
+}
+
+func TestUnformatted(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("good.fml", "A := MA(C, 5);\n")
+	bad := write("sub/bad.fml", "A:=ma(c,5)")
+	write("notes.txt", "A:=ma(c,5)")
+
+	paths, err := Unformatted(dir)
+	if err != nil {
+		t.Fatalf("Unformatted error: %v", err)
+	}
+	if len(paths) != 1 || paths[0] != bad {
+		t.Errorf("Expected only %s, got %v", bad, paths)
+	}
+
+	write("broken.fml", "A := (")
+	if _, err := Unformatted(dir); err == nil {
+		t.Error("Expected error for a file that does not parse")
+	}
+}
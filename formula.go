@@ -48,6 +48,9 @@ type (
 	FormulaResult = types.FormulaResult
 	OutputLine    = types.OutputLine
 	LineStyle     = types.LineStyle
+	SymbolInfo    = types.SymbolInfo
+	Series        = types.Series
+	ValueKind     = types.ValueKind
 )
 
 // Export engine types
@@ -66,6 +69,7 @@ type (
 var (
 	NewMarketData    = types.NewMarketData
 	NewFormulaResult = types.NewFormulaResult
+	NewSeries        = types.NewSeries
 	NewLexerError    = errors.NewLexerError
 	NewParserError   = errors.NewParserError
 	NewRuntimeError  = errors.NewRuntimeError
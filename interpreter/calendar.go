@@ -0,0 +1,73 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/DTrader-store/formula-go/errors"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// calendarFields computes the calendar variables bound for each bar when market data carries timestamps
+var calendarFields = map[string]func(t time.Time) float64{
+	"DATE":    types.TDXDate,
+	"TIME":    types.TDXTime,
+	"YEAR":    func(t time.Time) float64 { return float64(t.Year()) },
+	"MONTH":   func(t time.Time) float64 { return float64(t.Month()) },
+	"DAY":     func(t time.Time) float64 { return float64(t.Day()) },
+	"HOUR":    func(t time.Time) float64 { return float64(t.Hour()) },
+	"MINUTE":  func(t time.Time) float64 { return float64(t.Minute()) },
+	"WEEKDAY": func(t time.Time) float64 { return float64(t.Weekday()) }, // 0 = Sunday, as in TDX
+	"DAYSTOTODAY": func(t time.Time) float64 {
+		return float64(types.DaysBetween(t, time.Now().In(t.Location())))
+	},
+}
+
+// initCalendarVariables binds DATE, TIME, YEAR, ... as series when every bar has a timestamp
+func (interp *Interpreter) initCalendarVariables() {
+	if !interp.series.HasTime() {
+		return
+	}
+
+	for name, field := range calendarFields {
+		values := make([]float64, interp.series.Len())
+		for i, t := range interp.series.Time {
+			values[i] = field(t)
+		}
+		interp.variables[name] = withKind(NewArrayValue(values), types.KindInt)
+	}
+}
+
+// fnDATETODAY implements DATETODAY(date): calendar days from a TDX-encoded date to today
+func fnDATETODAY(args []*Value, _ []*types.MarketData) (*Value, error) {
+	today := time.Now()
+	daysTo := func(date float64) (float64, error) {
+		if math.IsNaN(date) {
+			return math.NaN(), nil
+		}
+		t, err := types.ParseTDXDate(date, today.Location())
+		if err != nil {
+			return 0, errors.NewRuntimeError(fmt.Sprintf("DATETODAY: %v", err))
+		}
+		return float64(types.DaysBetween(t, today)), nil
+	}
+
+	if !args[0].IsArray {
+		days, err := daysTo(args[0].Single)
+		if err != nil {
+			return nil, err
+		}
+		return NewSingleValue(days), nil
+	}
+
+	result := make([]float64, len(args[0].Array))
+	for i, date := range args[0].Array {
+		days, err := daysTo(date)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = days
+	}
+	return NewArrayValue(result), nil
+}
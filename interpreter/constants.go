@@ -0,0 +1,26 @@
+package interpreter
+
+import (
+	"math"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// SetSymbolInfo sets the symbol the market data belongs to, which feeds CAPITAL, TOTALCAPITAL,
+// FINANCE, DYNAINFO and INBLOCK
+func (interp *Interpreter) SetSymbolInfo(info *types.SymbolInfo) {
+	interp.symbol = info
+}
+
+// initConstants binds the TDX built-in constants. Symbol constants are NaN when no symbol info is set.
+func (interp *Interpreter) initConstants() {
+	capital, totalCapital := math.NaN(), math.NaN()
+	if interp.symbol != nil {
+		capital = interp.symbol.Capital
+		totalCapital = interp.symbol.TotalCapital
+	}
+
+	interp.variables["DRAWNULL"] = NewSingleValue(math.NaN()) // invalid value, not drawn
+	interp.variables["CAPITAL"] = NewSingleValue(capital)
+	interp.variables["TOTALCAPITAL"] = NewSingleValue(totalCapital)
+}
@@ -0,0 +1,361 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DTrader-store/formula-go/errors"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// ArgKind is what a function parameter accepts
+type ArgKind int
+
+const (
+	// ArgAny accepts a number or a series
+	ArgAny ArgKind = iota
+	// ArgSeries accepts a series, e.g. the data of MA(data, period)
+	ArgSeries
+	// ArgNumber accepts a single number, e.g. a period
+	ArgNumber
+	// ArgText accepts a text literal such as a block name
+	ArgText
+)
+
+// String returns the kind's name: "any", "series", "number" or "text"
+func (k ArgKind) String() string {
+	switch k {
+	case ArgSeries:
+		return "series"
+	case ArgNumber:
+		return "number"
+	case ArgText:
+		return "text"
+	}
+	return "any"
+}
+
+// Param describes a function parameter
+type Param struct {
+	Name     string
+	Kind     ArgKind
+	Optional bool     // Whether the argument may be omitted
+	Default  *float64 // Value passed when the argument is omitted; implies Optional
+}
+
+// omittable reports whether the argument for the parameter may be left out
+func (p Param) omittable() bool {
+	return p.Optional || p.Default != nil
+}
+
+// Localized is a text in Chinese and English
+type Localized struct {
+	Zh string
+	En string
+}
+
+// Descriptor describes a function once for calling, checking, documenting and completing it
+type Descriptor struct {
+	Name        string
+	Aliases     []string        // Other names the function is called by
+	Params      []Param         // Parameters in order; optional ones come last
+	Variadic    bool            // Whether the last parameter may be repeated
+	Returns     types.ValueKind // Kind of the result; a float function may set Value.Kind itself
+	Category    string          // Category, e.g. "引用函数"
+	Description Localized
+	Example     string // A typical call, e.g. "MA(CLOSE, 5)"
+	Future      bool   // Whether the function reads bars after the current one; no built-in does
+	WarmUp      int    // Bars needed before the first valid value
+	WarmUpParam string // Parameter whose value is added to WarmUp, e.g. "period"
+	Fn          Function
+	// Repository, when set, holds a formula named like the function. Interpreters then run it as
+	// they run "NAME"(args), sharing the memoized results of the formula reference; Fn is used by
+	// FunctionRegistry.Call only.
+	Repository FormulaRepository
+
+	seriesOnly bool           // Fn ignores its market data argument
+	kind       kindRule       // result kind depending on the arguments, for built-in functions
+	symbol     symbolFunction // for functions reading the interpreter's symbol info instead of Fn
+}
+
+// MinArgs returns the number of arguments that must be given
+func (d *Descriptor) MinArgs() int {
+	n := 0
+	for _, p := range d.Params {
+		if p.omittable() {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// MaxArgs returns the number of arguments that may be given, or -1 when there is no limit
+func (d *Descriptor) MaxArgs() int {
+	if d.Variadic {
+		return -1
+	}
+	return len(d.Params)
+}
+
+// Arity describes how many arguments the function takes, e.g. "2 to 3 arguments"
+func (d *Descriptor) Arity() string {
+	lo, hi := d.MinArgs(), d.MaxArgs()
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", n)
+	}
+	switch {
+	case hi < 0:
+		return "at least " + plural(lo)
+	case lo != hi:
+		return fmt.Sprintf("%d to %s", lo, plural(hi))
+	}
+	return plural(lo)
+}
+
+// Usage returns how the function is called, e.g. "SMA(data, period[, weight])"
+func (d *Descriptor) Usage() string {
+	var b strings.Builder
+	b.WriteString(d.Name)
+	b.WriteByte('(')
+	for i, p := range d.Params {
+		name := p.Name
+		if d.Variadic && i == len(d.Params)-1 {
+			name += "..."
+		}
+		switch {
+		case p.omittable() && i > 0:
+			b.WriteString("[, " + name + "]")
+		case p.omittable():
+			b.WriteString("[" + name + "]")
+		case i > 0:
+			b.WriteString(", " + name)
+		default:
+			b.WriteString(name)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// ResultKind returns the kind of the function's result, and false when it depends on the arguments
+func (d *Descriptor) ResultKind() (types.ValueKind, bool) {
+	if d.kind != nil {
+		return types.KindFloat, false
+	}
+	return d.Returns, true
+}
+
+// Param returns the parameter the i-th argument is passed to
+func (d *Descriptor) Param(i int) (Param, bool) {
+	switch {
+	case i < len(d.Params):
+		return d.Params[i], true
+	case d.Variadic && len(d.Params) > 0:
+		return d.Params[len(d.Params)-1], true
+	}
+	return Param{}, false
+}
+
+// check validates the number and kinds of the arguments of a call
+func (d *Descriptor) check(args []*Value) error {
+	if n := len(args); n < d.MinArgs() || d.MaxArgs() >= 0 && n > d.MaxArgs() {
+		return errors.NewRuntimeError(fmt.Sprintf("%s takes %s, got %d", d.Name, d.Arity(), n))
+	}
+	for i, arg := range args {
+		p, _ := d.Param(i)
+		position := fmt.Sprintf("%s argument %d (%s)", d.Name, i+1, p.Name)
+		switch {
+		case p.Kind == ArgText && !arg.IsText:
+			return errors.NewRuntimeError(position + " must be text, e.g. '银行'")
+		case p.Kind != ArgText && arg.IsText:
+			return errors.NewRuntimeError(position + " must be a number, got text")
+		case p.Kind == ArgSeries && !arg.IsArray:
+			return errors.NewRuntimeError(position + " must be a series, got a single value")
+		case p.Kind == ArgNumber && arg.IsArray:
+			return errors.NewRuntimeError(position + " must be a single number, got a series")
+		}
+	}
+	return nil
+}
+
+// withDefaults appends the defaults of omitted trailing parameters
+func (d *Descriptor) withDefaults(args []*Value) []*Value {
+	for i := len(args); i < len(d.Params) && d.Params[i].Default != nil; i++ {
+		args = append(args, NewSingleValue(*d.Params[i].Default))
+	}
+	return args
+}
+
+// resultKind returns the rule giving the kind of a call's result, or nil to keep the function's own
+func (d *Descriptor) resultKind() kindRule {
+	if d.kind == nil && d.Returns != types.KindFloat {
+		return always(d.Returns)
+	}
+	return d.kind
+}
+
+// Categories of the built-in functions
+const (
+	categoryReference = "引用函数"
+	categoryLogic     = "逻辑函数"
+	categoryMath      = "数学函数"
+	categoryStatistic = "统计函数"
+	categoryTime      = "时间函数"
+	categoryFinance   = "财务函数"
+	categoryQuote     = "行情函数"
+	categoryBlock     = "板块函数"
+)
+
+var (
+	data      = Param{Name: "data", Kind: ArgSeries}
+	condition = Param{Name: "condition", Kind: ArgSeries}
+	period    = Param{Name: "period", Kind: ArgNumber}
+)
+
+// either is a parameter accepting a number or a series
+func either(name string) Param {
+	return Param{Name: name, Kind: ArgAny}
+}
+
+// builtinDescriptors describe the built-in functions, including the symbol functions
+var builtinDescriptors = []*Descriptor{
+	{
+		Name: "MA", Params: []Param{data, period}, Category: categoryReference,
+		Description: Localized{"简单移动平均", "Simple moving average of data over period bars"},
+		WarmUpParam: "period", Fn: fnMA, Example: "MA(CLOSE, 5)",
+	},
+	{
+		Name: "EMA", Params: []Param{data, period}, Category: categoryReference,
+		Description: Localized{"指数移动平均", "Exponential moving average with smoothing 2/(period+1)"},
+		WarmUpParam: "period", Fn: fnEMA, Example: "EMA(CLOSE, 12)",
+	},
+	{
+		Name: "SMA", Params: []Param{data, period, {Name: "weight", Kind: ArgNumber, Optional: true}}, Category: categoryReference,
+		Description: Localized{"扩展指数加权移动平均，省略权重时同 MA", "Weighted moving average Y = (weight*X + (period-weight)*Y') / period; MA when weight is omitted"},
+		WarmUpParam: "period", Fn: fnSMA, Example: "SMA(CLOSE, 9, 1)",
+	},
+	{
+		Name: "WMA", Params: []Param{data, period}, Category: categoryReference,
+		Description: Localized{"加权移动平均，近期权重更大", "Linearly weighted moving average, recent bars weigh more"},
+		WarmUpParam: "period", Fn: fnWMA, Example: "WMA(CLOSE, 10)",
+	},
+	{
+		Name: "SUM", Params: []Param{data, period}, Category: categoryReference,
+		Description: Localized{"周期内求和，周期为 0 时从第一根 K 线累加", "Sum over period bars, or since the first bar when period is 0"},
+		WarmUpParam: "period", Fn: fnSUM, kind: sumKind, Example: "SUM(VOL, 0)",
+	},
+	{
+		Name: "REF", Params: []Param{data, period}, Category: categoryReference,
+		Description: Localized{"引用 period 周期前的数据", "Value of data period bars ago"},
+		WarmUp:      1, WarmUpParam: "period", Fn: fnREF, kind: kindOf(0), Example: "REF(CLOSE, 1)",
+	},
+	{
+		Name: "HHV", Params: []Param{data, period}, Category: categoryReference,
+		Description: Localized{"周期内最高值", "Highest value over period bars"},
+		WarmUpParam: "period", Fn: fnHHV, kind: kindOf(0), Example: "HHV(HIGH, 20)",
+	},
+	{
+		Name: "LLV", Params: []Param{data, period}, Category: categoryReference,
+		Description: Localized{"周期内最低值", "Lowest value over period bars"},
+		WarmUpParam: "period", Fn: fnLLV, kind: kindOf(0), Example: "LLV(LOW, 20)",
+	},
+	{
+		Name: "COUNT", Params: []Param{condition, period}, Returns: types.KindInt, Category: categoryReference,
+		Description: Localized{"周期内条件成立的次数", "Number of bars within period where condition holds"},
+		WarmUpParam: "period", Fn: fnCOUNT, Example: "COUNT(CLOSE > OPEN, 10)",
+	},
+	{
+		Name: "BARSLAST", Params: []Param{condition}, Returns: types.KindInt, Category: categoryReference,
+		Description: Localized{"上一次条件成立到当前的周期数", "Bars since condition last held"},
+		WarmUp:      1, Fn: fnBARSLAST, Example: "BARSLAST(CROSS(MA(C, 5), MA(C, 10)))",
+	},
+	{
+		Name: "FILTER", Params: []Param{condition, period}, Returns: types.KindBool, Category: categoryReference,
+		Description: Localized{"条件成立后的 period 周期内不再成立", "Condition with later signals cleared for period bars after each one"},
+		WarmUp:      1, Fn: fnFILTER, Example: "FILTER(CROSS(C, MA(C, 20)), 5)",
+	},
+	{
+		Name: "IF", Aliases: []string{"IFF"}, Params: []Param{either("condition"), either("then"), either("else")}, Category: categoryLogic,
+		Description: Localized{"条件成立返回 then，否则返回 else", "then where condition holds, else elsewhere"},
+		Fn:          fnIF, kind: kindOf(1, 2), Example: "IF(C > O, HIGH, LOW)",
+	},
+	{
+		Name: "CROSS", Params: []Param{either("a"), either("b")}, Returns: types.KindBool, Category: categoryLogic,
+		Description: Localized{"a 上穿 b", "1 on the bar where a crosses above b"},
+		WarmUp:      2, Fn: fnCROSS, Example: "CROSS(MA(C, 5), MA(C, 10))",
+	},
+	{
+		Name: "EVERY", Params: []Param{condition, period}, Returns: types.KindBool, Category: categoryLogic,
+		Description: Localized{"周期内条件一直成立", "Whether condition held on every bar within period"},
+		WarmUpParam: "period", Fn: fnEVERY, Example: "EVERY(C > O, 3)",
+	},
+	{
+		Name: "EXIST", Params: []Param{condition, period}, Returns: types.KindBool, Category: categoryLogic,
+		Description: Localized{"周期内条件至少成立一次", "Whether condition held on some bar within period"},
+		WarmUpParam: "period", Fn: fnEXIST, Example: "EXIST(C > REF(C, 1) * 1.09, 10)",
+	},
+	{
+		Name: "BETWEEN", Params: []Param{either("value"), either("lower"), either("upper")}, Returns: types.KindBool, Category: categoryLogic,
+		Description: Localized{"value 介于 lower 与 upper 之间", "Whether value lies between lower and upper inclusive"},
+		Fn:          fnBETWEEN, Example: "BETWEEN(C, LLV(L, 5), HHV(H, 5))",
+	},
+	{
+		Name: "MAX", Params: []Param{either("a"), either("b")}, Category: categoryMath,
+		Description: Localized{"两者中的较大值", "Larger of a and b"},
+		Fn:          fnMAX, kind: kindOf(0, 1), Example: "MAX(C - O, 0)",
+	},
+	{
+		Name: "MIN", Params: []Param{either("a"), either("b")}, Category: categoryMath,
+		Description: Localized{"两者中的较小值", "Smaller of a and b"},
+		Fn:          fnMIN, kind: kindOf(0, 1), Example: "MIN(O, C)",
+	},
+	{
+		Name: "ABS", Params: []Param{either("x")}, Category: categoryMath,
+		Description: Localized{"绝对值", "Absolute value"},
+		Fn:          fnABS, kind: kindOf(0), Example: "ABS(C - REF(C, 1))",
+	},
+	{
+		Name: "SQRT", Params: []Param{either("x")}, Category: categoryMath,
+		Description: Localized{"平方根", "Square root"},
+		Fn:          fnSQRT, Example: "SQRT(VAR(C, 20))",
+	},
+	{
+		Name: "STD", Params: []Param{data, period}, Category: categoryStatistic,
+		Description: Localized{"周期内的总体标准差", "Population standard deviation over period bars"},
+		WarmUpParam: "period", Fn: fnSTD, Example: "STD(CLOSE, 20)",
+	},
+	{
+		Name: "VAR", Params: []Param{data, period}, Category: categoryStatistic,
+		Description: Localized{"周期内的总体方差", "Population variance over period bars"},
+		WarmUpParam: "period", Fn: fnVAR, Example: "VAR(CLOSE, 20)",
+	},
+	{
+		Name: "AVEDEV", Params: []Param{data, period}, Category: categoryStatistic,
+		Description: Localized{"周期内的平均绝对偏差", "Mean absolute deviation over period bars"},
+		WarmUpParam: "period", Fn: fnAVEDEV, Example: "AVEDEV(CLOSE, 14)",
+	},
+	{
+		Name: "DATETODAY", Params: []Param{either("date")}, Returns: types.KindInt, Category: categoryTime,
+		Description: Localized{"从 date 到今天的自然日天数", "Calendar days from a TDX-encoded date to today"},
+		Fn:          fnDATETODAY, Example: "DATETODAY(DATE)",
+	},
+	{
+		Name: "FINANCE", Params: []Param{{Name: "n", Kind: ArgNumber}}, Category: categoryFinance,
+		Description: Localized{"第 n 号财务数据", "Fundamental field n of the symbol"},
+		symbol:      fnFINANCE, Example: "FINANCE(7)",
+	},
+	{
+		Name: "DYNAINFO", Params: []Param{{Name: "n", Kind: ArgNumber}}, Category: categoryQuote,
+		Description: Localized{"第 n 号即时行情数据", "Real-time snapshot field n of the symbol"},
+		symbol:      fnDYNAINFO, Example: "DYNAINFO(3)",
+	},
+	{
+		Name: "INBLOCK", Params: []Param{{Name: "block", Kind: ArgText}}, Returns: types.KindBool, Category: categoryBlock,
+		Description: Localized{"是否属于板块或行业", "Whether the symbol belongs to the block or industry"},
+		symbol:      fnINBLOCK, Example: "INBLOCK('银行')",
+	},
+}
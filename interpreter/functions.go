@@ -1,32 +1,82 @@
 package interpreter
 
 import (
-	"fmt"
 	"math"
 
 	"github.com/DTrader-store/formula-go/errors"
 	"github.com/DTrader-store/formula-go/types"
 )
 
-// fnMA implements Moving Average: MA(data, period)
-func fnMA(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("MA requires 2 arguments")
+// seriesLength returns the common length of the array arguments, or an error if they differ
+func seriesLength(values ...*Value) (int, error) {
+	n := -1
+	for _, v := range values {
+		if !v.IsArray {
+			continue
+		}
+		if n >= 0 && len(v.Array) != n {
+			return 0, errors.NewRuntimeError("array length mismatch")
+		}
+		n = len(v.Array)
+	}
+	if n < 0 {
+		n = 0
 	}
+	return n, nil
+}
 
-	data := args[0]
-	period := args[1]
+// broadcast returns the value as an array of length n, repeating a scalar for every bar
+func broadcast(v *Value, n int) []float64 {
+	if v.IsArray {
+		return v.Array
+	}
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = v.Single
+	}
+	return result
+}
 
-	if !data.IsArray {
-		return nil, errors.NewRuntimeError("MA first argument must be an array")
+// nanSeries returns n NaN values, the result of a window function whose period is longer than the data
+func nanSeries(n int) *Value {
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = math.NaN()
 	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("MA second argument must be a number")
+	return NewArrayValue(result)
+}
+
+// cumulativeSum returns the running total of data, skipping leading NaN values
+func cumulativeSum(data []float64) []float64 {
+	result := make([]float64, len(data))
+	sum := 0.0
+	started := false
+	for i, v := range data {
+		if math.IsNaN(v) {
+			if !started {
+				result[i] = math.NaN()
+				continue
+			}
+			v = 0
+		}
+		started = true
+		sum += v
+		result[i] = sum
 	}
+	return result
+}
+
+// fnMA implements Moving Average: MA(data, period)
+func fnMA(args []*Value, _ []*types.MarketData) (*Value, error) {
+	data := args[0]
+	period := args[1]
 
 	n := int(period.Single)
-	if n <= 0 || n > len(data.Array) {
-		return nil, errors.NewRuntimeError(fmt.Sprintf("MA period must be between 1 and %d", len(data.Array)))
+	if n <= 0 {
+		return nil, errors.NewRuntimeError("MA period must be positive")
+	}
+	if n > len(data.Array) {
+		return nanSeries(len(data.Array)), nil // Still warming up on every bar
 	}
 
 	result := make([]float64, len(data.Array))
@@ -50,23 +100,15 @@ func fnMA(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnEMA implements Exponential Moving Average: EMA(data, period)
 func fnEMA(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("EMA requires 2 arguments")
-	}
-
 	data := args[0]
 	period := args[1]
 
-	if !data.IsArray {
-		return nil, errors.NewRuntimeError("EMA first argument must be an array")
-	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("EMA second argument must be a number")
-	}
-
 	n := int(period.Single)
-	if n <= 0 || n > len(data.Array) {
-		return nil, errors.NewRuntimeError(fmt.Sprintf("EMA period must be between 1 and %d", len(data.Array)))
+	if n <= 0 {
+		return nil, errors.NewRuntimeError("EMA period must be positive")
+	}
+	if len(data.Array) == 0 {
+		return NewArrayValue([]float64{}), nil
 	}
 
 	alpha := 2.0 / float64(n+1)
@@ -85,23 +127,19 @@ func fnEMA(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnSUM implements Sum: SUM(data, period)
 func fnSUM(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("SUM requires 2 arguments")
-	}
-
 	data := args[0]
 	period := args[1]
 
-	if !data.IsArray {
-		return nil, errors.NewRuntimeError("SUM first argument must be an array")
+	n := int(period.Single)
+	if n == 0 {
+		// SUM(X, 0) accumulates from the first bar, as in TDX
+		return NewArrayValue(cumulativeSum(data.Array)), nil
 	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("SUM second argument must be a number")
+	if n < 0 {
+		return nil, errors.NewRuntimeError("SUM period must not be negative")
 	}
-
-	n := int(period.Single)
-	if n <= 0 || n > len(data.Array) {
-		return nil, errors.NewRuntimeError(fmt.Sprintf("SUM period must be between 1 and %d", len(data.Array)))
+	if n > len(data.Array) {
+		return nanSeries(len(data.Array)), nil // Still warming up on every bar
 	}
 
 	result := make([]float64, len(data.Array))
@@ -125,62 +163,46 @@ func fnSUM(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnMAX implements Max: MAX(a, b)
 func fnMAX(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("MAX requires 2 arguments")
-	}
-
 	a, b := args[0], args[1]
 
 	if !a.IsArray && !b.IsArray {
 		return NewSingleValue(math.Max(a.Single, b.Single)), nil
 	}
 
-	if a.IsArray && b.IsArray {
-		if len(a.Array) != len(b.Array) {
-			return nil, errors.NewRuntimeError("MAX: array length mismatch")
-		}
-		result := make([]float64, len(a.Array))
-		for i := range a.Array {
-			result[i] = math.Max(a.Array[i], b.Array[i])
-		}
-		return NewArrayValue(result), nil
+	n, err := seriesLength(a, b)
+	if err != nil {
+		return nil, errors.NewRuntimeError("MAX: array length mismatch")
 	}
-
-	return nil, errors.NewRuntimeError("MAX: incompatible argument types")
+	x, y := broadcast(a, n), broadcast(b, n)
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = math.Max(x[i], y[i])
+	}
+	return NewArrayValue(result), nil
 }
 
 // fnMIN implements Min: MIN(a, b)
 func fnMIN(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("MIN requires 2 arguments")
-	}
-
 	a, b := args[0], args[1]
 
 	if !a.IsArray && !b.IsArray {
 		return NewSingleValue(math.Min(a.Single, b.Single)), nil
 	}
 
-	if a.IsArray && b.IsArray {
-		if len(a.Array) != len(b.Array) {
-			return nil, errors.NewRuntimeError("MIN: array length mismatch")
-		}
-		result := make([]float64, len(a.Array))
-		for i := range a.Array {
-			result[i] = math.Min(a.Array[i], b.Array[i])
-		}
-		return NewArrayValue(result), nil
+	n, err := seriesLength(a, b)
+	if err != nil {
+		return nil, errors.NewRuntimeError("MIN: array length mismatch")
 	}
-
-	return nil, errors.NewRuntimeError("MIN: incompatible argument types")
+	x, y := broadcast(a, n), broadcast(b, n)
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = math.Min(x[i], y[i])
+	}
+	return NewArrayValue(result), nil
 }
 
 // fnABS implements Absolute value: ABS(value)
 func fnABS(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 1 {
-		return nil, errors.NewRuntimeError("ABS requires 1 argument")
-	}
-
 	val := args[0]
 
 	if !val.IsArray {
@@ -196,10 +218,6 @@ func fnABS(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnSQRT implements Square root: SQRT(value)
 func fnSQRT(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 1 {
-		return nil, errors.NewRuntimeError("SQRT requires 1 argument")
-	}
-
 	val := args[0]
 
 	if !val.IsArray {
@@ -215,20 +233,9 @@ func fnSQRT(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnREF implements Reference: REF(data, n) - reference data n periods ago
 func fnREF(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("REF requires 2 arguments")
-	}
-
 	data := args[0]
 	period := args[1]
 
-	if !data.IsArray {
-		return nil, errors.NewRuntimeError("REF first argument must be an array")
-	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("REF second argument must be a number")
-	}
-
 	n := int(period.Single)
 	if n < 0 {
 		return nil, errors.NewRuntimeError("REF period must be non-negative")
@@ -251,23 +258,15 @@ func fnREF(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnHHV implements Highest High Value: HHV(data, period)
 func fnHHV(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("HHV requires 2 arguments")
-	}
-
 	data := args[0]
 	period := args[1]
 
-	if !data.IsArray {
-		return nil, errors.NewRuntimeError("HHV first argument must be an array")
-	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("HHV second argument must be a number")
-	}
-
 	n := int(period.Single)
-	if n <= 0 || n > len(data.Array) {
-		return nil, errors.NewRuntimeError(fmt.Sprintf("HHV period must be between 1 and %d", len(data.Array)))
+	if n <= 0 {
+		return nil, errors.NewRuntimeError("HHV period must be positive")
+	}
+	if n > len(data.Array) {
+		return nanSeries(len(data.Array)), nil // Still warming up on every bar
 	}
 
 	result := make([]float64, len(data.Array))
@@ -293,23 +292,15 @@ func fnHHV(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnLLV implements Lowest Low Value: LLV(data, period)
 func fnLLV(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("LLV requires 2 arguments")
-	}
-
 	data := args[0]
 	period := args[1]
 
-	if !data.IsArray {
-		return nil, errors.NewRuntimeError("LLV first argument must be an array")
-	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("LLV second argument must be a number")
-	}
-
 	n := int(period.Single)
-	if n <= 0 || n > len(data.Array) {
-		return nil, errors.NewRuntimeError(fmt.Sprintf("LLV period must be between 1 and %d", len(data.Array)))
+	if n <= 0 {
+		return nil, errors.NewRuntimeError("LLV period must be positive")
+	}
+	if n > len(data.Array) {
+		return nanSeries(len(data.Array)), nil // Still warming up on every bar
 	}
 
 	result := make([]float64, len(data.Array))
@@ -335,10 +326,6 @@ func fnLLV(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnIF implements conditional: IF(condition, trueValue, falseValue)
 func fnIF(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 3 {
-		return nil, errors.NewRuntimeError("IF requires 3 arguments")
-	}
-
 	cond := args[0]
 	trueVal := args[1]
 	falseVal := args[2]
@@ -351,48 +338,48 @@ func fnIF(args []*Value, _ []*types.MarketData) (*Value, error) {
 		return falseVal, nil
 	}
 
-	// Handle array condition
-	if !trueVal.IsArray || !falseVal.IsArray {
-		return nil, errors.NewRuntimeError("IF: when condition is array, both true/false values must be arrays")
-	}
-
-	if len(cond.Array) != len(trueVal.Array) || len(cond.Array) != len(falseVal.Array) {
+	// Handle array condition, scalar branches are repeated for every bar
+	n, err := seriesLength(cond, trueVal, falseVal)
+	if err != nil {
 		return nil, errors.NewRuntimeError("IF: array length mismatch")
 	}
+	whenTrue, whenFalse := broadcast(trueVal, n), broadcast(falseVal, n)
 
-	result := make([]float64, len(cond.Array))
+	result := make([]float64, n)
 	for i := range cond.Array {
 		if cond.Array[i] != 0 {
-			result[i] = trueVal.Array[i]
+			result[i] = whenTrue[i]
 		} else {
-			result[i] = falseVal.Array[i]
+			result[i] = whenFalse[i]
 		}
 	}
 
 	return NewArrayValue(result), nil
 }
 
-// fnCROSS implements cross detection: CROSS(a, b) - returns 1 when a crosses above b
+// fnCROSS implements cross detection: CROSS(a, b) - returns 1 when a crosses above b.
+// Either side may be a number, as in CROSS(C, 10), but not both.
 func fnCROSS(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("CROSS requires 2 arguments")
-	}
-
 	a, b := args[0], args[1]
 
-	if !a.IsArray || !b.IsArray {
-		return nil, errors.NewRuntimeError("CROSS requires array arguments")
+	if !a.IsArray && !b.IsArray {
+		return nil, errors.NewRuntimeError("CROSS requires at least one series argument")
 	}
 
-	if len(a.Array) != len(b.Array) {
+	n, err := seriesLength(a, b)
+	if err != nil {
 		return nil, errors.NewRuntimeError("CROSS: array length mismatch")
 	}
+	x, y := broadcast(a, n), broadcast(b, n)
 
-	result := make([]float64, len(a.Array))
+	result := make([]float64, n)
+	if len(result) == 0 {
+		return NewArrayValue(result), nil
+	}
 	result[0] = 0 // First element is always 0
 
-	for i := 1; i < len(a.Array); i++ {
-		if a.Array[i-1] <= b.Array[i-1] && a.Array[i] > b.Array[i] {
+	for i := 1; i < n; i++ {
+		if x[i-1] <= y[i-1] && x[i] > y[i] {
 			result[i] = 1
 		} else {
 			result[i] = 0
@@ -1,7 +1,6 @@
 package interpreter
 
 import (
-	"fmt"
 	"math"
 
 	"github.com/DTrader-store/formula-go/errors"
@@ -12,23 +11,15 @@ import (
 
 // fnSTD implements Standard Deviation: STD(data, period)
 func fnSTD(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("STD requires 2 arguments")
-	}
-
 	data := args[0]
 	period := args[1]
 
-	if !data.IsArray {
-		return nil, errors.NewRuntimeError("STD first argument must be an array")
-	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("STD second argument must be a number")
-	}
-
 	n := int(period.Single)
-	if n <= 0 || n > len(data.Array) {
-		return nil, errors.NewRuntimeError(fmt.Sprintf("STD period must be between 1 and %d", len(data.Array)))
+	if n <= 0 {
+		return nil, errors.NewRuntimeError("STD period must be positive")
+	}
+	if n > len(data.Array) {
+		return nanSeries(len(data.Array)), nil // Still warming up on every bar
 	}
 
 	result := make([]float64, len(data.Array))
@@ -63,23 +54,15 @@ func fnSTD(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnVAR implements Variance: VAR(data, period)
 func fnVAR(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("VAR requires 2 arguments")
-	}
-
 	data := args[0]
 	period := args[1]
 
-	if !data.IsArray {
-		return nil, errors.NewRuntimeError("VAR first argument must be an array")
-	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("VAR second argument must be a number")
-	}
-
 	n := int(period.Single)
-	if n <= 0 || n > len(data.Array) {
-		return nil, errors.NewRuntimeError(fmt.Sprintf("VAR period must be between 1 and %d", len(data.Array)))
+	if n <= 0 {
+		return nil, errors.NewRuntimeError("VAR period must be positive")
+	}
+	if n > len(data.Array) {
+		return nanSeries(len(data.Array)), nil // Still warming up on every bar
 	}
 
 	result := make([]float64, len(data.Array))
@@ -110,30 +93,52 @@ func fnVAR(args []*Value, _ []*types.MarketData) (*Value, error) {
 	return NewArrayValue(result), nil
 }
 
-// fnSMA implements Simple Moving Average (alias for MA): SMA(data, period)
+// fnSMA implements SMA(data, period) as an alias for MA, and the TDX
+// weighted form SMA(data, period, weight): Y = (weight*X + (period-weight)*Y') / period
 func fnSMA(args []*Value, data []*types.MarketData) (*Value, error) {
-	return fnMA(args, data)
+	if len(args) != 3 {
+		return fnMA(args, data)
+	}
+
+	series := args[0]
+	period := args[1]
+	weight := args[2]
+
+	n := period.Single
+	m := weight.Single
+	if n <= 0 || m <= 0 || m > n {
+		return nil, errors.NewRuntimeError("SMA weight must be between 1 and period")
+	}
+
+	result := make([]float64, len(series.Array))
+	prev := math.NaN()
+	for i, v := range series.Array {
+		switch {
+		case math.IsNaN(v):
+			// Keep the previous smoothed value across invalid bars
+		case math.IsNaN(prev):
+			// Seed with the first valid value
+			prev = v
+		default:
+			prev = (m*v + (n-m)*prev) / n
+		}
+		result[i] = prev
+	}
+
+	return NewArrayValue(result), nil
 }
 
 // fnWMA implements Weighted Moving Average: WMA(data, period)
 func fnWMA(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("WMA requires 2 arguments")
-	}
-
 	data := args[0]
 	period := args[1]
 
-	if !data.IsArray {
-		return nil, errors.NewRuntimeError("WMA first argument must be an array")
-	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("WMA second argument must be a number")
-	}
-
 	n := int(period.Single)
-	if n <= 0 || n > len(data.Array) {
-		return nil, errors.NewRuntimeError(fmt.Sprintf("WMA period must be between 1 and %d", len(data.Array)))
+	if n <= 0 {
+		return nil, errors.NewRuntimeError("WMA period must be positive")
+	}
+	if n > len(data.Array) {
+		return nanSeries(len(data.Array)), nil // Still warming up on every bar
 	}
 
 	result := make([]float64, len(data.Array))
@@ -161,23 +166,15 @@ func fnWMA(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnCOUNT implements Count: COUNT(condition, period)
 func fnCOUNT(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("COUNT requires 2 arguments")
-	}
-
 	condition := args[0]
 	period := args[1]
 
-	if !condition.IsArray {
-		return nil, errors.NewRuntimeError("COUNT first argument must be an array")
-	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("COUNT second argument must be a number")
-	}
-
 	n := int(period.Single)
-	if n <= 0 || n > len(condition.Array) {
-		return nil, errors.NewRuntimeError(fmt.Sprintf("COUNT period must be between 1 and %d", len(condition.Array)))
+	if n <= 0 {
+		return nil, errors.NewRuntimeError("COUNT period must be positive")
+	}
+	if n > len(condition.Array) {
+		return nanSeries(len(condition.Array)), nil // Still warming up on every bar
 	}
 
 	result := make([]float64, len(condition.Array))
@@ -203,23 +200,15 @@ func fnCOUNT(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnEVERY implements Every: EVERY(condition, period) - returns 1 if condition is true for all periods
 func fnEVERY(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("EVERY requires 2 arguments")
-	}
-
 	condition := args[0]
 	period := args[1]
 
-	if !condition.IsArray {
-		return nil, errors.NewRuntimeError("EVERY first argument must be an array")
-	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("EVERY second argument must be a number")
-	}
-
 	n := int(period.Single)
-	if n <= 0 || n > len(condition.Array) {
-		return nil, errors.NewRuntimeError(fmt.Sprintf("EVERY period must be between 1 and %d", len(condition.Array)))
+	if n <= 0 {
+		return nil, errors.NewRuntimeError("EVERY period must be positive")
+	}
+	if n > len(condition.Array) {
+		return nanSeries(len(condition.Array)), nil // Still warming up on every bar
 	}
 
 	result := make([]float64, len(condition.Array))
@@ -250,23 +239,15 @@ func fnEVERY(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnEXIST implements Exist: EXIST(condition, period) - returns 1 if condition is true for any period
 func fnEXIST(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("EXIST requires 2 arguments")
-	}
-
 	condition := args[0]
 	period := args[1]
 
-	if !condition.IsArray {
-		return nil, errors.NewRuntimeError("EXIST first argument must be an array")
-	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("EXIST second argument must be a number")
-	}
-
 	n := int(period.Single)
-	if n <= 0 || n > len(condition.Array) {
-		return nil, errors.NewRuntimeError(fmt.Sprintf("EXIST period must be between 1 and %d", len(condition.Array)))
+	if n <= 0 {
+		return nil, errors.NewRuntimeError("EXIST period must be positive")
+	}
+	if n > len(condition.Array) {
+		return nanSeries(len(condition.Array)), nil // Still warming up on every bar
 	}
 
 	result := make([]float64, len(condition.Array))
@@ -297,16 +278,8 @@ func fnEXIST(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnBARSLAST implements BarsLast: BARSLAST(condition) - returns bars since last true condition
 func fnBARSLAST(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 1 {
-		return nil, errors.NewRuntimeError("BARSLAST requires 1 argument")
-	}
-
 	condition := args[0]
 
-	if !condition.IsArray {
-		return nil, errors.NewRuntimeError("BARSLAST argument must be an array")
-	}
-
 	result := make([]float64, len(condition.Array))
 	lastTrueIndex := -1
 
@@ -326,23 +299,15 @@ func fnBARSLAST(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnAVEDEV implements Average Deviation: AVEDEV(data, period)
 func fnAVEDEV(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("AVEDEV requires 2 arguments")
-	}
-
 	data := args[0]
 	period := args[1]
 
-	if !data.IsArray {
-		return nil, errors.NewRuntimeError("AVEDEV first argument must be an array")
-	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("AVEDEV second argument must be a number")
-	}
-
 	n := int(period.Single)
-	if n <= 0 || n > len(data.Array) {
-		return nil, errors.NewRuntimeError(fmt.Sprintf("AVEDEV period must be between 1 and %d", len(data.Array)))
+	if n <= 0 {
+		return nil, errors.NewRuntimeError("AVEDEV period must be positive")
+	}
+	if n > len(data.Array) {
+		return nanSeries(len(data.Array)), nil // Still warming up on every bar
 	}
 
 	result := make([]float64, len(data.Array))
@@ -374,20 +339,9 @@ func fnAVEDEV(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnFILTER implements Filter: FILTER(condition, period) - filters signals
 func fnFILTER(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 2 {
-		return nil, errors.NewRuntimeError("FILTER requires 2 arguments")
-	}
-
 	condition := args[0]
 	period := args[1]
 
-	if !condition.IsArray {
-		return nil, errors.NewRuntimeError("FILTER first argument must be an array")
-	}
-	if period.IsArray {
-		return nil, errors.NewRuntimeError("FILTER second argument must be a number")
-	}
-
 	n := int(period.Single)
 	if n <= 0 {
 		return nil, errors.NewRuntimeError("FILTER period must be positive")
@@ -410,10 +364,6 @@ func fnFILTER(args []*Value, _ []*types.MarketData) (*Value, error) {
 
 // fnBETWEEN implements Between: BETWEEN(value, lower, upper)
 func fnBETWEEN(args []*Value, _ []*types.MarketData) (*Value, error) {
-	if len(args) != 3 {
-		return nil, errors.NewRuntimeError("BETWEEN requires 3 arguments")
-	}
-
 	value := args[0]
 	lower := args[1]
 	upper := args[2]
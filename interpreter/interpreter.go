@@ -4,6 +4,8 @@ package interpreter
 import (
 	"fmt"
 	"math"
+	"sort"
+	"strings"
 
 	"github.com/DTrader-store/formula-go/errors"
 	"github.com/DTrader-store/formula-go/parser/ast"
@@ -12,9 +14,12 @@ import (
 
 // Value represents a computed value (can be single value or array)
 type Value struct {
-	Single float64    // Single value
-	Array  []float64  // Array of values
-	IsArray bool     // Whether this is an array value
+	Single  float64         // Single value
+	Array   []float64       // Array of values
+	IsArray bool            // Whether this is an array value
+	Text    string          // Text value, e.g. a block name
+	IsText  bool            // Whether this is a text value
+	Kind    types.ValueKind // What the numbers mean (float, bool or int); KindText for text
 }
 
 // NewSingleValue creates a single value
@@ -27,32 +32,158 @@ func NewArrayValue(arr []float64) *Value {
 	return &Value{Array: arr, IsArray: true}
 }
 
+// NewTextValue creates a text value
+func NewTextValue(text string) *Value {
+	return &Value{Text: text, IsText: true, Kind: types.KindText}
+}
+
 // Interpreter executes formula ASTs
 type Interpreter struct {
-	marketData  []*types.MarketData
-	variables   map[string]*Value
-	userVars    []string  // Track user-defined variables in order
+	series      *types.Series       // Market data bound to formula variables
+	marketData  []*types.MarketData // Row view of series for functions; built on first use when absent
+	variables   map[string]*Value   // keyed by upper-cased name
+	userVars    []string            // Track user-defined variables in order, as spelled by the user
 	functions   *FunctionRegistry
+	repository  FormulaRepository // Resolves references to other formulas
+	refs        *referenceContext // Memoized references and cycle detection
+	period      types.Period      // Period of the data when resampled; empty for the base data
+	frames      map[types.Period]*periodFrame
+	hideForming bool              // Whether #PERIOD hides the still-forming period bar from the last base bar
+	columns     []column          // Columns bound with BindColumn
+	symbol      *types.SymbolInfo // Symbol the data belongs to; nil when unknown
+	others      *otherSymbols     // Other symbols' data (INDEXC, "SH000001$CLOSE"); nil when not configured
+	bound       bool              // Whether the built-in and bound column variables are set
+}
+
+// column is a named per-bar input series
+type column struct {
+	name   string
+	values []float64
 }
 
 // NewInterpreter creates a new Interpreter
 func NewInterpreter(marketData []*types.MarketData) *Interpreter {
+	interp := NewSeriesInterpreter(types.SeriesFromMarketData(marketData))
+	interp.marketData = marketData
+	return interp
+}
+
+// NewSeriesInterpreter creates an Interpreter over columnar market data. Formula variables share
+// the series columns, so the caller must not modify them during execution.
+func NewSeriesInterpreter(series *types.Series) *Interpreter {
 	return &Interpreter{
-		marketData: marketData,
-		variables:  make(map[string]*Value),
-		userVars:   make([]string, 0),
-		functions:  NewFunctionRegistry(),
+		series:    series,
+		variables: make(map[string]*Value),
+		userVars:  make([]string, 0),
+		functions: NewFunctionRegistry(),
+		refs:      newReferenceContext(),
+		frames:    make(map[types.Period]*periodFrame),
+	}
+}
+
+// bars returns the market data as rows, converting the series the first time rows are needed
+func (interp *Interpreter) bars() []*types.MarketData {
+	if interp.marketData == nil && interp.series.Len() > 0 {
+		interp.marketData = interp.series.MarketData()
+	}
+	return interp.marketData
+}
+
+// SetFunctionRegistry replaces the registry used to resolve function calls
+func (interp *Interpreter) SetFunctionRegistry(registry *FunctionRegistry) {
+	interp.functions = registry
+}
+
+// SetVariable predefines a variable before execution, e.g. a formula parameter.
+// Predefined variables are visible to the formula but not reported as outputs.
+func (interp *Interpreter) SetVariable(name string, value *Value) {
+	interp.variables[variableKey(name)] = value
+}
+
+// variableKey normalizes a variable name; variables, like functions, are case-insensitive
+func variableKey(name string) string {
+	return strings.ToUpper(name)
+}
+
+// variableAliases maps the short TDX names to the built-in series they stand for
+var variableAliases = map[string]string{
+	"O":   "OPEN",
+	"C":   "CLOSE",
+	"H":   "HIGH",
+	"L":   "LOW",
+	"V":   "VOLUME",
+	"VOL": "VOLUME",
+	"AMO": "AMOUNT",
+}
+
+// builtinVariables are the variables bound from market data or constants, besides the calendar and
+// index variables, and whether each is a series
+var builtinVariables = map[string]bool{
+	"OPEN": true, "CLOSE": true, "HIGH": true, "LOW": true, "VOLUME": true, "AMOUNT": true,
+	"OPI": true, "SETTLE": true, "ADVANCE": true, "DECLINE": true,
+	"DRAWNULL": false, "CAPITAL": false, "TOTALCAPITAL": false,
+}
+
+// BuiltinVariable reports whether name (case-insensitive) is a variable the interpreter binds
+// itself, such as CLOSE, C, DATE or CAPITAL, and whether it is a series rather than a single value.
+// Calendar variables need bar times and INDEXC and friends need a data provider.
+func BuiltinVariable(name string) (series bool, ok bool) {
+	name = variableKey(name)
+	if alias, isAlias := variableAliases[name]; isAlias {
+		name = alias
 	}
+	if series, ok := builtinVariables[name]; ok {
+		return series, true
+	}
+	if _, isCalendar := calendarFields[name]; isCalendar {
+		return true, true
+	}
+	if _, isIndex := indexFields[name]; isIndex {
+		return true, true
+	}
+	return false, false
+}
+
+// BuiltinVariables returns the names BuiltinVariable accepts, including the short aliases, sorted
+func BuiltinVariables() []string {
+	var names []string
+	for name := range builtinVariables {
+		names = append(names, name)
+	}
+	for name := range variableAliases {
+		names = append(names, name)
+	}
+	for name := range calendarFields {
+		names = append(names, name)
+	}
+	for name := range indexFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupVariable resolves a variable by name, falling back to the built-in aliases (C, VOL, ...)
+// unless the formula defines a variable with that name itself
+func (interp *Interpreter) lookupVariable(name string) (*Value, bool) {
+	key := variableKey(name)
+	if value, exists := interp.variables[key]; exists {
+		return value, true
+	}
+	if target, ok := variableAliases[key]; ok {
+		value, exists := interp.variables[target]
+		return value, exists
+	}
+	return nil, false
 }
 
 // Execute executes a program and returns the result
 func (interp *Interpreter) Execute(program *ast.Program) (*types.FormulaResult, error) {
-	// Initialize market data variables
-	interp.initMarketDataVariables()
+	interp.bind()
 
 	// Execute all statements
 	for _, stmt := range program.Body {
-		if err := interp.executeStatement(stmt); err != nil {
+		if _, err := interp.executeStatement(stmt); err != nil {
 			return nil, err
 		}
 	}
@@ -61,39 +192,104 @@ func (interp *Interpreter) Execute(program *ast.Program) (*types.FormulaResult,
 	return interp.buildResult(), nil
 }
 
-// initMarketDataVariables initializes built-in market data variables
+// ExecuteStatement executes one statement and returns its value. Statements see the variables of
+// earlier calls, so a formula can be run a statement at a time, e.g. in a REPL.
+func (interp *Interpreter) ExecuteStatement(stmt ast.Statement) (*Value, error) {
+	if !interp.bound {
+		interp.bind()
+	}
+	return interp.executeStatement(stmt)
+}
+
+// Variable returns the value of a variable, built-in or defined by the formula
+func (interp *Interpreter) Variable(name string) (*Value, bool) {
+	return interp.lookupVariable(name)
+}
+
+// UserVariables returns the variables defined by the formula so far, in order of first definition
+func (interp *Interpreter) UserVariables() []string {
+	names := make([]string, 0, len(interp.userVars))
+	seen := make(map[string]bool, len(interp.userVars))
+	for _, name := range interp.userVars {
+		if key := variableKey(name); !seen[key] {
+			seen[key] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// bind initializes the constants, the market data variables and the bound columns
+func (interp *Interpreter) bind() {
+	interp.initConstants()
+	interp.initMarketDataVariables()
+	for _, col := range interp.columns {
+		interp.variables[variableKey(col.name)] = NewArrayValue(col.values)
+	}
+	interp.bound = true
+}
+
+// initMarketDataVariables binds the built-in market data variables directly to the series columns
 func (interp *Interpreter) initMarketDataVariables() {
-	if len(interp.marketData) == 0 {
+	series := interp.series
+	n := series.Len()
+	if n == 0 {
 		return
 	}
 
-	n := len(interp.marketData)
-	open := make([]float64, n)
-	close := make([]float64, n)
-	high := make([]float64, n)
-	low := make([]float64, n)
-	volume := make([]float64, n)
-	amount := make([]float64, n)
+	var zeros []float64 // shared by every absent optional column; series values are never modified
+	optional := func(values []float64) *Value {
+		if values != nil {
+			return NewArrayValue(values)
+		}
+		if zeros == nil {
+			zeros = make([]float64, n)
+		}
+		return NewArrayValue(zeros)
+	}
+
+	interp.variables["OPEN"] = NewArrayValue(series.Open)
+	interp.variables["CLOSE"] = NewArrayValue(series.Close)
+	interp.variables["HIGH"] = NewArrayValue(series.High)
+	interp.variables["LOW"] = NewArrayValue(series.Low)
+	interp.variables["VOLUME"] = NewArrayValue(series.Volume)
+	interp.variables["AMOUNT"] = NewArrayValue(series.Amount)
+	interp.variables["OPI"] = optional(series.OpenInterest)
+	interp.variables["SETTLE"] = optional(series.Settle)
+	interp.variables["ADVANCE"] = optional(series.Advance)
+	interp.variables["DECLINE"] = optional(series.Decline)
+
+	interp.initCalendarVariables()
+	interp.initExtraVariables()
+}
 
-	for i, data := range interp.marketData {
-		open[i] = data.Open
-		close[i] = data.Close
-		high[i] = data.High
-		low[i] = data.Low
-		volume[i] = data.Volume
-		amount[i] = data.Amount
+// initExtraVariables binds every custom column (MarketData.Extra) as a series; missing values are NaN.
+// Extra columns never replace built-in variables, including the short aliases such as C and VOL.
+func (interp *Interpreter) initExtraVariables() {
+	for extra, values := range interp.series.Extra {
+		name := variableKey(extra)
+		if _, exists := interp.variables[name]; exists {
+			continue
+		}
+		if _, builtin := BuiltinVariable(name); builtin {
+			continue
+		}
+		interp.variables[name] = NewArrayValue(values)
 	}
+}
 
-	interp.variables["OPEN"] = NewArrayValue(open)
-	interp.variables["CLOSE"] = NewArrayValue(close)
-	interp.variables["HIGH"] = NewArrayValue(high)
-	interp.variables["LOW"] = NewArrayValue(low)
-	interp.variables["VOLUME"] = NewArrayValue(volume)
-	interp.variables["AMOUNT"] = NewArrayValue(amount)
+// BindColumn exposes a column of per-bar values as a formula variable, e.g. alternative data kept
+// outside MarketData. The column must have one value per bar. Bound columns are inputs, not outputs.
+func (interp *Interpreter) BindColumn(name string, values []float64) error {
+	if len(values) != interp.series.Len() {
+		return errors.NewRuntimeError(fmt.Sprintf("column %s has %d values for %d bars", name, len(values), interp.series.Len()))
+	}
+	interp.columns = append(interp.columns, column{name: name, values: values})
+	return nil
 }
 
-// executeStatement executes a single statement
-func (interp *Interpreter) executeStatement(stmt ast.Statement) error {
+// executeStatement executes a single statement and returns its value
+func (interp *Interpreter) executeStatement(stmt ast.Statement) (*Value, error) {
 	switch s := stmt.(type) {
 	case *ast.VariableDeclaration:
 		return interp.executeVariableDeclaration(s)
@@ -103,41 +299,41 @@ func (interp *Interpreter) executeStatement(stmt ast.Statement) error {
 		// For standalone expressions, evaluate and add to output with temp name
 		value, err := interp.evaluateExpression(s.Expr)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		// Generate a name for standalone expressions
 		name := "__expr__"
 		if ident, ok := s.Expr.(*ast.Identifier); ok {
 			name = ident.Name
 		}
-		interp.variables[name] = value
+		interp.variables[variableKey(name)] = value
 		interp.userVars = append(interp.userVars, name)
-		return nil
+		return value, nil
 	default:
-		return errors.NewRuntimeError(fmt.Sprintf("unknown statement type: %T", stmt))
+		return nil, errors.NewRuntimeError(fmt.Sprintf("unknown statement type: %T", stmt))
 	}
 }
 
 // executeVariableDeclaration executes a variable declaration
-func (interp *Interpreter) executeVariableDeclaration(decl *ast.VariableDeclaration) error {
+func (interp *Interpreter) executeVariableDeclaration(decl *ast.VariableDeclaration) (*Value, error) {
 	value, err := interp.evaluateExpression(decl.Value)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	interp.variables[decl.Name] = value
-	interp.userVars = append(interp.userVars, decl.Name)  // Preserve order
-	return nil
+	interp.variables[variableKey(decl.Name)] = value
+	interp.userVars = append(interp.userVars, decl.Name) // Preserve order
+	return value, nil
 }
 
 // executeOutputDeclaration executes an output declaration
-func (interp *Interpreter) executeOutputDeclaration(decl *ast.OutputDeclaration) error {
+func (interp *Interpreter) executeOutputDeclaration(decl *ast.OutputDeclaration) (*Value, error) {
 	value, err := interp.evaluateExpression(decl.Value)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	interp.variables[decl.Name] = value
-	// Mark as output (we'll handle this in buildResult)
-	return nil
+	interp.variables[variableKey(decl.Name)] = value
+	interp.userVars = append(interp.userVars, decl.Name) // Preserve order
+	return value, nil
 }
 
 // evaluateExpression evaluates an expression and returns a value
@@ -145,6 +341,8 @@ func (interp *Interpreter) evaluateExpression(expr ast.Expression) (*Value, erro
 	switch e := expr.(type) {
 	case *ast.NumberLiteral:
 		return interp.evaluateNumberLiteral(e)
+	case *ast.StringLiteral:
+		return NewTextValue(e.Value), nil
 	case *ast.Identifier:
 		return interp.evaluateIdentifier(e)
 	case *ast.BinaryExpression:
@@ -153,6 +351,12 @@ func (interp *Interpreter) evaluateExpression(expr ast.Expression) (*Value, erro
 		return interp.evaluateUnaryExpression(e)
 	case *ast.FunctionCall:
 		return interp.evaluateFunctionCall(e)
+	case *ast.FormulaReference:
+		return interp.evaluateFormulaReference(e)
+	case *ast.PeriodExpression:
+		return interp.evaluatePeriodExpression(e)
+	case *ast.SymbolReference:
+		return interp.evaluateSymbolReference(e)
 	default:
 		return nil, errors.NewRuntimeError(fmt.Sprintf("unknown expression type: %T", expr))
 	}
@@ -160,13 +364,19 @@ func (interp *Interpreter) evaluateExpression(expr ast.Expression) (*Value, erro
 
 // evaluateNumberLiteral evaluates a number literal
 func (interp *Interpreter) evaluateNumberLiteral(lit *ast.NumberLiteral) (*Value, error) {
-	return NewSingleValue(lit.Value), nil
+	return withKind(NewSingleValue(lit.Value), numberKind(lit.Value)), nil
 }
 
 // evaluateIdentifier evaluates an identifier
 func (interp *Interpreter) evaluateIdentifier(id *ast.Identifier) (*Value, error) {
-	value, exists := interp.variables[id.Name]
+	value, exists := interp.lookupVariable(id.Name)
 	if !exists {
+		if field, ok := indexFields[variableKey(id.Name)]; ok {
+			if interp.others == nil || interp.others.benchmark == "" {
+				return nil, errors.NewRuntimeError(fmt.Sprintf("%s requires a data provider with a benchmark", id.Name))
+			}
+			return interp.symbolField(interp.others.benchmark, field)
+		}
 		return nil, errors.NewRuntimeError(fmt.Sprintf("undefined variable: %s", id.Name))
 	}
 	return value, nil
@@ -183,17 +393,26 @@ func (interp *Interpreter) evaluateBinaryExpression(expr *ast.BinaryExpression)
 	if err != nil {
 		return nil, err
 	}
+	if left.IsText || right.IsText {
+		return nil, errors.NewRuntimeError(fmt.Sprintf("operator %s cannot be applied to text", expr.Operator))
+	}
 
 	// Handle array operations
+	var result *Value
 	if left.IsArray && right.IsArray {
-		return interp.binaryOpArrayArray(expr.Operator, left.Array, right.Array)
+		result, err = interp.binaryOpArrayArray(expr.Operator, left.Array, right.Array)
 	} else if left.IsArray {
-		return interp.binaryOpArrayScalar(expr.Operator, left.Array, right.Single)
+		result, err = interp.binaryOpArrayScalar(expr.Operator, left.Array, right.Single)
 	} else if right.IsArray {
-		return interp.binaryOpScalarArray(expr.Operator, left.Single, right.Array)
+		result, err = interp.binaryOpScalarArray(expr.Operator, left.Single, right.Array)
 	} else {
-		return interp.binaryOpScalarScalar(expr.Operator, left.Single, right.Single)
+		result, err = interp.binaryOpScalarScalar(expr.Operator, left.Single, right.Single)
 	}
+	if err != nil {
+		return nil, err
+	}
+	result.Kind = binaryKind(expr.Operator, left.Kind, right.Kind)
+	return result, nil
 }
 
 // binaryOpScalarScalar performs binary operation on two scalars
@@ -266,7 +485,9 @@ func (interp *Interpreter) binaryOpScalarScalar(op ast.BinaryOperator, a, b floa
 	return NewSingleValue(result), nil
 }
 
-// binaryOpArrayArray performs binary operation on two arrays
+// binaryOpArrayArray performs binary operation on two arrays.
+// Division by zero inside a series yields NaN (an invalid bar) instead of failing the whole formula,
+// as TDX does; only dividing two scalars by zero is an error.
 func (interp *Interpreter) binaryOpArrayArray(op ast.BinaryOperator, a, b []float64) (*Value, error) {
 	if len(a) != len(b) {
 		return nil, errors.NewRuntimeError("array length mismatch")
@@ -274,6 +495,10 @@ func (interp *Interpreter) binaryOpArrayArray(op ast.BinaryOperator, a, b []floa
 
 	result := make([]float64, len(a))
 	for i := range a {
+		if op == ast.OpDivide && b[i] == 0 {
+			result[i] = math.NaN()
+			continue
+		}
 		val, err := interp.binaryOpScalarScalar(op, a[i], b[i])
 		if err != nil {
 			return nil, err
@@ -288,6 +513,10 @@ func (interp *Interpreter) binaryOpArrayArray(op ast.BinaryOperator, a, b []floa
 func (interp *Interpreter) binaryOpArrayScalar(op ast.BinaryOperator, arr []float64, scalar float64) (*Value, error) {
 	result := make([]float64, len(arr))
 	for i, v := range arr {
+		if op == ast.OpDivide && scalar == 0 {
+			result[i] = math.NaN()
+			continue
+		}
 		val, err := interp.binaryOpScalarScalar(op, v, scalar)
 		if err != nil {
 			return nil, err
@@ -301,6 +530,10 @@ func (interp *Interpreter) binaryOpArrayScalar(op ast.BinaryOperator, arr []floa
 func (interp *Interpreter) binaryOpScalarArray(op ast.BinaryOperator, scalar float64, arr []float64) (*Value, error) {
 	result := make([]float64, len(arr))
 	for i, v := range arr {
+		if op == ast.OpDivide && v == 0 {
+			result[i] = math.NaN()
+			continue
+		}
 		val, err := interp.binaryOpScalarScalar(op, scalar, v)
 		if err != nil {
 			return nil, err
@@ -316,16 +549,23 @@ func (interp *Interpreter) evaluateUnaryExpression(expr *ast.UnaryExpression) (*
 	if err != nil {
 		return nil, err
 	}
+	if operand.IsText {
+		return nil, errors.NewRuntimeError(fmt.Sprintf("operator %s cannot be applied to text", expr.Operator))
+	}
 
 	if expr.Operator == ast.OpUnaryMinus {
+		kind := types.KindFloat
+		if operand.Kind.Whole() {
+			kind = types.KindInt
+		}
 		if operand.IsArray {
 			result := make([]float64, len(operand.Array))
 			for i, v := range operand.Array {
 				result[i] = -v
 			}
-			return NewArrayValue(result), nil
+			return withKind(NewArrayValue(result), kind), nil
 		}
-		return NewSingleValue(-operand.Single), nil
+		return withKind(NewSingleValue(-operand.Single), kind), nil
 	}
 
 	return nil, errors.NewRuntimeError(fmt.Sprintf("unknown unary operator: %s", expr.Operator))
@@ -343,19 +583,41 @@ func (interp *Interpreter) evaluateFunctionCall(call *ast.FunctionCall) (*Value,
 		args[i] = val
 	}
 
-	// Call function
-	return interp.functions.Call(call.Name, args, interp.marketData)
+	// Fall back to a user formula when no function has this name
+	if !interp.functions.Has(call.Name) && interp.repository != nil {
+		if _, ok := interp.repository.LookupFormula(call.Name); ok {
+			return interp.callFormula(call.Name, "", args)
+		}
+	}
+
+	// Functions backed by a formula run through the same cache as formula references
+	if desc, ok := interp.functions.Lookup(call.Name); ok && desc.Repository != nil {
+		if err := desc.check(args); err != nil {
+			return nil, err
+		}
+		return interp.runReference(desc.Repository, desc.Name, "", desc.withDefaults(args))
+	}
+
+	// Call function; symbol functions (FINANCE, DYNAINFO, ...) read the interpreter's symbol info
+	return interp.functions.call(call.Name, args, interp.bars, interp.symbol)
 }
 
 // buildResult builds the final formula result
 func (interp *Interpreter) buildResult() *types.FormulaResult {
 	result := types.NewFormulaResult()
+	if interp.series.HasTime() {
+		result.Times = interp.series.Time
+	}
 
 	// Add user-defined variables to result in order
 	for _, name := range interp.userVars {
-		value := interp.variables[name]
+		value := interp.variables[variableKey(name)]
+		if value.IsText {
+			continue
+		}
 		if value.IsArray {
 			result.AddOutput(name, value.Array, nil)
+			result.Outputs[len(result.Outputs)-1].Kind = value.Kind
 		} else {
 			result.SetVariable(name, value.Single)
 		}
@@ -0,0 +1,92 @@
+package interpreter
+
+import (
+	"math"
+
+	"github.com/DTrader-store/formula-go/parser/ast"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// kindRule gives the kind of a function's result from its arguments
+type kindRule func(args []*Value) types.ValueKind
+
+// always is a rule for functions whose result kind does not depend on the arguments
+func always(kind types.ValueKind) kindRule {
+	return func([]*Value) types.ValueKind { return kind }
+}
+
+// kindOf is a rule for functions whose result has the common kind of the given arguments
+func kindOf(indices ...int) kindRule {
+	return func(args []*Value) types.ValueKind {
+		kinds := make([]types.ValueKind, 0, len(indices))
+		for _, i := range indices {
+			if i < len(args) {
+				kinds = append(kinds, args[i].Kind)
+			}
+		}
+		return commonKind(kinds...)
+	}
+}
+
+// sumKind is the rule of SUM: the sum of whole numbers is a whole number
+func sumKind(args []*Value) types.ValueKind {
+	if len(args) > 0 && args[0].Kind.Whole() {
+		return types.KindInt
+	}
+	return types.KindFloat
+}
+
+// commonKind returns the kind of a value that may come from any of the given kinds:
+// the kind itself when all agree, int when all are whole numbers, otherwise float
+func commonKind(kinds ...types.ValueKind) types.ValueKind {
+	if len(kinds) == 0 {
+		return types.KindFloat
+	}
+	whole := true
+	for _, kind := range kinds {
+		whole = whole && kind.Whole()
+	}
+	for _, kind := range kinds[1:] {
+		if kind != kinds[0] {
+			if whole {
+				return types.KindInt
+			}
+			return types.KindFloat
+		}
+	}
+	return kinds[0]
+}
+
+// binaryKind returns the kind of a binary operation's result: comparisons and logical operators
+// give bool, and adding, subtracting or multiplying whole numbers gives int
+func binaryKind(op ast.BinaryOperator, left, right types.ValueKind) types.ValueKind {
+	switch op {
+	case ast.OpGreaterThan, ast.OpLessThan, ast.OpGreaterThanOrEqual, ast.OpLessThanOrEqual,
+		ast.OpEqual, ast.OpNotEqual, ast.OpAnd, ast.OpOr:
+		return types.KindBool
+	case ast.OpPlus, ast.OpMinus, ast.OpMultiply:
+		if left.Whole() && right.Whole() {
+			return types.KindInt
+		}
+	}
+	return types.KindFloat
+}
+
+// numberKind returns int for whole number literals and float otherwise
+func numberKind(v float64) types.ValueKind {
+	if v == math.Trunc(v) && !math.IsInf(v, 0) {
+		return types.KindInt
+	}
+	return types.KindFloat
+}
+
+// withKind returns value with the given kind, copying it when the kind changes so that values
+// shared with variables or arguments are never modified
+func withKind(value *Value, kind types.ValueKind) *Value {
+	if value.Kind == kind {
+		return value
+	}
+	kinded := *value
+	kinded.Kind = kind
+	return &kinded
+}
@@ -0,0 +1,97 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/DTrader-store/formula-go/errors"
+	"github.com/DTrader-store/formula-go/parser/ast"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// periodFrame holds market data resampled to a longer period
+type periodFrame struct {
+	data  []*types.MarketData // resampled bars
+	index []int               // for each base bar, the resampled bar it belongs to
+}
+
+// periodFrame returns the base data resampled to period, computing it once per interpreter
+func (interp *Interpreter) periodFrame(period types.Period) (*periodFrame, error) {
+	if frame, ok := interp.frames[period]; ok {
+		return frame, nil
+	}
+
+	data, index, err := types.Resample(interp.bars(), period)
+	if err != nil {
+		return nil, errors.NewRuntimeError(err.Error())
+	}
+	frame := &periodFrame{data: data, index: index}
+	interp.frames[period] = frame
+	return frame, nil
+}
+
+// evaluatePeriodExpression evaluates expression#PERIOD on resampled bars and aligns the result back
+// onto the base bars. Scalar variables (e.g. formula parameters) remain visible; series variables
+// computed on the base period do not, since they cannot be resampled.
+func (interp *Interpreter) evaluatePeriodExpression(expr *ast.PeriodExpression) (*Value, error) {
+	period := types.Period(expr.Period)
+	frame, err := interp.periodFrame(period)
+	if err != nil {
+		return nil, err
+	}
+
+	child := NewInterpreter(frame.data)
+	child.functions = interp.functions
+	child.repository = interp.repository
+	child.refs = interp.refs
+	child.period = period
+	child.symbol = interp.symbol
+	child.others = interp.others.forBars()
+	child.hideForming = interp.hideForming
+	child.initMarketDataVariables()
+	for name, value := range interp.variables {
+		if _, builtin := child.variables[name]; !builtin && !value.IsArray {
+			child.variables[name] = value
+		}
+	}
+
+	value, err := child.evaluateExpression(expr.Expr)
+	if err != nil {
+		return nil, err
+	}
+	if !value.IsArray {
+		return value, nil
+	}
+	if len(value.Array) != len(frame.data) {
+		return nil, errors.NewRuntimeError(fmt.Sprintf("%s expression returned %d values for %d bars", period, len(value.Array), len(frame.data)))
+	}
+	return withKind(NewArrayValue(alignToBase(value.Array, frame.index, !interp.hideForming)), value.Kind), nil
+}
+
+// SetIncludeFormingBar sets whether expression#PERIOD shows the period bar still forming at the end of
+// the data. It is included by default: the last base bar sees that bar's value so far, as TDX shows it,
+// and the value changes as more bars of the period arrive. Excluded, the last base bar sees the last
+// completed period instead, so appending bars of the same period leaves earlier values unchanged.
+func (interp *Interpreter) SetIncludeFormingBar(include bool) {
+	interp.hideForming = !include
+}
+
+// alignToBase maps values computed on resampled bars back onto the base bars without look-ahead.
+// A resampled bar's value is only used from the base bar that completes it (its last base bar);
+// earlier base bars see the previous completed value. The last bar of the data, whose period may
+// still be forming, sees that period's value when forming is set.
+func alignToBase(values []float64, index []int, forming bool) []float64 {
+	result := make([]float64, len(index))
+	for i, k := range index {
+		completed := forming && i == len(index)-1 || i < len(index)-1 && index[i+1] != k
+		switch {
+		case completed:
+			result[i] = values[k]
+		case k > 0:
+			result[i] = values[k-1]
+		default:
+			result[i] = math.NaN()
+		}
+	}
+	return result
+}
@@ -0,0 +1,133 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DTrader-store/formula-go/errors"
+	"github.com/DTrader-store/formula-go/parser/ast"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// DataProvider loads the market data of other symbols, e.g. the benchmark index behind INDEXC.
+// Bars must carry times and be in ascending order; bars outside [from, to] are ignored.
+type DataProvider interface {
+	LoadMarketData(code string, from, to time.Time) ([]*types.MarketData, error)
+}
+
+// indexFields maps the INDEX* variables to the benchmark field they read
+var indexFields = map[string]string{
+	"INDEXO": "OPEN",
+	"INDEXC": "CLOSE",
+	"INDEXH": "HIGH",
+	"INDEXL": "LOW",
+	"INDEXV": "VOLUME",
+	"INDEXA": "AMOUNT",
+}
+
+// otherSymbols resolves other symbols' data aligned to the interpreter's bars
+type otherSymbols struct {
+	provider  DataProvider
+	benchmark string                   // code read by INDEXC, INDEXO, ...
+	fill      types.FillPolicy         // how bars missing on the other symbol are filled
+	aligned   map[string]*types.Series // aligned series by upper-cased code
+}
+
+// SetDataProvider sets the provider for other symbols' data, the benchmark code used by INDEXC and the
+// other INDEX* variables, and how bars missing on the other symbol (e.g. suspended days) are filled
+func (interp *Interpreter) SetDataProvider(provider DataProvider, benchmark string, fill types.FillPolicy) {
+	interp.others = &otherSymbols{
+		provider:  provider,
+		benchmark: benchmark,
+		fill:      fill,
+		aligned:   make(map[string]*types.Series),
+	}
+}
+
+// evaluateSymbolReference evaluates "CODE$FIELD"
+func (interp *Interpreter) evaluateSymbolReference(ref *ast.SymbolReference) (*Value, error) {
+	return interp.symbolField(ref.Symbol, ref.Field)
+}
+
+// symbolField returns a field of another symbol, aligned to the interpreter's bars
+func (interp *Interpreter) symbolField(code, field string) (*Value, error) {
+	if interp.others == nil || interp.others.provider == nil {
+		return nil, errors.NewRuntimeError(fmt.Sprintf("no data provider for %s$%s", code, field))
+	}
+	if interp.series.Len() == 0 {
+		return NewArrayValue([]float64{}), nil
+	}
+	if !interp.series.HasTime() {
+		return nil, errors.NewRuntimeError(fmt.Sprintf("%s$%s requires bar times to align symbols", code, field))
+	}
+
+	series, err := interp.alignedSeries(code)
+	if err != nil {
+		return nil, err
+	}
+
+	name := variableKey(field)
+	if target, ok := variableAliases[name]; ok {
+		name = target
+	}
+	switch name {
+	case "OPEN":
+		return NewArrayValue(series.Open), nil
+	case "CLOSE":
+		return NewArrayValue(series.Close), nil
+	case "HIGH":
+		return NewArrayValue(series.High), nil
+	case "LOW":
+		return NewArrayValue(series.Low), nil
+	case "VOLUME":
+		return NewArrayValue(series.Volume), nil
+	case "AMOUNT":
+		return NewArrayValue(series.Amount), nil
+	}
+	return nil, errors.NewRuntimeError(fmt.Sprintf("unknown field %s in %s$%s", field, code, field))
+}
+
+// alignedSeries loads a symbol once per interpreter and aligns it to the interpreter's bar times
+func (interp *Interpreter) alignedSeries(code string) (*types.Series, error) {
+	key := strings.ToUpper(code)
+	if series, ok := interp.others.aligned[key]; ok {
+		return series, nil
+	}
+
+	times := interp.series.Time
+	data, err := interp.others.provider.LoadMarketData(code, lookbackStart(times), types.AlignEnd(times))
+	if err != nil {
+		return nil, errors.NewRuntimeError(fmt.Sprintf("cannot load %s: %v", code, err))
+	}
+	series, err := types.Align(times, data, interp.others.fill)
+	if err != nil {
+		return nil, errors.NewRuntimeError(fmt.Sprintf("cannot align %s: %v", code, err))
+	}
+	interp.others.aligned[key] = series
+	return series, nil
+}
+
+// minLookback reaches back over weekends and the longest exchange holidays
+const minLookback = 14 * 24 * time.Hour
+
+// lookbackStart returns the time to load another symbol from so that its bar before times[0] is
+// included, which forward filling needs when the symbol has no bar on the first time. It goes back
+// twice the first gap between times, and at least minLookback.
+func lookbackStart(times []time.Time) time.Time {
+	lookback := minLookback
+	if len(times) > 1 {
+		lookback = max(lookback, 2*times[1].Sub(times[0]))
+	}
+	return times[0].Add(-lookback)
+}
+
+// forBars returns a copy of o with an empty cache, for an interpreter running on different bars
+func (o *otherSymbols) forBars() *otherSymbols {
+	if o == nil {
+		return nil
+	}
+	copied := *o
+	copied.aligned = make(map[string]*types.Series)
+	return &copied
+}
@@ -2,6 +2,7 @@ package interpreter
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/DTrader-store/formula-go/errors"
@@ -13,62 +14,108 @@ type Function func(args []*Value, marketData []*types.MarketData) (*Value, error
 
 // FunctionRegistry manages built-in functions
 type FunctionRegistry struct {
-	functions map[string]Function
+	functions map[string]*Descriptor // by name and alias
 }
 
 // NewFunctionRegistry creates a new function registry
 func NewFunctionRegistry() *FunctionRegistry {
 	reg := &FunctionRegistry{
-		functions: make(map[string]Function),
+		functions: make(map[string]*Descriptor),
 	}
 	reg.registerBuiltinFunctions()
 	return reg
 }
 
-// Register registers a function
+// Register registers a function taking any number of number or series arguments.
+// Use RegisterDescriptor to have calls checked and the function documented.
 func (r *FunctionRegistry) Register(name string, fn Function) {
-	r.functions[strings.ToUpper(name)] = fn
+	r.RegisterDescriptor(&Descriptor{
+		Name:     name,
+		Params:   []Param{{Name: "args", Kind: ArgAny, Optional: true}},
+		Variadic: true,
+		Fn:       fn,
+	})
+}
+
+// RegisterDescriptor registers a function under its name and aliases, replacing any function
+// registered under one of them. Calls are checked against the parameters before Fn is called.
+func (r *FunctionRegistry) RegisterDescriptor(desc *Descriptor) {
+	registered := *desc
+	registered.Name = strings.ToUpper(desc.Name)
+	registered.Aliases = make([]string, len(desc.Aliases))
+	for i, alias := range desc.Aliases {
+		registered.Aliases[i] = strings.ToUpper(alias)
+	}
+	registered.Params = append([]Param(nil), desc.Params...)
+	r.functions[registered.Name] = &registered
+	for _, alias := range registered.Aliases {
+		r.functions[alias] = &registered
+	}
+}
+
+// Has reports whether a function is registered under name
+func (r *FunctionRegistry) Has(name string) bool {
+	_, exists := r.functions[strings.ToUpper(name)]
+	return exists
+}
+
+// Lookup returns the descriptor of the function registered under name or alias
+func (r *FunctionRegistry) Lookup(name string) (*Descriptor, bool) {
+	desc, ok := r.functions[strings.ToUpper(name)]
+	return desc, ok
+}
+
+// Descriptors returns the registered functions sorted by name, each once
+func (r *FunctionRegistry) Descriptors() []*Descriptor {
+	descriptors := make([]*Descriptor, 0, len(r.functions))
+	for name, desc := range r.functions {
+		if name == desc.Name {
+			descriptors = append(descriptors, desc)
+		}
+	}
+	sort.Slice(descriptors, func(i, j int) bool { return descriptors[i].Name < descriptors[j].Name })
+	return descriptors
 }
 
 // Call calls a registered function
 func (r *FunctionRegistry) Call(name string, args []*Value, marketData []*types.MarketData) (*Value, error) {
-	fn, exists := r.functions[strings.ToUpper(name)]
+	return r.call(name, args, func() []*types.MarketData { return marketData }, nil)
+}
+
+// call checks the arguments and calls a registered function, building the market data rows only
+// for functions that use them
+func (r *FunctionRegistry) call(name string, args []*Value, bars func() []*types.MarketData, info *types.SymbolInfo) (*Value, error) {
+	desc, exists := r.Lookup(name)
 	if !exists {
 		return nil, errors.NewRuntimeError(fmt.Sprintf("undefined function: %s", name))
 	}
-	return fn(args, marketData)
+	if err := desc.check(args); err != nil {
+		return nil, err
+	}
+	args = desc.withDefaults(args)
+
+	var value *Value
+	var err error
+	switch {
+	case desc.symbol != nil:
+		value, err = desc.symbol(info, args)
+	case desc.seriesOnly:
+		value, err = desc.Fn(args, nil)
+	default:
+		value, err = desc.Fn(args, bars())
+	}
+	if rule := desc.resultKind(); err == nil && rule != nil {
+		value = withKind(value, rule(args))
+	}
+	return value, err
 }
 
-// registerBuiltinFunctions registers all built-in functions
+// registerBuiltinFunctions registers all built-in functions. Their implementations ignore the
+// market data argument, so calling them on columnar data does not require building rows.
 func (r *FunctionRegistry) registerBuiltinFunctions() {
-	// Mathematical functions
-	r.Register("MA", fnMA)
-	r.Register("EMA", fnEMA)
-	r.Register("SUM", fnSUM)
-	r.Register("MAX", fnMAX)
-	r.Register("MIN", fnMIN)
-	r.Register("ABS", fnABS)
-	r.Register("SQRT", fnSQRT)
-
-	// Reference functions
-	r.Register("REF", fnREF)
-	r.Register("HHV", fnHHV)
-	r.Register("LLV", fnLLV)
-
-	// Conditional functions
-	r.Register("IF", fnIF)
-	r.Register("CROSS", fnCROSS)
-
-	// Phase 4: Additional functions
-	r.Register("STD", fnSTD)
-	r.Register("VAR", fnVAR)
-	r.Register("SMA", fnSMA)
-	r.Register("WMA", fnWMA)
-	r.Register("COUNT", fnCOUNT)
-	r.Register("EVERY", fnEVERY)
-	r.Register("EXIST", fnEXIST)
-	r.Register("BARSLAST", fnBARSLAST)
-	r.Register("AVEDEV", fnAVEDEV)
-	r.Register("FILTER", fnFILTER)
-	r.Register("BETWEEN", fnBETWEEN)
+	for _, desc := range builtinDescriptors {
+		builtin := *desc
+		builtin.seriesOnly = true
+		r.RegisterDescriptor(&builtin)
+	}
 }
@@ -0,0 +1,184 @@
+package interpreter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DTrader-store/formula-go/errors"
+	"github.com/DTrader-store/formula-go/parser/ast"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// FormulaParam is a formula parameter with its default value
+type FormulaParam struct {
+	Name    string
+	Default float64
+}
+
+// FormulaDefinition is a compiled formula that other formulas can reference
+type FormulaDefinition struct {
+	Name    string         // Formula name
+	Program *ast.Program   // Compiled program
+	Params  []FormulaParam // Parameters in positional order
+	Outputs []string       // Declared outputs; the first is used when a reference names none. Empty means all user variables.
+}
+
+// FormulaRepository resolves formulas referenced by name, e.g. "MACD.DIF" or a user formula call
+type FormulaRepository interface {
+	LookupFormula(name string) (*FormulaDefinition, bool)
+}
+
+// RepositoryChain searches several repositories in order
+type RepositoryChain []FormulaRepository
+
+// LookupFormula returns the first definition found in the chain
+func (c RepositoryChain) LookupFormula(name string) (*FormulaDefinition, bool) {
+	for _, repo := range c {
+		if def, ok := repo.LookupFormula(name); ok {
+			return def, true
+		}
+	}
+	return nil, false
+}
+
+// referenceContext is shared by an interpreter and the interpreters it spawns for referenced formulas
+type referenceContext struct {
+	cache map[string]*types.FormulaResult // memoized results keyed by period, formula and parameters
+	stack []string                        // formulas currently being evaluated, for cycle detection
+}
+
+func newReferenceContext() *referenceContext {
+	return &referenceContext{cache: make(map[string]*types.FormulaResult)}
+}
+
+// SetRepository sets the repository used to resolve formula references
+func (interp *Interpreter) SetRepository(repo FormulaRepository) {
+	interp.repository = repo
+}
+
+// evaluateFormulaReference evaluates "FORMULA.OUTPUT"(args)
+func (interp *Interpreter) evaluateFormulaReference(ref *ast.FormulaReference) (*Value, error) {
+	args := make([]*Value, len(ref.Arguments))
+	for i, arg := range ref.Arguments {
+		val, err := interp.evaluateExpression(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+	}
+	return interp.callFormula(ref.Formula, ref.Output, args)
+}
+
+// callFormula runs a referenced formula (once per parameter set) and selects one of its outputs
+func (interp *Interpreter) callFormula(name, output string, args []*Value) (*Value, error) {
+	if interp.repository == nil {
+		return nil, errors.NewRuntimeError(fmt.Sprintf("undefined formula: %s (no formula repository)", name))
+	}
+	return interp.runReference(interp.repository, name, output, args)
+}
+
+// runReference runs a formula of repo (once per parameter set) and selects one of its outputs
+func (interp *Interpreter) runReference(repo FormulaRepository, name, output string, args []*Value) (*Value, error) {
+	def, ok := repo.LookupFormula(name)
+	if !ok {
+		return nil, errors.NewRuntimeError(fmt.Sprintf("undefined formula: %s", name))
+	}
+	if len(args) > len(def.Params) {
+		return nil, errors.NewRuntimeError(fmt.Sprintf("%s accepts at most %d parameters, got %d", def.Name, len(def.Params), len(args)))
+	}
+
+	params := make([]float64, len(def.Params))
+	for i, param := range def.Params {
+		params[i] = param.Default
+		if i < len(args) {
+			if args[i].IsArray || args[i].IsText {
+				return nil, errors.NewRuntimeError(fmt.Sprintf("%s parameter %s must be a number", def.Name, param.Name))
+			}
+			params[i] = args[i].Single
+		}
+	}
+
+	result, err := interp.runFormula(def, params)
+	if err != nil {
+		return nil, err
+	}
+	return selectOutput(def, result, output)
+}
+
+// runFormula executes a definition in a child interpreter, reusing a memoized result when available
+func (interp *Interpreter) runFormula(def *FormulaDefinition, params []float64) (*types.FormulaResult, error) {
+	key := formulaCacheKey(interp.period, def.Name, params)
+	if result, ok := interp.refs.cache[key]; ok {
+		return result, nil
+	}
+
+	upper := strings.ToUpper(def.Name)
+	for i, active := range interp.refs.stack {
+		if active == upper {
+			cycle := append(append([]string{}, interp.refs.stack[i:]...), upper)
+			return nil, errors.NewRuntimeError(fmt.Sprintf("circular formula reference: %s", strings.Join(cycle, " -> ")))
+		}
+	}
+
+	interp.refs.stack = append(interp.refs.stack, upper)
+	defer func() { interp.refs.stack = interp.refs.stack[:len(interp.refs.stack)-1] }()
+
+	child := NewSeriesInterpreter(interp.series)
+	child.marketData = interp.marketData
+	child.functions = interp.functions
+	child.repository = interp.repository
+	child.refs = interp.refs
+	child.period = interp.period
+	child.symbol = interp.symbol
+	child.others = interp.others
+	child.hideForming = interp.hideForming
+	for i, param := range def.Params {
+		child.SetVariable(param.Name, NewSingleValue(params[i]))
+	}
+
+	result, err := child.Execute(def.Program)
+	if err != nil {
+		return nil, err
+	}
+	interp.refs.cache[key] = result
+	return result, nil
+}
+
+// formulaCacheKey identifies a formula run by data period, name and parameter values
+func formulaCacheKey(period types.Period, name string, params []float64) string {
+	var b strings.Builder
+	b.WriteString(string(period))
+	b.WriteByte('|')
+	b.WriteString(strings.ToUpper(name))
+	for _, p := range params {
+		b.WriteByte(',')
+		b.WriteString(strconv.FormatFloat(p, 'g', -1, 64))
+	}
+	return b.String()
+}
+
+// selectOutput picks the named output (or the default one) from a referenced formula's result
+func selectOutput(def *FormulaDefinition, result *types.FormulaResult, output string) (*Value, error) {
+	if output == "" {
+		if len(def.Outputs) > 0 {
+			output = def.Outputs[0]
+		} else if len(result.Outputs) > 0 {
+			return withKind(NewArrayValue(result.Outputs[0].Data), result.Outputs[0].Kind), nil
+		} else {
+			return nil, errors.NewRuntimeError(fmt.Sprintf("%s has no outputs", def.Name))
+		}
+	}
+
+	for _, line := range result.Outputs {
+		if strings.EqualFold(line.Name, output) {
+			return withKind(NewArrayValue(line.Data), line.Kind), nil
+		}
+	}
+	for name, value := range result.Variables {
+		if strings.EqualFold(name, output) {
+			return NewSingleValue(value), nil
+		}
+	}
+	return nil, errors.NewRuntimeError(fmt.Sprintf("%s has no output %s", def.Name, output))
+}
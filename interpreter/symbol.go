@@ -0,0 +1,49 @@
+package interpreter
+
+import (
+	"math"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// SymbolInfoProvider supplies fundamental and snapshot data for a symbol
+type SymbolInfoProvider interface {
+	LookupSymbol(code string) (*types.SymbolInfo, bool)
+}
+
+// symbolFunction is a built-in that reads the symbol info of the data being evaluated
+type symbolFunction func(info *types.SymbolInfo, args []*Value) (*Value, error)
+
+// fnFINANCE implements FINANCE(n): fundamental field n of the symbol, NaN when unknown
+func fnFINANCE(info *types.SymbolInfo, args []*Value) (*Value, error) {
+	n := int(args[0].Single)
+	if info == nil {
+		return NewSingleValue(math.NaN()), nil
+	}
+	return NewSingleValue(lookupField(info.Finance, n)), nil
+}
+
+// fnDYNAINFO implements DYNAINFO(n): real-time snapshot field n of the symbol, NaN when unknown
+func fnDYNAINFO(info *types.SymbolInfo, args []*Value) (*Value, error) {
+	n := int(args[0].Single)
+	if info == nil {
+		return NewSingleValue(math.NaN()), nil
+	}
+	return NewSingleValue(lookupField(info.DynaInfo, n)), nil
+}
+
+// fnINBLOCK implements INBLOCK('block'): 1 when the symbol belongs to the block or industry, otherwise 0
+func fnINBLOCK(info *types.SymbolInfo, args []*Value) (*Value, error) {
+	if info != nil && info.InBlock(args[0].Text) {
+		return withKind(NewSingleValue(1), types.KindBool), nil
+	}
+	return withKind(NewSingleValue(0), types.KindBool), nil
+}
+
+// lookupField returns fields[n], or NaN when the field is missing
+func lookupField(fields map[int]float64, n int) float64 {
+	if v, ok := fields[n]; ok {
+		return v
+	}
+	return math.NaN()
+}
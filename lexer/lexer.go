@@ -10,11 +10,11 @@ import (
 
 // Lexer performs lexical analysis on formula source code
 type Lexer struct {
-	input   string   // the source code to analyze
-	pos     int      // current position in input
-	line    int      // current line number (1-indexed)
-	column  int      // current column number (1-indexed)
-	tokens  []*Token // collected tokens
+	input  string   // the source code to analyze
+	pos    int      // current position in input
+	line   int      // current line number (1-indexed)
+	column int      // current column number (1-indexed)
+	tokens []*Token // collected tokens
 }
 
 // NewLexer creates a new Lexer instance
@@ -63,6 +63,14 @@ func (l *Lexer) scanToken() error {
 		return nil
 	}
 
+	// Handle comments: {TDX style}, possibly spanning lines, and // to the end of the line
+	if ch == '{' {
+		return l.scanBlockComment()
+	}
+	if ch == '/' && l.peekNext() == '/' {
+		return l.scanLineComment()
+	}
+
 	// Handle numbers
 	if unicode.IsDigit(ch) {
 		return l.scanNumber()
@@ -73,10 +81,90 @@ func (l *Lexer) scanToken() error {
 		return l.scanIdentifier()
 	}
 
+	// Handle quoted formula references such as "MACD.DIF"
+	if ch == '"' {
+		return l.scanString('"', STRING)
+	}
+
+	// Handle text literals such as '银行'
+	if ch == '\'' {
+		return l.scanString('\'', TEXT)
+	}
+
 	// Handle operators and punctuation
 	return l.scanOperator()
 }
 
+// scanBlockComment scans a comment enclosed in braces; the token value includes the braces
+func (l *Lexer) scanBlockComment() error {
+	start := l.pos
+	startLine, startCol := l.line, l.column
+
+	for !l.isAtEnd() && l.peek() != '}' {
+		if l.advance() == '\n' {
+			l.line++
+			l.column = 1
+		}
+	}
+	if l.isAtEnd() {
+		return errors.NewLexerError("unterminated comment", startLine, startCol, "{")
+	}
+	l.advance() // consume '}'
+
+	l.tokens = append(l.tokens, &Token{
+		Type:   COMMENT,
+		Value:  l.input[start:l.pos],
+		Line:   startLine,
+		Column: startCol,
+	})
+	return nil
+}
+
+// scanLineComment scans a // comment up to the end of the line; the token value includes the slashes
+func (l *Lexer) scanLineComment() error {
+	start := l.pos
+	startCol := l.column
+
+	for !l.isAtEnd() && l.peek() != '\n' {
+		l.advance()
+	}
+
+	l.tokens = append(l.tokens, &Token{
+		Type:   COMMENT,
+		Value:  strings.TrimRight(l.input[start:l.pos], " \t\r"),
+		Line:   l.line,
+		Column: startCol,
+	})
+	return nil
+}
+
+// scanString scans a string enclosed in quote; the token value excludes the quotes
+func (l *Lexer) scanString(quote rune, tokenType TokenType) error {
+	startCol := l.column
+	l.advance() // consume opening quote
+	start := l.pos
+
+	for !l.isAtEnd() && l.peek() != quote {
+		if l.peek() == '\n' {
+			return errors.NewLexerError("unterminated string", l.line, startCol, string(quote))
+		}
+		l.advance()
+	}
+	if l.isAtEnd() {
+		return errors.NewLexerError("unterminated string", l.line, startCol, string(quote))
+	}
+
+	value := l.input[start:l.pos]
+	l.advance() // consume closing quote
+	l.tokens = append(l.tokens, &Token{
+		Type:   tokenType,
+		Value:  value,
+		Line:   l.line,
+		Column: startCol,
+	})
+	return nil
+}
+
 // scanNumber scans a number token
 func (l *Lexer) scanNumber() error {
 	start := l.pos
@@ -179,6 +267,10 @@ func (l *Lexer) scanOperator() error {
 		l.addToken(COMMA, ",")
 	case ';':
 		l.addToken(SEMICOLON, ";")
+	case '.':
+		l.addToken(DOT, ".")
+	case '#':
+		l.addToken(HASH, "#")
 	case ':':
 		// Check for := (assignment)
 		if !l.isAtEnd() && l.peek() == '=' {
@@ -237,6 +329,14 @@ func (l *Lexer) peek() rune {
 	return rune(l.input[l.pos])
 }
 
+// peekNext returns the character after the current one without advancing
+func (l *Lexer) peekNext() rune {
+	if l.pos+1 >= len(l.input) {
+		return 0
+	}
+	return rune(l.input[l.pos+1])
+}
+
 // advance returns the current character and moves to the next
 func (l *Lexer) advance() rune {
 	if l.isAtEnd() {
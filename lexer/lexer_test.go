@@ -30,6 +30,11 @@ func TestLexerSimpleTokens(t *testing.T) {
 			input:  "x := 10",
 			expect: []TokenType{IDENTIFIER, ASSIGN, NUMBER, EOF},
 		},
+		{
+			name:   "period suffix",
+			input:  "CLOSE#WEEK",
+			expect: []TokenType{IDENTIFIER, HASH, IDENTIFIER, EOF},
+		},
 	}
 
 	for _, tt := range tests {
@@ -207,7 +212,7 @@ func TestLexerErrors(t *testing.T) {
 		input string
 	}{
 		{"unexpected character", "@"},
-		{"invalid operator", "#"},
+		{"invalid operator", "?"},
 	}
 
 	for _, tt := range tests {
@@ -240,3 +245,88 @@ func TestLexerWhitespace(t *testing.T) {
 		}
 	}
 }
+
+func TestLexerFormulaReferences(t *testing.T) {
+	tokens, err := NewLexer(`"MACD.DIF"(12, 26, 9) + KDJ.K`).Tokenize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []TokenType{STRING, LPAREN, NUMBER, COMMA, NUMBER, COMMA, NUMBER, RPAREN, PLUS, IDENTIFIER, DOT, IDENTIFIER, EOF}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for i, expectedType := range expected {
+		if tokens[i].Type != expectedType {
+			t.Errorf("Token %d: expected type %s, got %s", i, expectedType, tokens[i].Type)
+		}
+	}
+
+	if tokens[0].Value != "MACD.DIF" {
+		t.Errorf("Expected string value 'MACD.DIF', got %q", tokens[0].Value)
+	}
+	if tokens[9].Column != 25 {
+		t.Errorf("Expected KDJ at column 25, got %d", tokens[9].Column)
+	}
+
+	if _, err := NewLexer(`"MACD.DIF`).Tokenize(); err == nil {
+		t.Error("Expected error for unterminated string")
+	}
+}
+
+func TestLexerTextLiterals(t *testing.T) {
+	tokens, err := NewLexer(`INBLOCK('银行')`).Tokenize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []TokenType{IDENTIFIER, LPAREN, TEXT, RPAREN, EOF}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for i, expectedType := range expected {
+		if tokens[i].Type != expectedType {
+			t.Errorf("Token %d: expected type %s, got %s", i, expectedType, tokens[i].Type)
+		}
+	}
+	if tokens[2].Value != "银行" {
+		t.Errorf("Expected text value '银行', got %q", tokens[2].Value)
+	}
+
+	if _, err := NewLexer(`INBLOCK('银行)`).Tokenize(); err == nil {
+		t.Error("Expected error for unterminated text")
+	}
+}
+
+func TestLexerComments(t *testing.T) {
+	input := "{均线\n参数} MA5 := MA(C, 5); // 五日\nX := 1 / 2"
+	tokens, err := NewLexer(input).Tokenize()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []TokenType{COMMENT, IDENTIFIER, ASSIGN, IDENTIFIER, LPAREN, IDENTIFIER, COMMA, NUMBER, RPAREN, SEMICOLON,
+		COMMENT, NEWLINE, IDENTIFIER, ASSIGN, NUMBER, DIVIDE, NUMBER, EOF}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for i, expectedType := range expected {
+		if tokens[i].Type != expectedType {
+			t.Errorf("Token %d: expected type %s, got %s", i, expectedType, tokens[i].Type)
+		}
+	}
+
+	if tokens[0].Value != "{均线\n参数}" || tokens[0].Line != 1 {
+		t.Errorf("Unexpected block comment %v", tokens[0])
+	}
+	if tokens[1].Line != 2 || tokens[10].Value != "// 五日" {
+		t.Errorf("Unexpected tokens after comment: %v, %v", tokens[1], tokens[10])
+	}
+	if tokens[12].Line != 3 {
+		t.Errorf("Expected X on line 3, got line %d", tokens[12].Line)
+	}
+
+	if _, err := NewLexer("{unterminated").Tokenize(); err == nil {
+		t.Error("Expected error for unterminated comment")
+	}
+}
@@ -9,6 +9,8 @@ const (
 	// Literals
 	NUMBER     TokenType = "NUMBER"
 	IDENTIFIER TokenType = "IDENTIFIER"
+	STRING     TokenType = "STRING" // double-quoted formula reference
+	TEXT       TokenType = "TEXT"   // single-quoted text literal
 
 	// Operators
 	PLUS     TokenType = "PLUS"
@@ -34,6 +36,8 @@ const (
 	COMMA     TokenType = "COMMA"
 	SEMICOLON TokenType = "SEMICOLON"
 	COLON     TokenType = "COLON"
+	DOT       TokenType = "DOT"
+	HASH      TokenType = "HASH"
 
 	// Assignment
 	ASSIGN TokenType = "ASSIGN"
@@ -49,5 +53,6 @@ const (
 
 	// Special
 	NEWLINE TokenType = "NEWLINE"
+	COMMENT TokenType = "COMMENT" // {...} or // ... to the end of the line; dropped by the parser
 	EOF     TokenType = "EOF"
 )
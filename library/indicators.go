@@ -0,0 +1,320 @@
+package library
+
+import "github.com/DTrader-store/formula-go/types"
+
+// line returns a solid line style with the given color
+func line(color string) *types.LineStyle {
+	return &types.LineStyle{Color: color, LineWidth: 1, LineStyle: "solid"}
+}
+
+// stick returns a style drawn as red/green vertical sticks from the zero axis
+func stick() *types.LineStyle {
+	return &types.LineStyle{LineWidth: 1, LineStyle: "colorstick"}
+}
+
+// standardIndicators returns the built-in TDX system indicators.
+// Sources follow the TDX definitions, adapted to the functions this interpreter provides.
+func standardIndicators() []*Indicator {
+	return []*Indicator{
+		{
+			Name:     "MA",
+			Title:    "均线",
+			Category: "均线型",
+			Params: []Param{
+				{Name: "M1", Default: 5, Min: 1, Max: 250},
+				{Name: "M2", Default: 10, Min: 1, Max: 250},
+				{Name: "M3", Default: 20, Min: 1, Max: 250},
+				{Name: "M4", Default: 60, Min: 1, Max: 250},
+			},
+			Outputs: []Output{
+				{Name: "MA1", Style: line("white")},
+				{Name: "MA2", Style: line("yellow")},
+				{Name: "MA3", Style: line("magenta")},
+				{Name: "MA4", Style: line("green")},
+			},
+			Source: `
+				MA1 := MA(CLOSE, M1)
+				MA2 := MA(CLOSE, M2)
+				MA3 := MA(CLOSE, M3)
+				MA4 := MA(CLOSE, M4)
+			`,
+		},
+		{
+			Name:     "MACD",
+			Title:    "平滑异同平均线",
+			Category: "趋势型",
+			Params: []Param{
+				{Name: "SHORT", Default: 12, Min: 2, Max: 200},
+				{Name: "LONG", Default: 26, Min: 2, Max: 200},
+				{Name: "MID", Default: 9, Min: 2, Max: 200},
+			},
+			Outputs: []Output{
+				{Name: "DIF", Style: line("white")},
+				{Name: "DEA", Style: line("yellow")},
+				{Name: "MACD", Style: stick()},
+			},
+			Source: `
+				DIF := EMA(CLOSE, SHORT) - EMA(CLOSE, LONG)
+				DEA := EMA(DIF, MID)
+				MACD := (DIF - DEA) * 2
+			`,
+		},
+		{
+			Name:     "KDJ",
+			Title:    "随机指标",
+			Category: "超买超卖型",
+			Params: []Param{
+				{Name: "N", Default: 9, Min: 1, Max: 100},
+				{Name: "M1", Default: 3, Min: 2, Max: 100},
+				{Name: "M2", Default: 3, Min: 2, Max: 100},
+			},
+			Outputs: []Output{
+				{Name: "K", Style: line("white")},
+				{Name: "D", Style: line("yellow")},
+				{Name: "J", Style: line("magenta")},
+			},
+			Source: `
+				RSV := (CLOSE - LLV(LOW, N)) / (HHV(HIGH, N) - LLV(LOW, N)) * 100
+				K := SMA(RSV, M1, 1)
+				D := SMA(K, M2, 1)
+				J := 3 * K - 2 * D
+			`,
+		},
+		{
+			Name:     "RSI",
+			Title:    "相对强弱指标",
+			Category: "超买超卖型",
+			Params: []Param{
+				{Name: "N1", Default: 6, Min: 2, Max: 120},
+				{Name: "N2", Default: 12, Min: 2, Max: 250},
+				{Name: "N3", Default: 24, Min: 2, Max: 500},
+			},
+			Outputs: []Output{
+				{Name: "RSI1", Style: line("white")},
+				{Name: "RSI2", Style: line("yellow")},
+				{Name: "RSI3", Style: line("magenta")},
+			},
+			Source: `
+				LC := REF(CLOSE, 1)
+				RSI1 := SMA(MAX(CLOSE - LC, 0), N1, 1) / SMA(ABS(CLOSE - LC), N1, 1) * 100
+				RSI2 := SMA(MAX(CLOSE - LC, 0), N2, 1) / SMA(ABS(CLOSE - LC), N2, 1) * 100
+				RSI3 := SMA(MAX(CLOSE - LC, 0), N3, 1) / SMA(ABS(CLOSE - LC), N3, 1) * 100
+			`,
+		},
+		{
+			Name:     "BOLL",
+			Title:    "布林带",
+			Category: "路径型",
+			Params: []Param{
+				{Name: "M", Default: 20, Min: 2, Max: 120},
+			},
+			Outputs: []Output{
+				{Name: "BOLL", Style: line("white")},
+				{Name: "UB", Style: line("yellow")},
+				{Name: "LB", Style: line("magenta")},
+			},
+			Source: `
+				BOLL := MA(CLOSE, M)
+				UB := BOLL + 2 * STD(CLOSE, M)
+				LB := BOLL - 2 * STD(CLOSE, M)
+			`,
+		},
+		{
+			Name:     "DMI",
+			Title:    "趋向指标",
+			Category: "趋势型",
+			Params: []Param{
+				{Name: "N", Default: 14, Min: 2, Max: 90},
+				{Name: "MM", Default: 6, Min: 1, Max: 60},
+			},
+			Outputs: []Output{
+				{Name: "PDI", Style: line("white")},
+				{Name: "MDI", Style: line("yellow")},
+				{Name: "ADX", Style: line("magenta")},
+				{Name: "ADXR", Style: line("green")},
+			},
+			Source: `
+				MTR := SUM(MAX(MAX(HIGH - LOW, ABS(HIGH - REF(CLOSE, 1))), ABS(REF(CLOSE, 1) - LOW)), N)
+				HD := HIGH - REF(HIGH, 1)
+				LD := REF(LOW, 1) - LOW
+				DMP := SUM(IF(HD > 0 AND HD > LD, HD, 0), N)
+				DMM := SUM(IF(LD > 0 AND LD > HD, LD, 0), N)
+				PDI := DMP * 100 / MTR
+				MDI := DMM * 100 / MTR
+				ADX := MA(ABS(MDI - PDI) / (MDI + PDI) * 100, MM)
+				ADXR := (ADX + REF(ADX, MM)) / 2
+			`,
+		},
+		{
+			Name:     "ATR",
+			Title:    "真实波幅",
+			Category: "超买超卖型",
+			Params: []Param{
+				{Name: "N", Default: 14, Min: 1, Max: 100},
+			},
+			Outputs: []Output{
+				{Name: "MTR", Style: line("white")},
+				{Name: "ATR", Style: line("yellow")},
+			},
+			Source: `
+				MTR := MAX(MAX(HIGH - LOW, ABS(REF(CLOSE, 1) - HIGH)), ABS(REF(CLOSE, 1) - LOW))
+				ATR := MA(MTR, N)
+			`,
+		},
+		{
+			Name:     "CCI",
+			Title:    "商品路径指标",
+			Category: "超买超卖型",
+			Params: []Param{
+				{Name: "N", Default: 14, Min: 2, Max: 100},
+			},
+			Outputs: []Output{
+				{Name: "CCI", Style: line("white")},
+			},
+			Source: `
+				TYP := (HIGH + LOW + CLOSE) / 3
+				CCI := (TYP - MA(TYP, N)) / (0.015 * AVEDEV(TYP, N))
+			`,
+		},
+		{
+			Name:     "OBV",
+			Title:    "累积能量线",
+			Category: "能量型",
+			Params: []Param{
+				{Name: "M", Default: 30, Min: 2, Max: 100},
+			},
+			Outputs: []Output{
+				{Name: "OBV", Style: line("white")},
+				{Name: "MAOBV", Style: line("yellow")},
+			},
+			Source: `
+				VA := IF(CLOSE > REF(CLOSE, 1), VOLUME, -VOLUME)
+				OBV := SUM(IF(CLOSE = REF(CLOSE, 1), 0, VA), 0)
+				MAOBV := MA(OBV, M)
+			`,
+		},
+		{
+			Name:     "WR",
+			Title:    "威廉指标",
+			Category: "超买超卖型",
+			Params: []Param{
+				{Name: "N", Default: 10, Min: 2, Max: 100},
+				{Name: "N1", Default: 6, Min: 2, Max: 100},
+			},
+			Outputs: []Output{
+				{Name: "WR1", Style: line("white")},
+				{Name: "WR2", Style: line("yellow")},
+			},
+			Source: `
+				WR1 := 100 * (HHV(HIGH, N) - CLOSE) / (HHV(HIGH, N) - LLV(LOW, N))
+				WR2 := 100 * (HHV(HIGH, N1) - CLOSE) / (HHV(HIGH, N1) - LLV(LOW, N1))
+			`,
+		},
+		{
+			Name:     "BIAS",
+			Title:    "乖离率",
+			Category: "超买超卖型",
+			Params: []Param{
+				{Name: "N1", Default: 6, Min: 2, Max: 250},
+				{Name: "N2", Default: 12, Min: 2, Max: 250},
+				{Name: "N3", Default: 24, Min: 2, Max: 250},
+			},
+			Outputs: []Output{
+				{Name: "BIAS1", Style: line("white")},
+				{Name: "BIAS2", Style: line("yellow")},
+				{Name: "BIAS3", Style: line("magenta")},
+			},
+			Source: `
+				BIAS1 := (CLOSE - MA(CLOSE, N1)) / MA(CLOSE, N1) * 100
+				BIAS2 := (CLOSE - MA(CLOSE, N2)) / MA(CLOSE, N2) * 100
+				BIAS3 := (CLOSE - MA(CLOSE, N3)) / MA(CLOSE, N3) * 100
+			`,
+		},
+		{
+			Name:     "ROC",
+			Title:    "变动率指标",
+			Category: "超买超卖型",
+			Params: []Param{
+				{Name: "N", Default: 12, Min: 2, Max: 120},
+				{Name: "M", Default: 6, Min: 2, Max: 60},
+			},
+			Outputs: []Output{
+				{Name: "ROC", Style: line("white")},
+				{Name: "MAROC", Style: line("yellow")},
+			},
+			Source: `
+				ROC := 100 * (CLOSE - REF(CLOSE, N)) / REF(CLOSE, N)
+				MAROC := MA(ROC, M)
+			`,
+		},
+		{
+			Name:     "MTM",
+			Title:    "动量线",
+			Category: "超买超卖型",
+			Params: []Param{
+				{Name: "N", Default: 12, Min: 2, Max: 120},
+				{Name: "M", Default: 6, Min: 2, Max: 60},
+			},
+			Outputs: []Output{
+				{Name: "MTM", Style: line("white")},
+				{Name: "MTMMA", Style: line("yellow")},
+			},
+			Source: `
+				MTM := CLOSE - REF(CLOSE, N)
+				MTMMA := MA(MTM, M)
+			`,
+		},
+		{
+			Name:     "PSY",
+			Title:    "心理线",
+			Category: "能量型",
+			Params: []Param{
+				{Name: "N", Default: 12, Min: 2, Max: 100},
+				{Name: "M", Default: 6, Min: 2, Max: 100},
+			},
+			Outputs: []Output{
+				{Name: "PSY", Style: line("white")},
+				{Name: "PSYMA", Style: line("yellow")},
+			},
+			Source: `
+				PSY := COUNT(CLOSE > REF(CLOSE, 1), N) / N * 100
+				PSYMA := MA(PSY, M)
+			`,
+		},
+		{
+			Name:     "BBI",
+			Title:    "多空均线",
+			Category: "均线型",
+			Params: []Param{
+				{Name: "M1", Default: 3, Min: 1, Max: 100},
+				{Name: "M2", Default: 6, Min: 1, Max: 100},
+				{Name: "M3", Default: 12, Min: 1, Max: 100},
+				{Name: "M4", Default: 24, Min: 1, Max: 100},
+			},
+			Outputs: []Output{
+				{Name: "BBI", Style: line("white")},
+			},
+			Source: `
+				BBI := (MA(CLOSE, M1) + MA(CLOSE, M2) + MA(CLOSE, M3) + MA(CLOSE, M4)) / 4
+			`,
+		},
+		{
+			Name:     "TRIX",
+			Title:    "三重指数平滑平均线",
+			Category: "趋势型",
+			Params: []Param{
+				{Name: "N", Default: 12, Min: 3, Max: 100},
+				{Name: "M", Default: 9, Min: 1, Max: 100},
+			},
+			Outputs: []Output{
+				{Name: "TRIX", Style: line("white")},
+				{Name: "MATRIX", Style: line("yellow")},
+			},
+			Source: `
+				MTR := EMA(EMA(EMA(CLOSE, N), N), N)
+				TRIX := (MTR - REF(MTR, 1)) / REF(MTR, 1) * 100
+				MATRIX := MA(TRIX, M)
+			`,
+		},
+	}
+}
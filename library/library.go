@@ -0,0 +1,287 @@
+// Package library provides a catalog of standard TDX system indicators
+// (MACD, KDJ, RSI, BOLL, ...) as reusable formula source with metadata
+package library
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DTrader-store/formula-go/engine"
+	"github.com/DTrader-store/formula-go/errors"
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/parser/ast"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// Param describes a tunable indicator parameter
+type Param struct {
+	Name    string  // Parameter name as used in the formula source
+	Default float64 // Default value
+	Min     float64 // Smallest accepted value
+	Max     float64 // Largest accepted value
+}
+
+// Output describes a line drawn by the indicator
+type Output struct {
+	Name  string           // Variable name in the formula source
+	Style *types.LineStyle // Default drawing style
+}
+
+// Indicator is a system formula with its metadata
+type Indicator struct {
+	Name     string   // Indicator name, e.g. "MACD"
+	Title    string   // Human readable name, e.g. "平滑异同平均线"
+	Category string   // Category, e.g. "趋势型"
+	Params   []Param  // Parameters in positional order
+	Outputs  []Output // Output lines in display order
+	Source   string   // Formula source
+}
+
+// Defaults returns the default parameter values
+func (ind *Indicator) Defaults() map[string]float64 {
+	params := make(map[string]float64, len(ind.Params))
+	for _, p := range ind.Params {
+		params[p.Name] = p.Default
+	}
+	return params
+}
+
+// resolveParams merges overrides into the defaults and checks their ranges
+func (ind *Indicator) resolveParams(overrides map[string]float64) (map[string]float64, error) {
+	params := ind.Defaults()
+	for name, value := range overrides {
+		param, ok := ind.param(name)
+		if !ok {
+			return nil, errors.NewFormulaError(fmt.Sprintf("%s has no parameter %s", ind.Name, name))
+		}
+		if value < param.Min || value > param.Max {
+			return nil, errors.NewFormulaError(fmt.Sprintf("%s parameter %s must be between %g and %g, got %g",
+				ind.Name, param.Name, param.Min, param.Max, value))
+		}
+		params[param.Name] = value
+	}
+	return params, nil
+}
+
+// param looks up a parameter by name (case-insensitive)
+func (ind *Indicator) param(name string) (Param, bool) {
+	for _, p := range ind.Params {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return Param{}, false
+}
+
+// Library is a named collection of indicators
+type Library struct {
+	mu         sync.RWMutex
+	indicators map[string]*Indicator
+	programs   map[string]*ast.Program
+	engine     *engine.FormulaEngine
+}
+
+// New creates an empty library
+func New() *Library {
+	return &Library{
+		indicators: make(map[string]*Indicator),
+		programs:   make(map[string]*ast.Program),
+		engine:     engine.NewFormulaEngine(),
+	}
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultLibrary *Library
+)
+
+// Default returns the shared library holding the standard system indicators
+func Default() *Library {
+	defaultOnce.Do(func() {
+		defaultLibrary = New()
+		for _, ind := range standardIndicators() {
+			if err := defaultLibrary.Add(ind); err != nil {
+				panic(fmt.Sprintf("library: invalid system indicator %s: %v", ind.Name, err))
+			}
+		}
+	})
+	return defaultLibrary
+}
+
+// Add compiles an indicator and adds it to the library, replacing any indicator with the same name
+func (l *Library) Add(ind *Indicator) error {
+	program, err := l.engine.Compile(ind.Source)
+	if err != nil {
+		return err
+	}
+
+	name := strings.ToUpper(ind.Name)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.indicators[name] = ind
+	l.programs[name] = program
+	return nil
+}
+
+// Get returns an indicator by name (case-insensitive)
+func (l *Library) Get(name string) (*Indicator, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	ind, ok := l.indicators[strings.ToUpper(name)]
+	return ind, ok
+}
+
+// Names returns the names of all indicators in alphabetical order
+func (l *Library) Names() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	names := make([]string, 0, len(l.indicators))
+	for name := range l.indicators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run executes an indicator on market data. Parameters not present in params keep their defaults.
+// Only the indicator's declared outputs are returned, in display order and with their styles.
+func (l *Library) Run(name string, marketData []*types.MarketData, params map[string]float64) (*types.FormulaResult, error) {
+	l.mu.RLock()
+	ind, ok := l.indicators[strings.ToUpper(name)]
+	program := l.programs[strings.ToUpper(name)]
+	l.mu.RUnlock()
+	if !ok {
+		return nil, errors.NewFormulaError(fmt.Sprintf("unknown indicator: %s", name))
+	}
+
+	values, err := ind.resolveParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := l.engine.ExecuteWithParams(program, marketData, values)
+	if err != nil {
+		return nil, err
+	}
+
+	result := types.NewFormulaResult()
+	result.Times = raw.Times
+	for _, out := range ind.Outputs {
+		line := findOutput(raw, out.Name)
+		if line == nil {
+			return nil, errors.NewFormulaError(fmt.Sprintf("%s does not produce output %s", ind.Name, out.Name))
+		}
+		result.AddOutput(out.Name, line.Data, out.Style)
+		result.Outputs[len(result.Outputs)-1].Kind = line.Kind
+	}
+	return result, nil
+}
+
+// LookupFormula implements interpreter.FormulaRepository, so formulas can reference
+// indicator outputs as "MACD.DEA" or "KDJ.J"(18, 3, 3)
+func (l *Library) LookupFormula(name string) (*interpreter.FormulaDefinition, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	ind, ok := l.indicators[strings.ToUpper(name)]
+	if !ok {
+		return nil, false
+	}
+
+	def := &interpreter.FormulaDefinition{
+		Name:    ind.Name,
+		Program: l.programs[strings.ToUpper(name)],
+		Params:  make([]interpreter.FormulaParam, len(ind.Params)),
+		Outputs: make([]string, len(ind.Outputs)),
+	}
+	for i, p := range ind.Params {
+		def.Params[i] = interpreter.FormulaParam{Name: p.Name, Default: p.Default}
+	}
+	for i, out := range ind.Outputs {
+		def.Outputs[i] = out.Name
+	}
+	return def, true
+}
+
+// RegisterFunctions registers every indicator as a function in registry, so other formulas can call
+// e.g. MACD(12, 26, 9). Arguments override parameters in declared order and the first output is returned.
+// Inside a formula the call shares the memoized run of the "MACD.DIF"(12, 26, 9) reference form.
+// Functions already in registry win, so the MA indicator does not hide the MA(X, N) built-in.
+func (l *Library) RegisterFunctions(registry *interpreter.FunctionRegistry) {
+	for _, name := range l.Names() {
+		if registry.Has(name) {
+			continue
+		}
+		ind, _ := l.Get(name)
+		registry.RegisterDescriptor(l.descriptor(ind))
+	}
+}
+
+// descriptor describes an indicator as a function whose parameters default to the indicator's
+func (l *Library) descriptor(ind *Indicator) *interpreter.Descriptor {
+	params := make([]interpreter.Param, len(ind.Params))
+	warmUp := 0 // the longest default period approximates the bars needed
+	for i, p := range ind.Params {
+		value := p.Default
+		params[i] = interpreter.Param{Name: p.Name, Kind: interpreter.ArgNumber, Default: &value}
+		warmUp = max(warmUp, int(p.Default))
+	}
+	return &interpreter.Descriptor{
+		Name:        ind.Name,
+		Params:      params,
+		Category:    ind.Category,
+		Description: interpreter.Localized{Zh: ind.Title},
+		WarmUp:      warmUp,
+		Fn:          l.indicatorFunction(ind.Name),
+		Repository:  l,
+	}
+}
+
+// indicatorFunction adapts an indicator to the interpreter's Function signature
+func (l *Library) indicatorFunction(name string) interpreter.Function {
+	return func(args []*interpreter.Value, marketData []*types.MarketData) (*interpreter.Value, error) {
+		ind, ok := l.Get(name)
+		if !ok {
+			return nil, errors.NewRuntimeError(fmt.Sprintf("unknown indicator: %s", name))
+		}
+		params := make(map[string]float64, len(args))
+		for i, arg := range args {
+			if arg.IsArray || arg.IsText {
+				return nil, errors.NewRuntimeError(fmt.Sprintf("%s parameter %s must be a number", name, ind.Params[i].Name))
+			}
+			params[ind.Params[i].Name] = arg.Single
+		}
+
+		result, err := l.Run(name, marketData, params)
+		if err != nil {
+			return nil, err
+		}
+		return interpreter.NewArrayValue(result.Outputs[0].Data), nil
+	}
+}
+
+// findOutput returns the output line with the given name, or nil
+func findOutput(result *types.FormulaResult, name string) *types.OutputLine {
+	for _, line := range result.Outputs {
+		if line.Name == name {
+			return line
+		}
+	}
+	return nil
+}
+
+// Get returns an indicator from the default library
+func Get(name string) (*Indicator, bool) {
+	return Default().Get(name)
+}
+
+// Names returns the names of all indicators in the default library
+func Names() []string {
+	return Default().Names()
+}
+
+// Run executes an indicator from the default library
+func Run(name string, marketData []*types.MarketData, params map[string]float64) (*types.FormulaResult, error) {
+	return Default().Run(name, marketData, params)
+}
@@ -0,0 +1,343 @@
+package library
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DTrader-store/formula-go/engine"
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// fixedData returns a deterministic 120-bar dataset used by the golden tests
+func fixedData() []*types.MarketData {
+	data := make([]*types.MarketData, 120)
+	price := 10.0
+	for i := range data {
+		x := float64(i)
+		open := price
+		close := open * (1 + 0.02*math.Sin(x*0.7) + 0.006*math.Cos(x*1.3))
+		high := math.Max(open, close) * (1 + 0.004*(2+math.Sin(x*2.1)))
+		low := math.Min(open, close) * (1 - 0.004*(2+math.Cos(x*1.7)))
+		volume := 10000 + 3000*math.Sin(x*0.9) + 50*x
+		data[i] = types.NewMarketData(open, close, high, low, volume, volume*close)
+		price = close
+	}
+	return data
+}
+
+func TestStandardIndicatorsGolden(t *testing.T) {
+	data := fixedData()
+
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			result, err := Run(name, data, nil)
+			if err != nil {
+				t.Fatalf("Run(%s) failed: %v", name, err)
+			}
+
+			ind, _ := Get(name)
+			if len(result.Outputs) != len(ind.Outputs) {
+				t.Fatalf("Expected %d outputs, got %d", len(ind.Outputs), len(result.Outputs))
+			}
+
+			path := filepath.Join("testdata", name+".golden")
+			if *update {
+				writeGolden(t, path, result)
+				return
+			}
+
+			golden := readGolden(t, path)
+			for _, out := range result.Outputs {
+				expected, ok := golden[out.Name]
+				if !ok {
+					t.Fatalf("Golden file has no output %s", out.Name)
+				}
+				if len(expected) != len(out.Data) {
+					t.Fatalf("%s: expected %d values, got %d", out.Name, len(expected), len(out.Data))
+				}
+				for i := range expected {
+					if !closeEnough(expected[i], out.Data[i]) {
+						t.Errorf("%s[%d]: expected %v, got %v", out.Name, i, expected[i], out.Data[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMACDMatchesHandWrittenFormula(t *testing.T) {
+	data := fixedData()
+
+	result, err := Run("MACD", data, map[string]float64{"SHORT": 5, "LONG": 10, "MID": 4})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	reference, err := engine.NewFormulaEngine().Run(`
+		DIF := EMA(CLOSE, 5) - EMA(CLOSE, 10)
+		DEA := EMA(DIF, 4)
+	`, data)
+	if err != nil {
+		t.Fatalf("Reference formula failed: %v", err)
+	}
+
+	for i := range data {
+		if !closeEnough(reference.Outputs[1].Data[i], result.Outputs[1].Data[i]) {
+			t.Errorf("DEA[%d]: expected %v, got %v", i, reference.Outputs[1].Data[i], result.Outputs[1].Data[i])
+		}
+	}
+}
+
+func TestRunRejectsInvalidParams(t *testing.T) {
+	data := fixedData()
+
+	if _, err := Run("MACD", data, map[string]float64{"FAST": 5}); err == nil {
+		t.Error("Expected error for unknown parameter")
+	}
+	if _, err := Run("MACD", data, map[string]float64{"SHORT": 0}); err == nil {
+		t.Error("Expected error for out-of-range parameter")
+	}
+	if _, err := Run("NOPE", data, nil); err == nil {
+		t.Error("Expected error for unknown indicator")
+	}
+}
+
+func TestIndicatorsSurviveFlatBars(t *testing.T) {
+	// Ten suspended bars with open = high = low = close and no volume make HIGH - LOW and
+	// HHV - LLV zero; the division only invalidates those bars
+	data := fixedData()
+	for i := 50; i < 60; i++ {
+		price := data[49].Close
+		data[i] = types.NewMarketData(price, price, price, price, 0, 0)
+	}
+
+	for _, name := range Names() {
+		result, err := Run(name, data, nil)
+		if err != nil {
+			t.Errorf("Run(%s) failed on flat bars: %v", name, err)
+			continue
+		}
+		for _, out := range result.Outputs {
+			if last := out.Data[len(out.Data)-1]; math.IsNaN(last) || math.IsInf(last, 0) {
+				t.Errorf("%s.%s: expected a valid last value after the flat bars, got %v", name, out.Name, last)
+			}
+		}
+	}
+}
+
+func TestIndicatorsOnShortData(t *testing.T) {
+	// Fewer bars than the longest default period (MA's M4 = 60)
+	data := fixedData()[:30]
+	for _, name := range Names() {
+		if _, err := Run(name, data, nil); err != nil {
+			t.Errorf("Run(%s) failed on %d bars: %v", name, len(data), err)
+		}
+	}
+
+	result, err := Run("MA", data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ma4 := result.Outputs[3].Data; !math.IsNaN(ma4[len(ma4)-1]) {
+		t.Errorf("Expected MA4 to still be warming up, got %v", ma4[len(ma4)-1])
+	}
+	if ma1 := result.Outputs[0].Data; math.IsNaN(ma1[len(ma1)-1]) {
+		t.Error("Expected MA1 to be valid")
+	}
+}
+
+func TestRunOutputStyles(t *testing.T) {
+	result, err := Run("macd", fixedData(), nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	names := []string{"DIF", "DEA", "MACD"}
+	for i, out := range result.Outputs {
+		if out.Name != names[i] {
+			t.Errorf("Output %d: expected %s, got %s", i, names[i], out.Name)
+		}
+		if out.Style == nil {
+			t.Errorf("Output %s has no style", out.Name)
+		}
+	}
+	if result.Outputs[2].Style.LineStyle != "colorstick" {
+		t.Errorf("Expected MACD drawn as colorstick, got %s", result.Outputs[2].Style.LineStyle)
+	}
+}
+
+func TestRegisterFunctions(t *testing.T) {
+	data := fixedData()
+	e := engine.NewFormulaEngine()
+	Default().RegisterFunctions(e.Functions())
+
+	result, err := e.Run(`
+		FAST := MACD(5, 10, 4)
+		SIGNAL := FAST > 0
+	`, data)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	expected, _ := Run("MACD", data, map[string]float64{"SHORT": 5, "LONG": 10, "MID": 4})
+	for i := range data {
+		if !closeEnough(expected.Outputs[0].Data[i], result.Outputs[0].Data[i]) {
+			t.Errorf("FAST[%d]: expected %v, got %v", i, expected.Outputs[0].Data[i], result.Outputs[0].Data[i])
+		}
+	}
+
+	if _, err := e.Run("X := MACD(1, 2, 3, 4)", data); err == nil {
+		t.Error("Expected error for too many arguments")
+	}
+	if _, err := e.Run("X := MA(CLOSE, 3)", data); err != nil {
+		t.Errorf("Expected the MA built-in to stay callable, got %v", err)
+	}
+}
+
+func TestIndicatorFunctionRejectsSeries(t *testing.T) {
+	data := fixedData()
+	closes := make([]float64, len(data))
+	_, err := Default().indicatorFunction("MACD")([]*interpreter.Value{interpreter.NewArrayValue(closes)}, data)
+	if err == nil || !strings.Contains(err.Error(), "SHORT must be a number") {
+		t.Errorf("Expected an error for a series parameter, got %v", err)
+	}
+
+	e := engine.NewFormulaEngine()
+	Default().RegisterFunctions(e.Functions())
+	if _, err := e.Run("X := MACD(C, 26, 9)", data); err == nil {
+		t.Error("Expected an error for MACD(C, 26, 9)")
+	}
+}
+
+func TestRegisterFunctionsSharesReferenceCache(t *testing.T) {
+	runs := 0
+	e := engine.NewFormulaEngine()
+	e.Functions().Register("TICK", func(args []*interpreter.Value, _ []*types.MarketData) (*interpreter.Value, error) {
+		runs++
+		return args[0], nil
+	})
+
+	l := New()
+	if err := l.Add(&Indicator{
+		Name:    "CNT",
+		Params:  []Param{{Name: "N", Default: 1, Min: 1, Max: 10}},
+		Outputs: []Output{{Name: "X"}},
+		Source:  "X : TICK(CLOSE) * N;",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	l.RegisterFunctions(e.Functions())
+	e.SetRepository(l)
+
+	result, err := e.Run(`
+		A := CNT(2)
+		B := "CNT.X"(2)
+		C2 := CNT()
+	`, fixedData())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("Expected one run per parameter set, got %d runs", runs)
+	}
+	if result.Outputs[0].Data[5] != result.Outputs[1].Data[5] {
+		t.Errorf("Expected CNT(2) and \"CNT.X\"(2) to agree, got %v and %v", result.Outputs[0].Data[5], result.Outputs[1].Data[5])
+	}
+}
+
+func TestLibraryAsRepository(t *testing.T) {
+	data := fixedData()
+	e := engine.NewFormulaEngine()
+	e.SetRepository(Default())
+
+	result, err := e.Run(`
+		K := "KDJ.K"(18, 3, 3)
+		FIRST := RSI()
+	`, data)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	kdj, _ := Run("KDJ", data, map[string]float64{"N": 18})
+	rsi, _ := Run("RSI", data, nil)
+	for i := range data {
+		if !closeEnough(kdj.Outputs[0].Data[i], result.Outputs[0].Data[i]) {
+			t.Errorf("K[%d]: expected %v, got %v", i, kdj.Outputs[0].Data[i], result.Outputs[0].Data[i])
+		}
+		// Without an output name the first declared output (RSI1) is used, not the LC intermediate
+		if !closeEnough(rsi.Outputs[0].Data[i], result.Outputs[1].Data[i]) {
+			t.Errorf("RSI1[%d]: expected %v, got %v", i, rsi.Outputs[0].Data[i], result.Outputs[1].Data[i])
+		}
+	}
+}
+
+// closeEnough compares two values with a relative tolerance, treating NaN as equal to NaN
+func closeEnough(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(a))
+}
+
+// writeGolden stores one output per line: NAME v0 v1 ...
+func writeGolden(t *testing.T, path string, result *types.FormulaResult) {
+	t.Helper()
+	var b strings.Builder
+	for _, out := range result.Outputs {
+		b.WriteString(out.Name)
+		for _, v := range out.Data {
+			b.WriteByte(' ')
+			b.WriteString(strconv.FormatFloat(v, 'g', 12, 64))
+		}
+		b.WriteByte('\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readGolden loads a file written by writeGolden
+func readGolden(t *testing.T, path string) map[string][]float64 {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Cannot open golden file (run with -update to create it): %v", err)
+	}
+	defer f.Close()
+
+	golden := make(map[string][]float64)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		values := make([]float64, len(fields)-1)
+		for i, field := range fields[1:] {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				t.Fatal(fmt.Errorf("%s: %w", path, err))
+			}
+			values[i] = v
+		}
+		golden[fields[0]] = values
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return golden
+}
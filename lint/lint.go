@@ -0,0 +1,445 @@
+// Package lint checks formulas for mistakes that otherwise only surface when they run: undefined
+// and unused variables, unknown functions, wrong argument counts and kinds, shadowed built-ins,
+// calls to functions registered as reading later bars, and constant conditions.
+package lint
+
+import (
+	stderrors "errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/DTrader-store/formula-go/errors"
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/lexer"
+	"github.com/DTrader-store/formula-go/parser"
+	"github.com/DTrader-store/formula-go/parser/ast"
+)
+
+// Severity tells how serious a diagnostic is
+type Severity int
+
+const (
+	// Error marks code that fails when the formula runs
+	Error Severity = iota
+	// Warning marks code that runs but is probably a mistake
+	Warning
+)
+
+// String returns "error" or "warning"
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Diagnostic codes
+const (
+	CodeSyntax            = "syntax"             // the formula does not parse
+	CodeUndefined         = "undefined"          // a variable is never defined
+	CodeUseBeforeDefine   = "use-before-define"  // a variable is used before its definition
+	CodeUnused            = "unused"             // an intermediate variable is never used
+	CodeUnknownFunction   = "unknown-function"   // no function or formula has the name
+	CodeArity             = "arity"              // wrong number of arguments
+	CodeArgumentKind      = "argument-kind"      // e.g. a series where a single number is needed
+	CodeShadow            = "shadow"             // a variable hides a built-in function or variable
+	CodeFuture            = "future"             // a function reads later bars
+	CodeConstantCondition = "constant-condition" // a condition that is always true or always false
+	CodeLongPeriod        = "long-period"        // a period longer than typical data
+)
+
+// Diagnostic is a problem found in a formula
+type Diagnostic struct {
+	Span     ast.Span
+	Severity Severity
+	Code     string
+	Message  string
+}
+
+// String formats the diagnostic as line:column: severity: message
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", d.Span.Start.Line, d.Span.Start.Column, d.Severity, d.Message)
+}
+
+// DefaultTypicalBars is the number of bars a period may cover before it is reported as long
+const DefaultTypicalBars = 250
+
+// Linter checks formulas against a function registry
+type Linter struct {
+	functions   *interpreter.FunctionRegistry
+	repository  interpreter.FormulaRepository
+	defined     map[string]bool
+	TypicalBars int // Periods longer than this are reported; DefaultTypicalBars unless changed
+}
+
+// New creates a linter checking calls against functions; nil uses the built-in functions
+func New(functions *interpreter.FunctionRegistry) *Linter {
+	if functions == nil {
+		functions = interpreter.NewFunctionRegistry()
+	}
+	return &Linter{
+		functions:   functions,
+		defined:     make(map[string]bool),
+		TypicalBars: DefaultTypicalBars,
+	}
+}
+
+// SetRepository sets the repository that resolves references to other formulas. Without one,
+// formula references are not checked.
+func (l *Linter) SetRepository(repo interpreter.FormulaRepository) {
+	l.repository = repo
+}
+
+// Define declares variables supplied when the formula runs, such as formula parameters, bound
+// columns or extra market data fields
+func (l *Linter) Define(names ...string) {
+	for _, name := range names {
+		l.defined[strings.ToUpper(name)] = true
+	}
+}
+
+// Source checks formula source. A formula that does not parse gives a single syntax diagnostic.
+func Source(src string) []Diagnostic {
+	return New(nil).Source(src)
+}
+
+// Source checks formula source. A formula that does not parse gives a single syntax diagnostic.
+func (l *Linter) Source(src string) []Diagnostic {
+	tokens, err := lexer.NewLexer(src).Tokenize()
+	if err != nil {
+		return []Diagnostic{syntaxError(err)}
+	}
+	p := parser.NewParser(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		return []Diagnostic{syntaxError(err)}
+	}
+	return l.Program(program, p.Spans())
+}
+
+// syntaxError converts a lexer or parser error to a diagnostic at the error position
+func syntaxError(err error) Diagnostic {
+	var pos ast.Position
+	var lexErr *errors.LexerError
+	var parseErr *errors.ParserError
+	switch {
+	case stderrors.As(err, &lexErr):
+		pos = ast.Position{Line: lexErr.Line, Column: lexErr.Column}
+	case stderrors.As(err, &parseErr):
+		pos = ast.Position{Line: parseErr.Line, Column: parseErr.Column}
+	}
+	end := ast.Position{Line: pos.Line, Column: pos.Column + 1}
+	return Diagnostic{Span: ast.Span{Start: pos, End: end}, Severity: Error, Code: CodeSyntax, Message: err.Error()}
+}
+
+// Program checks a parsed program; spans are the source ranges from the parser, see parser.Spans.
+// Diagnostics are sorted by position.
+func (l *Linter) Program(program *ast.Program, spans map[ast.Node]ast.Span) []Diagnostic {
+	c := &checker{
+		Linter:    l,
+		spans:     spans,
+		variables: make(map[string]*variable),
+		assigned:  make(map[string]bool),
+	}
+	c.program(program)
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		a, b := c.diagnostics[i].Span.Start, c.diagnostics[j].Span.Start
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return c.diagnostics
+}
+
+// shape is what an expression evaluates to, as far as the checker can tell
+type shape int
+
+const (
+	unknown shape = iota // depends on run time inputs
+	scalar               // a single number
+	series               // one value per bar
+	text                 // a text literal
+)
+
+// info describes an expression
+type info struct {
+	shape    shape
+	constant bool    // whether the value is known before running
+	value    float64 // the value when constant
+}
+
+// variable is a variable defined by the formula
+type variable struct {
+	info
+	name   string
+	span   ast.Span // where the name is defined
+	output bool     // declared with NAME : expr
+	used   bool
+}
+
+// checker holds the state of checking one program
+type checker struct {
+	*Linter
+	spans       map[ast.Node]ast.Span
+	variables   map[string]*variable // defined so far, by upper-cased name
+	defs        []*variable          // every definition in order
+	assigned    map[string]bool      // names assigned anywhere in the program
+	diagnostics []Diagnostic
+}
+
+// report adds a diagnostic for node
+func (c *checker) report(node ast.Node, severity Severity, code, format string, args ...any) {
+	c.reportAt(c.spans[node], severity, code, format, args...)
+}
+
+// reportAt adds a diagnostic for a source range
+func (c *checker) reportAt(span ast.Span, severity Severity, code, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{Span: span, Severity: severity, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// program checks the statements in order
+func (c *checker) program(program *ast.Program) {
+	hasOutputs := false
+	for _, stmt := range program.Body {
+		switch s := stmt.(type) {
+		case *ast.VariableDeclaration:
+			c.assigned[strings.ToUpper(s.Name)] = true
+		case *ast.OutputDeclaration:
+			c.assigned[strings.ToUpper(s.Name)] = true
+			hasOutputs = true
+		}
+	}
+
+	for _, stmt := range program.Body {
+		switch s := stmt.(type) {
+		case *ast.VariableDeclaration:
+			c.define(s, s.Name, c.expression(s.Value), false)
+		case *ast.OutputDeclaration:
+			c.define(s, s.Name, c.expression(s.Value), true)
+		case *ast.ExpressionStatement:
+			result := c.expression(s.Expr)
+			if ident, ok := s.Expr.(*ast.Identifier); ok {
+				c.variables[strings.ToUpper(ident.Name)] = &variable{info: result, name: ident.Name, output: true}
+			}
+		}
+	}
+
+	// Without NAME : expr outputs every variable is an output, so none is unused
+	if !hasOutputs {
+		return
+	}
+	for _, v := range c.defs {
+		if !v.used && !v.output {
+			c.reportAt(v.span, Warning, CodeUnused, "%s is never used", v.name)
+		}
+	}
+}
+
+// define records a variable defined by stmt, warning when it hides a built-in
+func (c *checker) define(stmt ast.Statement, name string, result info, output bool) {
+	start := c.spans[stmt].Start
+	span := ast.Span{Start: start, End: ast.Position{Line: start.Line, Column: start.Column + len(name)}}
+	if _, isFunction := c.functions.Lookup(name); isFunction {
+		c.reportAt(span, Warning, CodeShadow, "%s hides the function %s", name, strings.ToUpper(name))
+	} else if _, isBuiltin := interpreter.BuiltinVariable(name); isBuiltin {
+		c.reportAt(span, Warning, CodeShadow, "%s hides the built-in variable %s", name, strings.ToUpper(name))
+	}
+
+	v := &variable{info: result, name: name, span: span, output: output}
+	if previous, ok := c.variables[strings.ToUpper(name)]; ok {
+		v.used = previous.used // a redefinition such as X := X + 1 uses the earlier definition
+	}
+	c.variables[strings.ToUpper(name)] = v
+	c.defs = append(c.defs, v)
+}
+
+// expression checks an expression and returns what it evaluates to
+func (c *checker) expression(expr ast.Expression) info {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		return info{shape: scalar, constant: true, value: e.Value}
+	case *ast.StringLiteral:
+		return info{shape: text}
+	case *ast.Identifier:
+		return c.identifier(e)
+	case *ast.UnaryExpression:
+		operand := c.expression(e.Operand)
+		if operand.shape == text {
+			c.report(e, Error, CodeArgumentKind, "operator %s cannot be applied to text", e.Operator)
+			return info{}
+		}
+		operand.value = -operand.value
+		return operand
+	case *ast.BinaryExpression:
+		return c.binary(e)
+	case *ast.FunctionCall:
+		return c.call(e)
+	case *ast.FormulaReference:
+		if c.repository != nil {
+			if _, ok := c.repository.LookupFormula(e.Formula); !ok {
+				c.report(e, Error, CodeUnknownFunction, "unknown formula %s", e.Formula)
+			}
+		}
+		for _, arg := range e.Arguments {
+			c.expression(arg)
+		}
+		return info{shape: series}
+	case *ast.PeriodExpression:
+		inner := c.expression(e.Expr)
+		return info{shape: inner.shape}
+	case *ast.SymbolReference:
+		return info{shape: series}
+	case *ast.ConditionalExpression:
+		c.expression(e.Test)
+		c.expression(e.Consequent)
+		c.expression(e.Alternate)
+	}
+	return info{}
+}
+
+// identifier resolves a variable the way the interpreter does: the formula's own variables first,
+// then built-in variables, then variables supplied at run time
+func (c *checker) identifier(id *ast.Identifier) info {
+	key := strings.ToUpper(id.Name)
+	if v, ok := c.variables[key]; ok {
+		v.used = true
+		return v.info
+	}
+	if isSeries, ok := interpreter.BuiltinVariable(id.Name); ok {
+		if isSeries {
+			return info{shape: series}
+		}
+		return info{shape: scalar}
+	}
+	if c.defined[key] {
+		return info{}
+	}
+	if c.assigned[key] {
+		c.report(id, Error, CodeUseBeforeDefine, "%s is used before it is defined", id.Name)
+	} else {
+		c.report(id, Error, CodeUndefined, "undefined variable %s", id.Name)
+	}
+	return info{}
+}
+
+// binary checks a binary expression, folding constant operands
+func (c *checker) binary(e *ast.BinaryExpression) info {
+	left, right := c.expression(e.Left), c.expression(e.Right)
+	if left.shape == text || right.shape == text {
+		c.report(e, Error, CodeArgumentKind, "operator %s cannot be applied to text", e.Operator)
+		return info{}
+	}
+
+	result := info{shape: combine(left.shape, right.shape)}
+	if left.constant && right.constant {
+		result.constant = true
+		result.value = fold(e.Operator, left.value, right.value)
+	}
+	return result
+}
+
+// combine returns the shape of a value computed from values of shapes a and b
+func combine(a, b shape) shape {
+	switch {
+	case a == series || b == series:
+		return series
+	case a == unknown || b == unknown:
+		return unknown
+	}
+	return scalar
+}
+
+// fold computes a binary operation on constants as the interpreter does
+func fold(op ast.BinaryOperator, a, b float64) float64 {
+	truth := func(v bool) float64 {
+		if v {
+			return 1
+		}
+		return 0
+	}
+	switch op {
+	case ast.OpPlus:
+		return a + b
+	case ast.OpMinus:
+		return a - b
+	case ast.OpMultiply:
+		return a * b
+	case ast.OpDivide:
+		return a / b
+	case ast.OpEqual:
+		return truth(a == b)
+	case ast.OpNotEqual:
+		return truth(a != b)
+	case ast.OpLessThan:
+		return truth(a < b)
+	case ast.OpLessThanOrEqual:
+		return truth(a <= b)
+	case ast.OpGreaterThan:
+		return truth(a > b)
+	case ast.OpGreaterThanOrEqual:
+		return truth(a >= b)
+	case ast.OpAnd:
+		return truth(a != 0 && b != 0)
+	case ast.OpOr:
+		return truth(a != 0 || b != 0)
+	}
+	return math.NaN()
+}
+
+// call checks a function call against the function's descriptor
+func (c *checker) call(call *ast.FunctionCall) info {
+	args := make([]info, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		args[i] = c.expression(arg)
+	}
+
+	desc, known := c.functions.Lookup(call.Name)
+	if !known {
+		if c.repository != nil {
+			if _, ok := c.repository.LookupFormula(call.Name); ok {
+				return info{shape: series}
+			}
+		}
+		c.report(call, Error, CodeUnknownFunction, "unknown function %s", call.Name)
+		return info{}
+	}
+
+	result := info{shape: scalar}
+	for _, arg := range args {
+		result.shape = combine(result.shape, arg.shape)
+	}
+
+	if desc.Future {
+		c.report(call, Warning, CodeFuture, "%s reads later bars, so its values change as new bars arrive", call.Name)
+	}
+	if n := len(call.Arguments); n < desc.MinArgs() || desc.MaxArgs() >= 0 && n > desc.MaxArgs() {
+		c.report(call, Error, CodeArity, "%s takes %s, got %d", call.Name, desc.Arity(), n)
+		return result
+	}
+	for i, arg := range args {
+		param, _ := desc.Param(i)
+		c.argument(call, i, param, arg)
+	}
+	if desc.Name == "IF" && args[0].constant {
+		c.report(call.Arguments[0], Warning, CodeConstantCondition, "IF condition is always %t", args[0].value != 0 && !math.IsNaN(args[0].value))
+	}
+	return result
+}
+
+// argument checks the i-th argument of a call against its parameter
+func (c *checker) argument(call *ast.FunctionCall, i int, param interpreter.Param, arg info) {
+	node := call.Arguments[i]
+	position := fmt.Sprintf("%s argument %d (%s)", call.Name, i+1, param.Name)
+	switch {
+	case param.Kind == interpreter.ArgText && arg.shape != text:
+		c.report(node, Error, CodeArgumentKind, "%s must be text, e.g. '银行'", position)
+	case param.Kind != interpreter.ArgText && arg.shape == text:
+		c.report(node, Error, CodeArgumentKind, "%s cannot be text", position)
+	case param.Kind == interpreter.ArgSeries && arg.shape == scalar:
+		c.report(node, Error, CodeArgumentKind, "%s must be a series, got a single value", position)
+	case param.Kind == interpreter.ArgNumber && arg.shape == series:
+		c.report(node, Error, CodeArgumentKind, "%s must be a single number, got a series", position)
+	case param.Name == "period" && arg.constant && arg.value > float64(c.TypicalBars):
+		c.report(node, Warning, CodeLongPeriod, "%s %g is longer than the %d bars of typical data", position, arg.value, c.TypicalBars)
+	}
+}
@@ -0,0 +1,149 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DTrader-store/formula-go/engine"
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/library"
+	"github.com/DTrader-store/formula-go/parser/ast"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// codes returns the diagnostic codes in order
+func codes(diagnostics []Diagnostic) []string {
+	result := make([]string, len(diagnostics))
+	for i, d := range diagnostics {
+		result[i] = d.Code
+	}
+	return result
+}
+
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"clean", "MA5 := MA(C, 5);\nSIGNAL := CROSS(C, MA5) AND VOL > REF(VOL, 1)", nil},
+		{"undefined", "X := MA(CLOSEE, 5)", []string{CodeUndefined}},
+		{"use before define", "X := Y + 1;\nY := C", []string{CodeUseBeforeDefine}},
+		{"unused", "T := C - O;\nUNUSED := C + O;\nOUT : T * 2", []string{CodeUnused}},
+		{"no unused without outputs", "A := C;\nB := O", nil},
+		{"unknown function", "X := MAA(C, 5)", []string{CodeUnknownFunction}},
+		{"arity", "X := MA(C);\nY := SMA(C, 5, 1, 2)", []string{CodeArity, CodeArity}},
+		{"optional argument", "X := SMA(C, 5);\nY := SMA(C, 5, 1)", nil},
+		{"series argument", "N := 5;\nX := MA(N, 5)", []string{CodeArgumentKind}},
+		{"number argument", "X := MA(C, C)", []string{CodeArgumentKind}},
+		{"text argument", "X := INBLOCK(5) + MA('银行', 5)", []string{CodeArgumentKind, CodeArgumentKind}},
+		{"text operand", "X := C + '银行'", []string{CodeArgumentKind}},
+		{"shadow function", "MA := C", []string{CodeShadow}},
+		{"shadow variable", "C := O", []string{CodeShadow}},
+		{"constant condition", "X := IFF(1 > 2, C, O);\nY := IF(C > O, C, O)", []string{CodeConstantCondition}},
+		{"long period", "X := MA(C, 1000)", []string{CodeLongPeriod}},
+		{"syntax", "X := MA(C, 5", []string{CodeSyntax}},
+		{"lexer", "X := C @ 1", []string{CodeSyntax}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := Source(tt.input)
+			got := codes(diagnostics)
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, diagnostics)
+			}
+		})
+	}
+}
+
+func TestLintPositions(t *testing.T) {
+	diagnostics := Source("A := C;\nB := MA(A, X1) + FOO(1)")
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v", diagnostics)
+	}
+
+	undefined := diagnostics[0]
+	if undefined.Code != CodeUndefined || undefined.Severity != Error {
+		t.Errorf("Unexpected diagnostic %v", undefined)
+	}
+	if undefined.Span != (ast.Span{Start: ast.Position{Line: 2, Column: 12}, End: ast.Position{Line: 2, Column: 14}}) {
+		t.Errorf("Unexpected span %+v", undefined.Span)
+	}
+	if got := undefined.String(); got != "2:12: error: undefined variable X1" {
+		t.Errorf("Unexpected text %q", got)
+	}
+
+	if unknown := diagnostics[1]; unknown.Code != CodeUnknownFunction || unknown.Span.Start.Column != 18 || unknown.Span.End.Column != 24 {
+		t.Errorf("Unexpected diagnostic %v at %+v", unknown, unknown.Span)
+	}
+}
+
+func TestLintCustomFunctions(t *testing.T) {
+	functions := interpreter.NewFunctionRegistry()
+	zig := func(args []*interpreter.Value, _ []*types.MarketData) (*interpreter.Value, error) {
+		return args[0], nil
+	}
+	functions.Register("ZIG", zig)
+	linter := New(functions)
+	if diagnostics := linter.Source("X := ZIG(C, 5, 6)"); len(diagnostics) != 0 {
+		t.Errorf("Expected no checks without a descriptor, got %v", diagnostics)
+	}
+
+	functions.RegisterDescriptor(&interpreter.Descriptor{
+		Name:    "ZIG",
+		Aliases: []string{"ZIGZAG"},
+		Params:  []interpreter.Param{{Name: "data", Kind: interpreter.ArgSeries}, {Name: "percent", Kind: interpreter.ArgNumber}},
+		Future:  true,
+		Fn:      zig,
+	})
+	if got := codes(linter.Source("X := ZIG(C, 5)")); len(got) != 1 || got[0] != CodeFuture {
+		t.Errorf("Expected a future function warning, got %v", got)
+	}
+	if got := codes(linter.Source("X := ZIGZAG(C)")); len(got) != 2 || got[1] != CodeArity {
+		t.Errorf("Expected the alias to be checked, got %v", got)
+	}
+}
+
+func TestBuiltinsDoNotReadLaterBars(t *testing.T) {
+	// The future check is for registered functions only; the README promises no built-in triggers it
+	for _, desc := range interpreter.NewFunctionRegistry().Descriptors() {
+		if desc.Future {
+			t.Errorf("Built-in %s is marked as reading later bars", desc.Name)
+		}
+	}
+}
+
+func TestLintDefinedAndRepository(t *testing.T) {
+	linter := New(nil)
+	if got := codes(linter.Source("X := MA(C, N) + PE")); len(got) != 2 {
+		t.Errorf("Expected N and PE undefined, got %v", got)
+	}
+	linter.Define("N", "pe")
+	if got := linter.Source("X := MA(C, N) + PE"); len(got) != 0 {
+		t.Errorf("Expected no diagnostics after Define, got %v", got)
+	}
+
+	repo := engine.NewMemoryRepository()
+	if err := repo.Add("MYMA", "M := MA(C, 5)"); err != nil {
+		t.Fatal(err)
+	}
+	linter.SetRepository(repo)
+	if got := codes(linter.Source("X := MYMA(5) + MYMA.M + NOPE.M")); len(got) != 1 || got[0] != CodeUnknownFunction {
+		t.Errorf("Expected only NOPE unknown, got %v", got)
+	}
+}
+
+func TestLintLibraryIsClean(t *testing.T) {
+	lib := library.Default()
+	for _, name := range lib.Names() {
+		ind, _ := lib.Get(name)
+		linter := New(nil)
+		for _, param := range ind.Params {
+			linter.Define(param.Name)
+		}
+		if diagnostics := linter.Source(ind.Source); len(diagnostics) != 0 {
+			t.Errorf("%s: unexpected diagnostics %v", name, diagnostics)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package lsp
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/DTrader-store/formula-go/lexer"
+	"github.com/DTrader-store/formula-go/parser/ast"
+)
+
+// document is an open formula file
+type document struct {
+	text   string
+	lines  []string
+	tokens []*lexer.Token // without EOF; nil when the text does not tokenize
+}
+
+// newDocument splits and tokenizes text
+func newDocument(text string) *document {
+	d := &document{text: text, lines: strings.Split(text, "\n")}
+	if tokens, err := lexer.NewLexer(text).Tokenize(); err == nil {
+		for _, tok := range tokens {
+			if tok.Type != lexer.EOF && tok.Type != lexer.NEWLINE {
+				d.tokens = append(d.tokens, tok)
+			}
+		}
+	}
+	return d
+}
+
+// position converts a one-based line and byte column to an LSP position
+func (d *document) position(pos ast.Position) Position {
+	line := pos.Line - 1
+	if line < 0 || line >= len(d.lines) {
+		return Position{Line: max(line, 0)}
+	}
+	text := d.lines[line]
+	column := min(max(pos.Column-1, 0), len(text))
+	return Position{Line: line, Character: utf16Len(text[:column])}
+}
+
+// span converts a source range to an LSP range
+func (d *document) span(span ast.Span) Range {
+	return Range{Start: d.position(span.Start), End: d.position(span.End)}
+}
+
+// source converts an LSP position to a one-based line and byte column
+func (d *document) source(pos Position) ast.Position {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return ast.Position{Line: pos.Line + 1, Column: 1}
+	}
+	text := d.lines[pos.Line]
+	units := 0
+	for i, r := range text {
+		if units >= pos.Character {
+			return ast.Position{Line: pos.Line + 1, Column: i + 1}
+		}
+		units += utf16.RuneLen(r)
+	}
+	return ast.Position{Line: pos.Line + 1, Column: len(text) + 1}
+}
+
+// end returns the position after the last character
+func (d *document) end() Position {
+	last := len(d.lines) - 1
+	return Position{Line: last, Character: utf16Len(d.lines[last])}
+}
+
+// tokenEnd returns the byte column after a single-line token
+func tokenEnd(tok *lexer.Token) int {
+	switch tok.Type {
+	case lexer.STRING, lexer.TEXT:
+		return tok.Column + len(tok.Value) + 2 // quotes
+	}
+	return tok.Column + len(tok.Value)
+}
+
+// tokenRange returns the range of a single-line token
+func (d *document) tokenRange(tok *lexer.Token) Range {
+	return d.span(ast.Span{
+		Start: ast.Position{Line: tok.Line, Column: tok.Column},
+		End:   ast.Position{Line: tok.Line, Column: tokenEnd(tok)},
+	})
+}
+
+// tokenAt returns the index of the token at pos, or of the token ending right before it, or -1
+func (d *document) tokenAt(pos ast.Position) int {
+	found := -1
+	for i, tok := range d.tokens {
+		if tok.Line != pos.Line || tok.Type == lexer.COMMENT || pos.Column < tok.Column || pos.Column > tokenEnd(tok) {
+			continue
+		}
+		if pos.Column < tokenEnd(tok) {
+			return i
+		}
+		found = i
+	}
+	return found
+}
+
+// significant returns the token at index i, skipping comments in direction step, or nil
+func (d *document) significant(i, step int) *lexer.Token {
+	for ; i >= 0 && i < len(d.tokens); i += step {
+		if d.tokens[i].Type != lexer.COMMENT {
+			return d.tokens[i]
+		}
+	}
+	return nil
+}
+
+// isDefinition reports whether the identifier at index i names a variable being defined, as in
+// NAME := expr or NAME : expr at the start of a statement
+func (d *document) isDefinition(i int) bool {
+	tok := d.tokens[i]
+	if tok.Type != lexer.IDENTIFIER {
+		return false
+	}
+	next := d.significant(i+1, 1)
+	if next == nil || next.Type != lexer.ASSIGN && next.Type != lexer.COLON {
+		return false
+	}
+	prev := d.significant(i-1, -1)
+	return prev == nil || prev.Type == lexer.SEMICOLON || prev.Line < tok.Line
+}
+
+// definitions returns the identifier tokens defining variables, in source order
+func (d *document) definitions() []*lexer.Token {
+	var defs []*lexer.Token
+	for i := range d.tokens {
+		if d.isDefinition(i) {
+			defs = append(defs, d.tokens[i])
+		}
+	}
+	return defs
+}
+
+// definition returns the definition of name in effect at pos: the last one before it, or the first
+// one when it is used before being defined
+func (d *document) definition(name string, pos ast.Position) *lexer.Token {
+	var found *lexer.Token
+	for _, def := range d.definitions() {
+		if !strings.EqualFold(def.Value, name) {
+			continue
+		}
+		if found == nil || def.Line < pos.Line || def.Line == pos.Line && def.Column <= pos.Column {
+			found = def
+		}
+	}
+	return found
+}
+
+// utf16Len returns the length of s in UTF-16 code units, as LSP counts characters
+func utf16Len(s string) int {
+	n := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		n += utf16.RuneLen(r)
+		s = s[size:]
+	}
+	return n
+}
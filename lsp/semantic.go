@@ -0,0 +1,105 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/lexer"
+	"github.com/DTrader-store/formula-go/parser/ast"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// Semantic token types and modifiers, in the order of the legend sent to the client
+var (
+	tokenTypes     = []string{"function", "variable", "number", "string", "comment", "operator", "keyword", "enumMember"}
+	tokenModifiers = []string{"declaration", "defaultLibrary"}
+)
+
+const (
+	tokenFunction = iota
+	tokenVariable
+	tokenNumber
+	tokenString
+	tokenComment
+	tokenOperator
+	tokenKeyword
+	tokenEnumMember
+)
+
+const (
+	modifierDeclaration = 1 << iota
+	modifierDefaultLibrary
+)
+
+// operators are the token types highlighted as operators
+var operators = map[lexer.TokenType]bool{
+	lexer.PLUS: true, lexer.MINUS: true, lexer.MULTIPLY: true, lexer.DIVIDE: true,
+	lexer.GT: true, lexer.LT: true, lexer.GTE: true, lexer.LTE: true, lexer.EQ: true, lexer.NEQ: true,
+	lexer.ASSIGN: true, lexer.COLON: true, lexer.HASH: true,
+}
+
+// semanticTokens classifies the tokens of a document, encoded relative to the previous token
+func (s *Server) semanticTokens(_ string, d *document) *SemanticTokens {
+	data := []int{}
+	var line, char int
+	emit := func(r Range, tokenType, modifiers int) {
+		if r.Start.Line != line {
+			char = 0
+		}
+		data = append(data, r.Start.Line-line, r.Start.Character-char, r.End.Character-r.Start.Character, tokenType, modifiers)
+		line, char = r.Start.Line, r.Start.Character
+	}
+
+	for i, tok := range d.tokens {
+		switch {
+		case tok.Type == lexer.COMMENT:
+			// Block comments may span lines, and tokens must not
+			for j, text := range strings.Split(tok.Value, "\n") {
+				start := ast.Position{Line: tok.Line + j, Column: 1}
+				if j == 0 {
+					start.Column = tok.Column
+				}
+				end := ast.Position{Line: start.Line, Column: start.Column + len(text)}
+				emit(d.span(ast.Span{Start: start, End: end}), tokenComment, 0)
+			}
+		case tok.Type == lexer.IDENTIFIER:
+			tokenType, modifiers := s.identifier(d, i)
+			emit(d.tokenRange(tok), tokenType, modifiers)
+		case tok.Type == lexer.NUMBER:
+			emit(d.tokenRange(tok), tokenNumber, 0)
+		case tok.Type == lexer.STRING || tok.Type == lexer.TEXT:
+			emit(d.tokenRange(tok), tokenString, 0)
+		case tok.Type == lexer.AND || tok.Type == lexer.OR || tok.Type == lexer.IF:
+			emit(d.tokenRange(tok), tokenKeyword, 0)
+		case tok.Type == lexer.COLOR || tok.Type == lexer.LINETHICK || tok.Type == lexer.DOTLINE || tok.Type == lexer.STICK:
+			emit(d.tokenRange(tok), tokenEnumMember, 0)
+		case operators[tok.Type]:
+			emit(d.tokenRange(tok), tokenOperator, 0)
+		}
+	}
+	return &SemanticTokens{Data: data}
+}
+
+// identifier classifies the identifier at index i as a function, attribute or variable
+func (s *Server) identifier(d *document, i int) (tokenType, modifiers int) {
+	tok := d.tokens[i]
+	if next := d.significant(i+1, 1); next != nil && next.Type == lexer.LPAREN {
+		if _, ok := s.functions.Lookup(tok.Value); ok {
+			return tokenFunction, modifierDefaultLibrary
+		}
+		return tokenFunction, 0
+	}
+	if d.isDefinition(i) {
+		return tokenVariable, modifierDeclaration
+	}
+	if d.definition(tok.Value, ast.Position{Line: tok.Line, Column: tok.Column}) != nil {
+		return tokenVariable, 0
+	}
+	if _, ok := interpreter.BuiltinVariable(tok.Value); ok {
+		return tokenVariable, modifierDefaultLibrary
+	}
+	if types.IsAttribute(tok.Value) {
+		return tokenEnumMember, 0
+	}
+	return tokenVariable, 0
+}
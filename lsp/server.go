@@ -0,0 +1,365 @@
+// Package lsp implements a Language Server Protocol server for formula files over stdio: it
+// publishes lint diagnostics and provides hover, completion, go-to-definition, semantic tokens
+// and formatting, so any LSP client can edit formulas.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DTrader-store/formula-go/format"
+	"github.com/DTrader-store/formula-go/interpreter"
+	"github.com/DTrader-store/formula-go/lexer"
+	"github.com/DTrader-store/formula-go/lint"
+	"github.com/DTrader-store/formula-go/parser/ast"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// ErrNoShutdown is returned by Serve when the client exits without a shutdown request
+var ErrNoShutdown = stderrors.New("exit without shutdown")
+
+// Server answers LSP requests for formula documents
+type Server struct {
+	functions *interpreter.FunctionRegistry
+	linter    *lint.Linter
+	documents map[string]*document
+	out       io.Writer
+	shutdown  bool
+}
+
+// NewServer creates a server resolving functions in functions; nil uses the built-in functions
+func NewServer(functions *interpreter.FunctionRegistry) *Server {
+	if functions == nil {
+		functions = interpreter.NewFunctionRegistry()
+	}
+	return &Server{
+		functions: functions,
+		linter:    lint.New(functions),
+		documents: make(map[string]*document),
+	}
+}
+
+// Linter returns the linter used for diagnostics, e.g. to Define variables supplied at run time
+func (s *Server) Linter() *lint.Linter {
+	return s.linter
+}
+
+// Serve reads requests from in and writes responses and notifications to out until the client
+// sends exit. It returns nil after a shutdown request, and ErrNoShutdown otherwise.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+	r := bufio.NewReader(in)
+	for {
+		msg, err := readMessage(r)
+		var rpcErr *responseError
+		switch {
+		case stderrors.As(err, &rpcErr):
+			if err := s.reply(nil, nil, rpcErr); err != nil {
+				return err
+			}
+			continue
+		case stderrors.Is(err, io.EOF):
+			return ErrNoShutdown
+		case err != nil:
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if s.shutdown {
+				return nil
+			}
+			return ErrNoShutdown
+		}
+		result, rpcErr := s.handle(msg)
+		if msg.ID == nil {
+			continue // notifications get no response
+		}
+		if err := s.reply(msg.ID, result, rpcErr); err != nil {
+			return err
+		}
+	}
+}
+
+// reply sends the response to a request
+func (s *Server) reply(id *json.RawMessage, result any, rpcErr *responseError) error {
+	msg := &message{ID: id, Error: rpcErr}
+	if id == nil {
+		null := json.RawMessage("null")
+		msg.ID = &null
+	}
+	if rpcErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		msg.Result = data
+	}
+	return writeMessage(s.out, msg)
+}
+
+// notify sends a notification to the client
+func (s *Server) notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.out, &message{Method: method, Params: data})
+}
+
+// handle dispatches a request or notification
+func (s *Server) handle(msg *message) (any, *responseError) {
+	if s.shutdown && msg.Method != "exit" {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return s.initialize(), nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.open(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		// Documents are synchronized in full, so the last change holds the whole text
+		if n := len(params.ContentChanges); n > 0 {
+			s.open(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params documentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.documents, params.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+		return nil, nil
+	case "textDocument/hover":
+		return withPosition(s, msg, s.hover)
+	case "textDocument/completion":
+		return withPosition(s, msg, s.completion)
+	case "textDocument/definition":
+		return withPosition(s, msg, s.definition)
+	case "textDocument/semanticTokens/full":
+		return withDocument(s, msg, s.semanticTokens)
+	case "textDocument/formatting":
+		return withDocument(s, msg, s.formatting)
+	}
+
+	if msg.ID == nil {
+		return nil, nil // unknown notifications such as initialized are ignored
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+// invalidParams reports parameters that do not decode
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+// withDocument decodes the document of a request and calls handler with it
+func withDocument[T any](s *Server, msg *message, handler func(uri string, d *document) T) (any, *responseError) {
+	var params documentParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return nil, invalidParams(err)
+	}
+	d, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+	return handler(params.TextDocument.URI, d), nil
+}
+
+// withPosition decodes the document and position of a request and calls handler with them
+func withPosition[T any](s *Server, msg *message, handler func(uri string, d *document, pos ast.Position) T) (any, *responseError) {
+	var params positionParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return nil, invalidParams(err)
+	}
+	d, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+	return handler(params.TextDocument.URI, d, d.source(params.Position)), nil
+}
+
+// initialize returns the server capabilities
+func (s *Server) initialize() any {
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync":           1, // full
+			"hoverProvider":              true,
+			"completionProvider":         map[string]any{"triggerCharacters": []string{","}},
+			"definitionProvider":         true,
+			"documentFormattingProvider": true,
+			"semanticTokensProvider": map[string]any{
+				"legend": map[string]any{"tokenTypes": tokenTypes, "tokenModifiers": tokenModifiers},
+				"full":   true,
+			},
+		},
+		"serverInfo": map[string]string{"name": "formula-lsp"},
+	}
+}
+
+// open stores a document and publishes its diagnostics
+func (s *Server) open(uri, text string) {
+	d := newDocument(text)
+	s.documents[uri] = d
+
+	diagnostics := []Diagnostic{}
+	for _, diag := range s.linter.Source(text) {
+		severity := severityError
+		if diag.Severity == lint.Warning {
+			severity = severityWarning
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.span(diag.Span),
+			Severity: severity,
+			Code:     diag.Code,
+			Source:   "formula",
+			Message:  diag.Message,
+		})
+	}
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+}
+
+// hover describes the function, variable or attribute under the cursor
+func (s *Server) hover(_ string, d *document, pos ast.Position) *Hover {
+	i := d.tokenAt(pos)
+	if i < 0 || d.tokens[i].Type != lexer.IDENTIFIER {
+		return nil
+	}
+	tok := d.tokens[i]
+	r := d.tokenRange(tok)
+
+	if next := d.significant(i+1, 1); next != nil && next.Type == lexer.LPAREN {
+		if desc, ok := s.functions.Lookup(tok.Value); ok {
+			return &Hover{Contents: functionDoc(desc), Range: &r}
+		}
+	}
+	if def := d.definition(tok.Value, pos); def != nil {
+		return &Hover{Contents: markdown("```\n" + strings.TrimSpace(d.lines[def.Line-1]) + "\n```"), Range: &r}
+	}
+	if series, ok := interpreter.BuiltinVariable(tok.Value); ok {
+		kind := "单值"
+		if series {
+			kind = "序列"
+		}
+		return &Hover{Contents: markdown(fmt.Sprintf("内置变量 `%s`（%s）", strings.ToUpper(tok.Value), kind)), Range: &r}
+	}
+	if types.IsAttribute(tok.Value) {
+		return &Hover{Contents: markdown(fmt.Sprintf("绘图属性 `%s`", strings.ToUpper(tok.Value))), Range: &r}
+	}
+	if desc, ok := s.functions.Lookup(tok.Value); ok {
+		return &Hover{Contents: functionDoc(desc), Range: &r}
+	}
+	return nil
+}
+
+// functionDoc renders a function's usage and description as Markdown
+func functionDoc(desc *interpreter.Descriptor) *MarkupContent {
+	lines := []string{"```\n" + desc.Usage() + "\n```"}
+	for _, text := range []string{desc.Description.Zh, desc.Description.En} {
+		if text != "" {
+			lines = append(lines, text)
+		}
+	}
+	if desc.Future {
+		lines = append(lines, "⚠️ 未来函数：会引用之后的 K 线")
+	}
+	if desc.Example != "" {
+		lines = append(lines, "示例: `"+desc.Example+"`")
+	}
+	return markdown(lines...)
+}
+
+// completion offers drawing attributes after the expression of an output declaration, and
+// functions and variables elsewhere
+func (s *Server) completion(_ string, d *document, pos ast.Position) []CompletionItem {
+	if pos.Line <= len(d.lines) && inAttributes(d.lines[pos.Line-1][:pos.Column-1]) {
+		var items []CompletionItem
+		for _, name := range types.AttributeNames() {
+			items = append(items, CompletionItem{Label: name, Kind: completionEnumMember, Detail: "绘图属性"})
+		}
+		return items
+	}
+
+	var items []CompletionItem
+	seen := make(map[string]bool)
+	for _, def := range d.definitions() {
+		if key := strings.ToUpper(def.Value); !seen[key] {
+			seen[key] = true
+			items = append(items, CompletionItem{Label: def.Value, Kind: completionVariable, Detail: strings.TrimSpace(d.lines[def.Line-1])})
+		}
+	}
+	for _, desc := range s.functions.Descriptors() {
+		items = append(items, CompletionItem{Label: desc.Name, Kind: completionFunction, Detail: desc.Usage(), Documentation: functionDoc(desc)})
+	}
+	for _, name := range interpreter.BuiltinVariables() {
+		if !seen[name] {
+			items = append(items, CompletionItem{Label: name, Kind: completionConstant, Detail: "内置变量"})
+		}
+	}
+	return items
+}
+
+// inAttributes reports whether the text before the cursor ends in the attribute list of an
+// output declaration, as in UP : MA(C, 5), COLOR
+func inAttributes(prefix string) bool {
+	tokens, err := lexer.NewLexer(prefix).Tokenize()
+	if err != nil {
+		return false
+	}
+	depth, output, attributes := 0, false, false
+	for _, tok := range tokens {
+		switch tok.Type {
+		case lexer.LPAREN:
+			depth++
+		case lexer.RPAREN:
+			depth--
+		case lexer.COLON:
+			output = depth == 0
+		case lexer.COMMA:
+			attributes = attributes || output && depth == 0
+		case lexer.SEMICOLON:
+			depth, output, attributes = 0, false, false
+		}
+	}
+	return attributes
+}
+
+// definition returns where the variable under the cursor is defined
+func (s *Server) definition(uri string, d *document, pos ast.Position) *Location {
+	i := d.tokenAt(pos)
+	if i < 0 || d.tokens[i].Type != lexer.IDENTIFIER {
+		return nil
+	}
+	def := d.definition(d.tokens[i].Value, pos)
+	if def == nil {
+		return nil
+	}
+	return &Location{URI: uri, Range: d.tokenRange(def)}
+}
+
+// formatting replaces the document with its canonical form; a document that does not parse is
+// left as it is
+func (s *Server) formatting(_ string, d *document) []TextEdit {
+	formatted, err := format.Source(d.text)
+	if err != nil || formatted == d.text {
+		return []TextEdit{}
+	}
+	return []TextEdit{{Range: Range{End: d.end()}, NewText: formatted}}
+}
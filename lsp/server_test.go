@@ -0,0 +1,229 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const uri = "file:///test.fml"
+
+// request builds a framed request; an id of 0 makes a notification
+func request(id int, method string, params any) string {
+	msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if id != 0 {
+		msg["id"] = id
+	}
+	body, _ := json.Marshal(msg)
+	return "Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + string(body)
+}
+
+// session opens text, sends the requests followed by shutdown and exit, and returns the messages
+// the server wrote
+func session(t *testing.T, text string, requests ...string) []*message {
+	t.Helper()
+	in := request(1, "initialize", map[string]any{}) +
+		request(0, "initialized", map[string]any{}) +
+		request(0, "textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "languageId": "formula", "version": 1, "text": text}}) +
+		strings.Join(requests, "") +
+		request(99, "shutdown", nil) +
+		request(0, "exit", nil)
+
+	var out bytes.Buffer
+	if err := NewServer(nil).Serve(strings.NewReader(in), &out); err != nil {
+		t.Fatalf("Serve error: %v", err)
+	}
+	var messages []*message
+	r := bufio.NewReader(&out)
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			return messages
+		}
+		messages = append(messages, msg)
+	}
+}
+
+// response returns the result of the request with id, decoded into v
+func response(t *testing.T, messages []*message, id int, v any) {
+	t.Helper()
+	for _, msg := range messages {
+		if msg.ID != nil && string(*msg.ID) == strconv.Itoa(id) {
+			if msg.Error != nil {
+				t.Fatalf("Request %d failed: %s", id, msg.Error.Message)
+			}
+			if err := json.Unmarshal(msg.Result, v); err != nil {
+				t.Fatalf("Decode result %d: %v", id, err)
+			}
+			return
+		}
+	}
+	t.Fatalf("No response to request %d", id)
+}
+
+// at builds the parameters of a request at a position in the test document
+func at(line, character int) map[string]any {
+	return map[string]any{"textDocument": map[string]any{"uri": uri}, "position": map[string]any{"line": line, "character": character}}
+}
+
+func TestDiagnostics(t *testing.T) {
+	messages := session(t, "{说明}X := MA(C, N)\nY := MAA(C, 5)")
+	var params publishDiagnosticsParams
+	for _, msg := range messages {
+		if msg.Method == "textDocument/publishDiagnostics" {
+			json.Unmarshal(msg.Params, &params)
+			break
+		}
+	}
+	if params.URI != uri || len(params.Diagnostics) != 2 {
+		t.Fatalf("Unexpected diagnostics %+v", params)
+	}
+	undefined := params.Diagnostics[0]
+	if undefined.Code != "undefined" || undefined.Severity != severityError ||
+		undefined.Range != (Range{Start: Position{Character: 15}, End: Position{Character: 16}}) {
+		t.Errorf("Unexpected diagnostic %+v", undefined)
+	}
+
+	syntax := session(t, "X := MA(C, 5")
+	json.Unmarshal(syntax[1].Params, &params)
+	if len(params.Diagnostics) != 1 || params.Diagnostics[0].Code != "syntax" {
+		t.Errorf("Expected a syntax diagnostic, got %+v", params.Diagnostics)
+	}
+}
+
+func TestHoverAndDefinition(t *testing.T) {
+	text := "MA5 := MA(C, 5);\nUP : CROSS(C, MA5), COLORRED"
+	messages := session(t, text,
+		request(2, "textDocument/hover", at(0, 8)),
+		request(3, "textDocument/hover", at(1, 16)),
+		request(4, "textDocument/hover", at(1, 11)),
+		request(5, "textDocument/hover", at(1, 22)),
+		request(6, "textDocument/definition", at(1, 15)),
+		request(7, "textDocument/hover", at(0, 6)),
+	)
+
+	var hover Hover
+	response(t, messages, 2, &hover)
+	if !strings.Contains(hover.Contents.Value, "MA(data, period)") || !strings.Contains(hover.Contents.Value, "简单移动平均") {
+		t.Errorf("Unexpected function hover %q", hover.Contents.Value)
+	}
+	hover = Hover{}
+	response(t, messages, 3, &hover)
+	if !strings.Contains(hover.Contents.Value, "MA5 := MA(C, 5);") {
+		t.Errorf("Unexpected variable hover %q", hover.Contents.Value)
+	}
+	hover = Hover{}
+	response(t, messages, 4, &hover)
+	if hover.Contents == nil || !strings.Contains(hover.Contents.Value, "内置变量 `C`（序列）") {
+		t.Errorf("Unexpected built-in variable hover %q", hover.Contents.Value)
+	}
+	hover = Hover{}
+	response(t, messages, 5, &hover)
+	if !strings.Contains(hover.Contents.Value, "绘图属性") {
+		t.Errorf("Unexpected attribute hover %q", hover.Contents.Value)
+	}
+
+	var location Location
+	response(t, messages, 6, &location)
+	if location.URI != uri || location.Range != (Range{End: Position{Character: 3}}) {
+		t.Errorf("Unexpected definition %+v", location)
+	}
+
+	var none *Hover
+	response(t, messages, 7, &none)
+	if none != nil {
+		t.Errorf("Expected no hover on an operator, got %+v", none)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	text := "MA5 := MA(C, 5);\nUP : MA5, "
+	messages := session(t, text,
+		request(2, "textDocument/completion", at(1, 5)),
+		request(3, "textDocument/completion", at(1, 10)),
+	)
+
+	labels := func(items []CompletionItem) map[string]int {
+		kinds := make(map[string]int)
+		for _, item := range items {
+			kinds[item.Label] = item.Kind
+		}
+		return kinds
+	}
+
+	var items []CompletionItem
+	response(t, messages, 2, &items)
+	kinds := labels(items)
+	if kinds["MA5"] != completionVariable || kinds["CROSS"] != completionFunction || kinds["CLOSE"] != completionConstant {
+		t.Errorf("Expected variables and functions, got %v", kinds)
+	}
+	if _, ok := kinds["COLORRED"]; ok {
+		t.Error("Attributes offered outside an attribute list")
+	}
+
+	response(t, messages, 3, &items)
+	kinds = labels(items)
+	if kinds["COLORRED"] != completionEnumMember || kinds["LINETHICK2"] != completionEnumMember {
+		t.Errorf("Expected attributes, got %v", kinds)
+	}
+	if _, ok := kinds["MA"]; ok {
+		t.Error("Functions offered in an attribute list")
+	}
+}
+
+func TestSemanticTokens(t *testing.T) {
+	text := "{均线}\nMA5 := MA(C, 5);\nUP : MA5 > 'x', COLORRED"
+	messages := session(t, text, request(2, "textDocument/semanticTokens/full", map[string]any{"textDocument": map[string]any{"uri": uri}}))
+
+	var tokens SemanticTokens
+	response(t, messages, 2, &tokens)
+	expected := []int{
+		0, 0, 4, tokenComment, 0, // {均线} is 4 UTF-16 units
+		1, 0, 3, tokenVariable, modifierDeclaration,
+		0, 4, 2, tokenOperator, 0,
+		0, 3, 2, tokenFunction, modifierDefaultLibrary,
+		0, 3, 1, tokenVariable, modifierDefaultLibrary,
+		0, 3, 1, tokenNumber, 0,
+		1, 0, 2, tokenVariable, modifierDeclaration,
+		0, 3, 1, tokenOperator, 0,
+		0, 2, 3, tokenVariable, 0,
+		0, 4, 1, tokenOperator, 0,
+		0, 2, 3, tokenString, 0,
+		0, 5, 8, tokenEnumMember, 0,
+	}
+	if !slices.Equal(tokens.Data, expected) {
+		t.Errorf("Expected\n%v\ngot\n%v", expected, tokens.Data)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	messages := session(t, "ma5:=ma(c,5)\nx:=ma5>c",
+		request(2, "textDocument/formatting", map[string]any{"textDocument": map[string]any{"uri": uri}}))
+
+	var edits []TextEdit
+	response(t, messages, 2, &edits)
+	if len(edits) != 1 || edits[0].NewText != "ma5 := MA(c, 5);\nx := ma5 > c;\n" ||
+		edits[0].Range != (Range{End: Position{Line: 1, Character: 8}}) {
+		t.Errorf("Unexpected edits %+v", edits)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	messages := session(t, "", request(2, "textDocument/unknown", map[string]any{}))
+	for _, msg := range messages {
+		if msg.ID != nil && string(*msg.ID) == "2" {
+			if msg.Error == nil || msg.Error.Code != codeMethodNotFound {
+				t.Errorf("Expected method not found, got %+v", msg)
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	if err := NewServer(nil).Serve(strings.NewReader(request(0, "exit", nil)), &out); err != ErrNoShutdown {
+		t.Errorf("Expected ErrNoShutdown, got %v", err)
+	}
+}
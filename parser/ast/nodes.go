@@ -17,10 +17,14 @@ const (
 	UnaryExpressionNode       NodeType = "UnaryExpression"
 	FunctionCallNode          NodeType = "FunctionCall"
 	ConditionalExpressionNode NodeType = "ConditionalExpression"
+	FormulaReferenceNode      NodeType = "FormulaReference"
+	PeriodExpressionNode      NodeType = "PeriodExpression"
+	SymbolReferenceNode       NodeType = "SymbolReference"
 
 	// Literals and Identifiers
 	IdentifierNode    NodeType = "Identifier"
 	NumberLiteralNode NodeType = "NumberLiteral"
+	StringLiteralNode NodeType = "StringLiteral"
 )
 
 // BinaryOperator represents binary operators
@@ -64,6 +68,18 @@ type DrawingStyle struct {
 	Italic *bool
 }
 
+// Position is a location in formula source; lines and columns are 1-indexed
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Span is the source range of a node, from its first character to just past its last
+type Span struct {
+	Start Position
+	End   Position
+}
+
 // Node is the base interface for all AST nodes
 type Node interface {
 	Type() NodeType
@@ -98,11 +114,12 @@ type VariableDeclaration struct {
 func (v *VariableDeclaration) Type() NodeType { return VariableDeclarationNode }
 func (v *VariableDeclaration) stmtNode()      {}
 
-// OutputDeclaration represents: output result = x + y; or output result = x + y; [color: red, size: 14];
+// OutputDeclaration represents: NAME : expression, COLORRED, LINETHICK2;
 type OutputDeclaration struct {
-	Name  string
-	Value Expression
-	Style *DrawingStyle
+	Name       string
+	Value      Expression
+	Style      *DrawingStyle
+	Attributes []string // Drawing attributes in upper case, e.g. COLORRED or DOTLINE
 }
 
 func (o *OutputDeclaration) Type() NodeType { return OutputDeclarationNode }
@@ -154,6 +171,34 @@ type ConditionalExpression struct {
 func (c *ConditionalExpression) Type() NodeType { return ConditionalExpressionNode }
 func (c *ConditionalExpression) exprNode()      {}
 
+// FormulaReference represents: "FORMULA.OUTPUT"(param1, param2, ...) or FORMULA.OUTPUT
+type FormulaReference struct {
+	Formula   string       // Referenced formula name
+	Output    string       // Output name; empty selects the formula's first output
+	Arguments []Expression // Parameter overrides in the formula's declared order
+}
+
+func (f *FormulaReference) Type() NodeType { return FormulaReferenceNode }
+func (f *FormulaReference) exprNode()      {}
+
+// PeriodExpression represents: expression#PERIOD, evaluated on bars of another period
+type PeriodExpression struct {
+	Expr   Expression // Expression evaluated on the resampled bars
+	Period string     // Period name, e.g. "WEEK" or "MIN15"
+}
+
+func (p *PeriodExpression) Type() NodeType { return PeriodExpressionNode }
+func (p *PeriodExpression) exprNode()      {}
+
+// SymbolReference represents: "SH000001$CLOSE", a data field of another symbol
+type SymbolReference struct {
+	Symbol string // Symbol code, e.g. "SH000001"
+	Field  string // Market data field, e.g. "CLOSE"
+}
+
+func (s *SymbolReference) Type() NodeType { return SymbolReferenceNode }
+func (s *SymbolReference) exprNode()      {}
+
 // Identifier represents: variable or function name reference
 type Identifier struct {
 	Name string
@@ -169,3 +214,11 @@ type NumberLiteral struct {
 
 func (n *NumberLiteral) Type() NodeType { return NumberLiteralNode }
 func (n *NumberLiteral) exprNode()      {}
+
+// StringLiteral represents: 'text', e.g. a block name passed to INBLOCK
+type StringLiteral struct {
+	Value string
+}
+
+func (s *StringLiteral) Type() NodeType { return StringLiteralNode }
+func (s *StringLiteral) exprNode()      {}
@@ -3,10 +3,12 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/DTrader-store/formula-go/errors"
 	"github.com/DTrader-store/formula-go/lexer"
 	"github.com/DTrader-store/formula-go/parser/ast"
+	"github.com/DTrader-store/formula-go/types"
 )
 
 // Parser performs syntactic analysis on tokens
@@ -14,20 +16,36 @@ type Parser struct {
 	tokens  []*lexer.Token
 	pos     int
 	current *lexer.Token
+	last    *lexer.Token          // most recently consumed token
+	spans   map[ast.Node]ast.Span // source ranges of the parsed statements and expressions
 }
 
-// NewParser creates a new Parser instance
+// NewParser creates a new Parser instance. Comment tokens are skipped.
 func NewParser(tokens []*lexer.Token) *Parser {
+	code := make([]*lexer.Token, 0, len(tokens))
+	for _, tok := range tokens {
+		if tok.Type != lexer.COMMENT {
+			code = append(code, tok)
+		}
+	}
+
 	p := &Parser{
-		tokens: tokens,
+		tokens: code,
 		pos:    0,
+		spans:  make(map[ast.Node]ast.Span),
 	}
-	if len(tokens) > 0 {
-		p.current = tokens[0]
+	if len(code) > 0 {
+		p.current = code[0]
 	}
 	return p
 }
 
+// Spans returns the source ranges of the statements and expressions returned by Parse. Statement
+// ranges exclude the terminating semicolon; a parenthesized expression's range excludes the parentheses.
+func (p *Parser) Spans() map[ast.Node]ast.Span {
+	return p.spans
+}
+
 // Parse parses the tokens and returns an AST
 func (p *Parser) Parse() (*ast.Program, error) {
 	statements := make([]ast.Statement, 0)
@@ -52,25 +70,33 @@ func (p *Parser) Parse() (*ast.Program, error) {
 	return &ast.Program{Body: statements}, nil
 }
 
-// parseStatement parses a single statement
+// parseStatement parses a single statement and the semicolon or newline ending it
 func (p *Parser) parseStatement() (ast.Statement, error) {
-	// Check for assignment (identifier := expression)
-	if p.current.Type == lexer.IDENTIFIER && p.peek() != nil && p.peek().Type == lexer.ASSIGN {
-		return p.parseVariableDeclaration()
+	start := p.current
+
+	var stmt ast.Statement
+	var err error
+	switch {
+	case p.current.Type == lexer.IDENTIFIER && p.peek() != nil && p.peek().Type == lexer.ASSIGN:
+		stmt, err = p.parseVariableDeclaration()
+	case p.current.Type == lexer.IDENTIFIER && p.peek() != nil && p.peek().Type == lexer.COLON:
+		stmt, err = p.parseOutputDeclaration()
+	default:
+		var expr ast.Expression
+		expr, err = p.parseExpression()
+		stmt = &ast.ExpressionStatement{Expr: expr}
 	}
-
-	// Otherwise, parse as expression statement
-	expr, err := p.parseExpression()
 	if err != nil {
 		return nil, err
 	}
+	p.mark(stmt, tokenStart(start))
 
 	// Skip optional semicolon or newline
 	if !p.isAtEnd() && (p.current.Type == lexer.SEMICOLON || p.current.Type == lexer.NEWLINE) {
 		p.advance()
 	}
 
-	return &ast.ExpressionStatement{Expr: expr}, nil
+	return stmt, nil
 }
 
 // parseVariableDeclaration parses a variable declaration: name := expression
@@ -88,12 +114,37 @@ func (p *Parser) parseVariableDeclaration() (*ast.VariableDeclaration, error) {
 		return nil, err
 	}
 
-	// Skip optional semicolon or newline
-	if !p.isAtEnd() && (p.current.Type == lexer.SEMICOLON || p.current.Type == lexer.NEWLINE) {
-		p.advance()
+	return &ast.VariableDeclaration{Name: name, Value: value}, nil
+}
+
+// parseOutputDeclaration parses an output declaration with optional drawing attributes:
+// name : expression, COLORRED, LINETHICK2
+func (p *Parser) parseOutputDeclaration() (*ast.OutputDeclaration, error) {
+	name := p.current.Value
+	p.advance() // consume identifier
+	p.advance() // consume ':'
+
+	value, err := p.parseExpression()
+	if err != nil {
+		return nil, err
 	}
 
-	return &ast.VariableDeclaration{Name: name, Value: value}, nil
+	decl := &ast.OutputDeclaration{Name: name, Value: value}
+	for !p.isAtEnd() && p.current.Type == lexer.COMMA {
+		p.advance() // consume ','
+		attr := strings.ToUpper(p.current.Value)
+		switch p.current.Type {
+		case lexer.IDENTIFIER, lexer.COLOR, lexer.LINETHICK, lexer.DOTLINE, lexer.STICK:
+		default:
+			return nil, p.error("expected drawing attribute after ','")
+		}
+		if !types.IsAttribute(attr) {
+			return nil, p.error(fmt.Sprintf("unknown drawing attribute: %s", p.current.Value))
+		}
+		decl.Attributes = append(decl.Attributes, attr)
+		p.advance()
+	}
+	return decl, nil
 }
 
 // parseExpression parses an expression (handles operator precedence)
@@ -114,11 +165,7 @@ func (p *Parser) parseLogicalOr() (ast.Expression, error) {
 		if err != nil {
 			return nil, err
 		}
-		left = &ast.BinaryExpression{
-			Left:     left,
-			Operator: ast.OpOr,
-			Right:    right,
-		}
+		left = p.binary(left, ast.OpOr, right)
 	}
 
 	return left, nil
@@ -137,11 +184,7 @@ func (p *Parser) parseLogicalAnd() (ast.Expression, error) {
 		if err != nil {
 			return nil, err
 		}
-		left = &ast.BinaryExpression{
-			Left:     left,
-			Operator: ast.OpAnd,
-			Right:    right,
-		}
+		left = p.binary(left, ast.OpAnd, right)
 	}
 
 	return left, nil
@@ -178,11 +221,7 @@ func (p *Parser) parseComparison() (ast.Expression, error) {
 		if err != nil {
 			return nil, err
 		}
-		left = &ast.BinaryExpression{
-			Left:     left,
-			Operator: op,
-			Right:    right,
-		}
+		left = p.binary(left, op, right)
 	}
 
 	return left, nil
@@ -206,11 +245,7 @@ func (p *Parser) parseAdditive() (ast.Expression, error) {
 		if err != nil {
 			return nil, err
 		}
-		left = &ast.BinaryExpression{
-			Left:     left,
-			Operator: op,
-			Right:    right,
-		}
+		left = p.binary(left, op, right)
 	}
 
 	return left, nil
@@ -234,11 +269,7 @@ func (p *Parser) parseMultiplicative() (ast.Expression, error) {
 		if err != nil {
 			return nil, err
 		}
-		left = &ast.BinaryExpression{
-			Left:     left,
-			Operator: op,
-			Right:    right,
-		}
+		left = p.binary(left, op, right)
 	}
 
 	return left, nil
@@ -247,18 +278,46 @@ func (p *Parser) parseMultiplicative() (ast.Expression, error) {
 // parseUnary parses unary expressions
 func (p *Parser) parseUnary() (ast.Expression, error) {
 	if !p.isAtEnd() && p.current.Type == lexer.MINUS {
+		start := tokenStart(p.current)
 		p.advance()
 		operand, err := p.parseUnary()
 		if err != nil {
 			return nil, err
 		}
-		return &ast.UnaryExpression{
+		unary := &ast.UnaryExpression{
 			Operator: ast.OpUnaryMinus,
 			Operand:  operand,
-		}, nil
+		}
+		p.mark(unary, start)
+		return unary, nil
+	}
+
+	return p.parsePostfix()
+}
+
+// parsePostfix parses a primary expression followed by optional period suffixes: MA(CLOSE, 20)#WEEK
+func (p *Parser) parsePostfix() (ast.Expression, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for !p.isAtEnd() && p.current.Type == lexer.HASH {
+		p.advance() // consume '#'
+		if p.current.Type != lexer.IDENTIFIER {
+			return nil, p.error("expected period name after '#'")
+		}
+		period, err := types.ParsePeriod(p.current.Value)
+		if err != nil {
+			return nil, p.error(err.Error())
+		}
+		p.advance()
+		start := p.spans[expr].Start
+		expr = &ast.PeriodExpression{Expr: expr, Period: string(period)}
+		p.mark(expr, start)
 	}
 
-	return p.parsePrimary()
+	return expr, nil
 }
 
 // parsePrimary parses primary expressions (literals, identifiers, function calls, parentheses)
@@ -267,11 +326,28 @@ func (p *Parser) parsePrimary() (ast.Expression, error) {
 		return nil, p.error("unexpected end of input")
 	}
 
+	start := tokenStart(p.current)
+	expr, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	p.mark(expr, start)
+	return expr, nil
+}
+
+// parseOperand parses the expression parsePrimary returns
+func (p *Parser) parseOperand() (ast.Expression, error) {
 	switch p.current.Type {
 	case lexer.NUMBER:
 		return p.parseNumber()
 	case lexer.IDENTIFIER, lexer.IF: // IF can be used as function name
 		return p.parseIdentifierOrCall()
+	case lexer.STRING:
+		return p.parseQuotedReference()
+	case lexer.TEXT:
+		text := &ast.StringLiteral{Value: p.current.Value}
+		p.advance()
+		return text, nil
 	case lexer.LPAREN:
 		return p.parseGroupedExpression()
 	default:
@@ -294,9 +370,20 @@ func (p *Parser) parseIdentifierOrCall() (ast.Expression, error) {
 	name := p.current.Value
 	p.advance()
 
-	// Check if this is a function call
+	// Check if this is a formula reference: FORMULA.OUTPUT
+	if !p.isAtEnd() && p.current.Type == lexer.DOT {
+		p.advance() // consume '.'
+		if p.current.Type != lexer.IDENTIFIER {
+			return nil, p.error("expected output name after '.'")
+		}
+		ref := &ast.FormulaReference{Formula: name, Output: p.current.Value}
+		p.advance()
+		return p.parseReferenceArguments(ref)
+	}
+
+	// Check if this is a function call; function names are case-insensitive and kept upper-cased
 	if !p.isAtEnd() && p.current.Type == lexer.LPAREN {
-		return p.parseFunctionCall(name)
+		return p.parseFunctionCall(strings.ToUpper(name))
 	}
 
 	// Just an identifier
@@ -332,6 +419,61 @@ func (p *Parser) parseFunctionCall(name string) (*ast.FunctionCall, error) {
 	return &ast.FunctionCall{Name: name, Arguments: args}, nil
 }
 
+// parseQuotedReference parses a quoted formula reference: "FORMULA.OUTPUT"(args) or "FORMULA.OUTPUT#PERIOD"(args),
+// or a reference to another symbol's data: "SH000001$CLOSE"
+func (p *Parser) parseQuotedReference() (ast.Expression, error) {
+	text := strings.TrimSpace(p.current.Value)
+	text, periodName, hasPeriod := strings.Cut(text, "#")
+
+	var period types.Period
+	if hasPeriod {
+		var err error
+		if period, err = types.ParsePeriod(strings.TrimSpace(periodName)); err != nil {
+			return nil, p.error(err.Error())
+		}
+	}
+
+	var expr ast.Expression
+	if symbol, field, isSymbol := strings.Cut(text, "$"); isSymbol {
+		if symbol == "" || field == "" {
+			return nil, p.error(fmt.Sprintf("invalid symbol reference: %q", p.current.Value))
+		}
+		p.advance()
+		expr = &ast.SymbolReference{Symbol: symbol, Field: field}
+	} else {
+		formula, output, _ := strings.Cut(text, ".")
+		if formula == "" {
+			return nil, p.error(fmt.Sprintf("invalid formula reference: %q", p.current.Value))
+		}
+		p.advance()
+
+		var err error
+		expr, err = p.parseReferenceArguments(&ast.FormulaReference{Formula: formula, Output: output})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !hasPeriod {
+		return expr, nil
+	}
+	return &ast.PeriodExpression{Expr: expr, Period: string(period)}, nil
+}
+
+// parseReferenceArguments parses optional parameter overrides following a formula reference
+func (p *Parser) parseReferenceArguments(ref *ast.FormulaReference) (ast.Expression, error) {
+	if p.isAtEnd() || p.current.Type != lexer.LPAREN {
+		return ref, nil
+	}
+
+	call, err := p.parseFunctionCall(ref.Formula)
+	if err != nil {
+		return nil, err
+	}
+	ref.Arguments = call.Arguments
+	return ref, nil
+}
+
 // parseGroupedExpression parses a parenthesized expression
 func (p *Parser) parseGroupedExpression() (ast.Expression, error) {
 	p.advance() // consume '('
@@ -352,6 +494,7 @@ func (p *Parser) parseGroupedExpression() (ast.Expression, error) {
 // advance moves to the next token
 func (p *Parser) advance() {
 	if !p.isAtEnd() {
+		p.last = p.current
 		p.pos++
 		if p.pos < len(p.tokens) {
 			p.current = p.tokens[p.pos]
@@ -359,6 +502,35 @@ func (p *Parser) advance() {
 	}
 }
 
+// binary builds a binary expression spanning both operands
+func (p *Parser) binary(left ast.Expression, op ast.BinaryOperator, right ast.Expression) ast.Expression {
+	expr := &ast.BinaryExpression{Left: left, Operator: op, Right: right}
+	p.mark(expr, p.spans[left].Start)
+	return expr
+}
+
+// mark records the range of node from start to the end of the last consumed token, unless a
+// range was recorded already
+func (p *Parser) mark(node ast.Node, start ast.Position) {
+	if _, ok := p.spans[node]; !ok {
+		p.spans[node] = ast.Span{Start: start, End: tokenEnd(p.last)}
+	}
+}
+
+// tokenStart returns the position of a token
+func tokenStart(tok *lexer.Token) ast.Position {
+	return ast.Position{Line: tok.Line, Column: tok.Column}
+}
+
+// tokenEnd returns the position just past a token
+func tokenEnd(tok *lexer.Token) ast.Position {
+	width := len(tok.Value)
+	if tok.Type == lexer.STRING || tok.Type == lexer.TEXT {
+		width += 2 // quotes
+	}
+	return ast.Position{Line: tok.Line, Column: tok.Column + width}
+}
+
 // peek returns the next token without advancing
 func (p *Parser) peek() *lexer.Token {
 	if p.pos+1 < len(p.tokens) {
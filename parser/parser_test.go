@@ -221,3 +221,207 @@ func TestParserMultipleStatements(t *testing.T) {
 		t.Fatalf("Expected 3 statements, got %d", len(program.Body))
 	}
 }
+
+func TestParserFormulaReference(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		formula  string
+		output   string
+		argCount int
+	}{
+		{"quoted with output", `"MACD.DIF"`, "MACD", "DIF", 0},
+		{"quoted with params", `"KDJ.J"(18, 3, 3)`, "KDJ", "J", 3},
+		{"quoted without output", `"MYFORMULA"(5)`, "MYFORMULA", "", 1},
+		{"dotted identifier", "MACD.DEA", "MACD", "DEA", 0},
+		{"dotted with params", "BOLL.UB(26)", "BOLL", "UB", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := lexer.NewLexer(tt.input).Tokenize()
+			if err != nil {
+				t.Fatalf("Lexer error: %v", err)
+			}
+
+			program, err := NewParser(tokens).Parse()
+			if err != nil {
+				t.Fatalf("Parser error: %v", err)
+			}
+
+			exprStmt := program.Body[0].(*ast.ExpressionStatement)
+			ref, ok := exprStmt.Expr.(*ast.FormulaReference)
+			if !ok {
+				t.Fatalf("Expected FormulaReference, got %T", exprStmt.Expr)
+			}
+			if ref.Formula != tt.formula || ref.Output != tt.output {
+				t.Errorf("Expected %s.%s, got %s.%s", tt.formula, tt.output, ref.Formula, ref.Output)
+			}
+			if len(ref.Arguments) != tt.argCount {
+				t.Errorf("Expected %d arguments, got %d", tt.argCount, len(ref.Arguments))
+			}
+		})
+	}
+
+	tokens, _ := lexer.NewLexer("MACD.5").Tokenize()
+	if _, err := NewParser(tokens).Parse(); err == nil {
+		t.Error("Expected error for missing output name")
+	}
+}
+
+func TestParserPeriodExpression(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		period string
+		inner  string
+	}{
+		{"identifier", "CLOSE#WEEK", "WEEK", "Identifier"},
+		{"function call", "MA(CLOSE, 20)#week", "WEEK", "FunctionCall"},
+		{"minutes", "HIGH#MIN15", "MIN15", "Identifier"},
+		{"quoted reference", `"MACD.DIF#WEEK"(12, 26, 9)`, "WEEK", "FormulaReference"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := lexer.NewLexer(tt.input).Tokenize()
+			if err != nil {
+				t.Fatalf("Lexer error: %v", err)
+			}
+
+			program, err := NewParser(tokens).Parse()
+			if err != nil {
+				t.Fatalf("Parser error: %v", err)
+			}
+
+			exprStmt := program.Body[0].(*ast.ExpressionStatement)
+			periodExpr, ok := exprStmt.Expr.(*ast.PeriodExpression)
+			if !ok {
+				t.Fatalf("Expected PeriodExpression, got %T", exprStmt.Expr)
+			}
+			if periodExpr.Period != tt.period {
+				t.Errorf("Expected period %s, got %s", tt.period, periodExpr.Period)
+			}
+			if string(periodExpr.Expr.Type()) != tt.inner {
+				t.Errorf("Expected inner %s, got %s", tt.inner, periodExpr.Expr.Type())
+			}
+		})
+	}
+
+	// The suffix binds tighter than comparison
+	tokens, _ := lexer.NewLexer("CLOSE#WEEK > MA(CLOSE, 20)#WEEK").Tokenize()
+	program, err := NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parser error: %v", err)
+	}
+	binary, ok := program.Body[0].(*ast.ExpressionStatement).Expr.(*ast.BinaryExpression)
+	if !ok || binary.Left.Type() != ast.PeriodExpressionNode || binary.Right.Type() != ast.PeriodExpressionNode {
+		t.Errorf("Expected comparison of two period expressions")
+	}
+
+	for _, input := range []string{"CLOSE#FORTNIGHT", "CLOSE#5", `"MACD.DIF#NOPE"`} {
+		tokens, _ := lexer.NewLexer(input).Tokenize()
+		if _, err := NewParser(tokens).Parse(); err == nil {
+			t.Errorf("%s: expected error, got nil", input)
+		}
+	}
+}
+
+func TestParserStringLiteral(t *testing.T) {
+	tokens, err := lexer.NewLexer(`INBLOCK('沪深300')`).Tokenize()
+	if err != nil {
+		t.Fatalf("Lexer error: %v", err)
+	}
+
+	program, err := NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parser error: %v", err)
+	}
+
+	call := program.Body[0].(*ast.ExpressionStatement).Expr.(*ast.FunctionCall)
+	text, ok := call.Arguments[0].(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("Expected StringLiteral argument, got %T", call.Arguments[0])
+	}
+	if text.Value != "沪深300" {
+		t.Errorf("Expected '沪深300', got %q", text.Value)
+	}
+}
+
+func TestParserSymbolReference(t *testing.T) {
+	tokens, err := lexer.NewLexer(`"SH000001$CLOSE"`).Tokenize()
+	if err != nil {
+		t.Fatalf("Lexer error: %v", err)
+	}
+
+	program, err := NewParser(tokens).Parse()
+	if err != nil {
+		t.Fatalf("Parser error: %v", err)
+	}
+
+	ref, ok := program.Body[0].(*ast.ExpressionStatement).Expr.(*ast.SymbolReference)
+	if !ok {
+		t.Fatalf("Expected SymbolReference, got %T", program.Body[0].(*ast.ExpressionStatement).Expr)
+	}
+	if ref.Symbol != "SH000001" || ref.Field != "CLOSE" {
+		t.Errorf("Expected SH000001$CLOSE, got %s$%s", ref.Symbol, ref.Field)
+	}
+
+	tokens, _ = lexer.NewLexer(`"SH000001$"`).Tokenize()
+	if _, err := NewParser(tokens).Parse(); err == nil {
+		t.Error("Expected error for missing field")
+	}
+}
+
+func TestParserOutputDeclaration(t *testing.T) {
+	tokens, err := lexer.NewLexer("{均线} MID := ma(C, 20);\nUPPER : MID + 2 * STD(C, 20), colorred, LINETHICK2, DOTLINE; // 上轨").Tokenize()
+	if err != nil {
+		t.Fatalf("Lexer error: %v", err)
+	}
+
+	p := NewParser(tokens)
+	program, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parser error: %v", err)
+	}
+	if len(program.Body) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(program.Body))
+	}
+
+	if call := program.Body[0].(*ast.VariableDeclaration).Value.(*ast.FunctionCall); call.Name != "MA" {
+		t.Errorf("Expected upper-cased function name MA, got %s", call.Name)
+	}
+
+	decl, ok := program.Body[1].(*ast.OutputDeclaration)
+	if !ok {
+		t.Fatalf("Expected OutputDeclaration, got %T", program.Body[1])
+	}
+	if decl.Name != "UPPER" || decl.Value.Type() != ast.BinaryExpressionNode {
+		t.Errorf("Unexpected declaration %s of %s", decl.Name, decl.Value.Type())
+	}
+	if len(decl.Attributes) != 3 || decl.Attributes[0] != "COLORRED" || decl.Attributes[2] != "DOTLINE" {
+		t.Errorf("Unexpected attributes %v", decl.Attributes)
+	}
+
+	spans := p.Spans()
+	if span := spans[program.Body[0]]; span != (ast.Span{Start: ast.Position{Line: 1, Column: 10}, End: ast.Position{Line: 1, Column: 26}}) {
+		t.Errorf("Unexpected span of first statement: %+v", span)
+	}
+	call := program.Body[0].(*ast.VariableDeclaration).Value
+	if span := spans[call]; span != (ast.Span{Start: ast.Position{Line: 1, Column: 17}, End: ast.Position{Line: 1, Column: 26}}) {
+		t.Errorf("Unexpected span of call: %+v", span)
+	}
+	if span := spans[decl.Value]; span.Start.Column != 9 || span.End.Column != 29 {
+		t.Errorf("Unexpected span of binary expression: %+v", span)
+	}
+	if span := spans[program.Body[1]]; span.Start.Line != 2 || span.End.Column != 60 {
+		t.Errorf("Unexpected span of second statement: %+v", span)
+	}
+
+	for _, input := range []string{"X : C, COLORPINK", "X : C,", "X : C, 2"} {
+		tokens, _ := lexer.NewLexer(input).Tokenize()
+		if _, err := NewParser(tokens).Parse(); err == nil {
+			t.Errorf("%s: expected error, got nil", input)
+		}
+	}
+}
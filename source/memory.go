@@ -0,0 +1,174 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// Memory is an in-memory source for tests and offline examples. It can be filled by hand, by a
+// Recorder, or from a recording saved with Save.
+type Memory struct {
+	mu   sync.RWMutex
+	bars map[string]map[types.Period][]*types.MarketData // by lower-cased symbol, then period
+}
+
+// NewMemory creates an empty source
+func NewMemory() *Memory {
+	return &Memory{bars: make(map[string]map[types.Period][]*types.MarketData)}
+}
+
+// Add stores bars of a symbol, merging them with the stored ones; a bar at a time already
+// stored replaces it. Bars must carry times.
+func (m *Memory) Add(symbol string, period types.Period, bars []*types.MarketData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	symbol = strings.ToLower(symbol)
+	if m.bars[symbol] == nil {
+		m.bars[symbol] = make(map[types.Period][]*types.MarketData)
+	}
+	byTime := make(map[int64]*types.MarketData)
+	for _, list := range [][]*types.MarketData{m.bars[symbol][period], bars} {
+		for _, bar := range list {
+			byTime[bar.Time.UnixNano()] = bar
+		}
+	}
+	merged := make([]*types.MarketData, 0, len(byTime))
+	for _, bar := range byTime {
+		merged = append(merged, bar)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Time.Before(merged[j].Time) })
+	m.bars[symbol][period] = merged
+}
+
+// Bars returns the stored bars of a symbol within [from, to]; an unknown symbol or period is an error
+func (m *Memory) Bars(symbol string, period types.Period, from, to time.Time) ([]*types.MarketData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	bars, ok := m.bars[strings.ToLower(symbol)][period]
+	if !ok {
+		return nil, fmt.Errorf("no %s bars for %s", period, symbol)
+	}
+	return inRange(bars, from, to), nil
+}
+
+// Symbols lists the stored symbols, sorted
+func (m *Memory) Symbols() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	symbols := make([]string, 0, len(m.bars))
+	for symbol := range m.bars {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols, nil
+}
+
+// recording is the JSON form of a Memory source
+type recording struct {
+	Symbol string              `json:"symbol"`
+	Period types.Period        `json:"period"`
+	Bars   []*types.MarketData `json:"bars"`
+}
+
+// WriteJSON writes the stored bars as JSON, see ReadJSON
+func (m *Memory) WriteJSON(w io.Writer) error {
+	m.mu.RLock()
+	var records []recording
+	for symbol, periods := range m.bars {
+		for period, bars := range periods {
+			records = append(records, recording{Symbol: symbol, Period: period, Bars: bars})
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Symbol != records[j].Symbol {
+			return records[i].Symbol < records[j].Symbol
+		}
+		return records[i].Period < records[j].Period
+	})
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", " ")
+	return encoder.Encode(records)
+}
+
+// ReadJSON adds the bars of a recording written by WriteJSON
+func (m *Memory) ReadJSON(r io.Reader) error {
+	var records []recording
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return fmt.Errorf("cannot read recording: %w", err)
+	}
+	for _, record := range records {
+		m.Add(record.Symbol, record.Period, record.Bars)
+	}
+	return nil
+}
+
+// Save writes the stored bars to a JSON file
+func (m *Memory) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := m.WriteJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadMemory reads a source from a JSON file written by Save
+func LoadMemory(path string) (*Memory, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := NewMemory()
+	if err := m.ReadJSON(f); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Recorder passes requests through to another source and keeps every bar it returns, so a
+// session against a live server can be saved and replayed offline
+type Recorder struct {
+	source   MarketDataSource
+	recorded *Memory
+}
+
+// NewRecorder creates a recorder in front of source
+func NewRecorder(source MarketDataSource) *Recorder {
+	return &Recorder{source: source, recorded: NewMemory()}
+}
+
+// Bars fetches bars from the wrapped source and records them
+func (r *Recorder) Bars(symbol string, period types.Period, from, to time.Time) ([]*types.MarketData, error) {
+	bars, err := r.source.Bars(symbol, period, from, to)
+	if err != nil {
+		return nil, err
+	}
+	r.recorded.Add(symbol, period, bars)
+	return bars, nil
+}
+
+// Symbols lists the symbols of the wrapped source
+func (r *Recorder) Symbols() ([]string, error) {
+	return r.source.Symbols()
+}
+
+// Recorded returns the bars recorded so far; call Save on it to keep them
+func (r *Recorder) Recorded() *Memory {
+	return r.recorded
+}
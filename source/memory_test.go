@@ -0,0 +1,90 @@
+package source
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DTrader-store/formula-go/engine"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// createBars creates n daily bars from 2024-01-02 with close = start, start+1, ...
+func createBars(n int, start float64) []*types.MarketData {
+	bars := make([]*types.MarketData, n)
+	for i := range bars {
+		price := start + float64(i)
+		bars[i] = &types.MarketData{
+			Time:   time.Date(2024, 1, 2+i, 15, 0, 0, 0, time.UTC),
+			Open:   price,
+			High:   price,
+			Low:    price,
+			Close:  price,
+			Volume: 100,
+		}
+	}
+	return bars
+}
+
+func TestMemorySource(t *testing.T) {
+	m := NewMemory()
+	bars := createBars(5, 10)
+	m.Add("SZ000001", types.PeriodDay, bars[:3])
+	m.Add("sz000001", types.PeriodDay, bars[2:])
+
+	all, err := m.Bars("sz000001", types.PeriodDay, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Bars error: %v", err)
+	}
+	if len(all) != 5 || all[4].Close != 14 {
+		t.Errorf("Expected 5 merged bars, got %d", len(all))
+	}
+
+	ranged, _ := m.Bars("sz000001", types.PeriodDay, bars[1].Time, bars[2].Time)
+	if len(ranged) != 2 || ranged[0].Close != 11 {
+		t.Errorf("Unexpected range: %+v", ranged)
+	}
+	if _, err := m.Bars("sz000001", types.PeriodWeek, time.Time{}, time.Time{}); err == nil {
+		t.Error("Expected error for a period without bars")
+	}
+
+	symbols, _ := m.Symbols()
+	if len(symbols) != 1 || symbols[0] != "sz000001" {
+		t.Errorf("Unexpected symbols: %v", symbols)
+	}
+}
+
+func TestRecorderReplay(t *testing.T) {
+	live := NewMemory()
+	live.Add("sh000001", types.PeriodDay, createBars(5, 100))
+	live.Add("sz000001", types.PeriodDay, createBars(5, 10))
+
+	recorder := NewRecorder(live)
+	if _, err := recorder.Bars("sh000001", types.PeriodDay, time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("Bars error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := recorder.Recorded().WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON error: %v", err)
+	}
+	replay := NewMemory()
+	if err := replay.ReadJSON(&buf); err != nil {
+		t.Fatalf("ReadJSON error: %v", err)
+	}
+
+	if symbols, _ := replay.Symbols(); len(symbols) != 1 || symbols[0] != "sh000001" {
+		t.Errorf("Expected only the requested symbol to be recorded, got %v", symbols)
+	}
+
+	e := engine.NewFormulaEngine()
+	e.SetDataProvider(NewProvider(replay, types.PeriodDay))
+	result, err := e.Run("R := C / INDEXC", createBars(5, 10))
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if got := result.Outputs[0].Data[4]; math.Abs(got-14.0/104) > 1e-12 {
+		t.Errorf("Expected 14/104, got %v", got)
+	}
+}
@@ -0,0 +1,47 @@
+// Package source fetches market data from quote services behind one interface, so examples,
+// screening and tests can swap a live server for a recording
+package source
+
+import (
+	"time"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// MarketDataSource loads bars and lists the symbols a service knows. Symbols are market-prefixed
+// codes such as "sz000001" and are matched case-insensitively.
+type MarketDataSource interface {
+	// Bars returns the bars of a symbol within [from, to] in ascending time order.
+	// A zero from or to leaves that end of the range open.
+	Bars(symbol string, period types.Period, from, to time.Time) ([]*types.MarketData, error)
+	// Symbols lists the tradable symbols
+	Symbols() ([]string, error)
+}
+
+// Provider serves one period of a source as the data provider of other symbols, see
+// FormulaEngine.SetDataProvider
+type Provider struct {
+	source MarketDataSource
+	period types.Period
+}
+
+// NewProvider creates a data provider reading the bars of period from source
+func NewProvider(source MarketDataSource, period types.Period) *Provider {
+	return &Provider{source: source, period: period}
+}
+
+// LoadMarketData returns the bars of code within [from, to]
+func (p *Provider) LoadMarketData(code string, from, to time.Time) ([]*types.MarketData, error) {
+	return p.source.Bars(code, p.period, from, to)
+}
+
+// inRange filters bars to [from, to]; a zero bound is open
+func inRange(bars []*types.MarketData, from, to time.Time) []*types.MarketData {
+	result := make([]*types.MarketData, 0, len(bars))
+	for _, bar := range bars {
+		if (from.IsZero() || !bar.Time.Before(from)) && (to.IsZero() || !bar.Time.After(to)) {
+			result = append(result, bar)
+		}
+	}
+	return result
+}
@@ -0,0 +1,159 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/injoyai/tdx"
+	"github.com/injoyai/tdx/protocol"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// tdxKlineTypes maps periods to the kline types of the TDX protocol
+var tdxKlineTypes = map[types.Period]uint8{
+	types.PeriodMin1:    protocol.TypeKlineMinute,
+	types.PeriodMin5:    protocol.TypeKline5Minute,
+	types.PeriodMin15:   protocol.TypeKline15Minute,
+	types.PeriodMin30:   protocol.TypeKline30Minute,
+	types.PeriodMin60:   protocol.TypeKline60Minute,
+	types.PeriodDay:     protocol.TypeKlineDay,
+	types.PeriodWeek:    protocol.TypeKlineWeek,
+	types.PeriodMonth:   protocol.TypeKlineMonth,
+	types.PeriodQuarter: protocol.TypeKlineQuarter,
+	types.PeriodYear:    protocol.TypeKlineYear,
+}
+
+// tdxClient is the part of the TDX client the source uses
+type tdxClient interface {
+	GetKlineUntil(kind uint8, code string, stop func(k *protocol.Kline) bool) (*protocol.KlineResp, error)
+	GetIndexUntil(kind uint8, code string, stop func(k *protocol.Kline) bool) (*protocol.KlineResp, error)
+	GetStockAll() ([]string, error)
+	Close() error
+}
+
+// TDXOptions configures the TDX source
+type TDXOptions struct {
+	Retries    int           // Attempts after a failed request
+	RetryDelay time.Duration // Wait before the first retry, doubled for each further retry
+}
+
+// DefaultTDXOptions retries a failed request twice, after 500ms and 1s
+var DefaultTDXOptions = TDXOptions{Retries: 2, RetryDelay: 500 * time.Millisecond}
+
+// TDX is a source backed by a TDX quote server
+type TDX struct {
+	client tdxClient
+	opts   TDXOptions
+}
+
+// DialTDX connects to the TDX server at addr, or to the first reachable known server when addr is
+// empty. The connection is re-established after a drop. A nil opts uses DefaultTDXOptions.
+func DialTDX(addr string, opts *TDXOptions) (*TDX, error) {
+	var client *tdx.Client
+	var err error
+	if addr == "" {
+		client, err = tdx.DialDefault()
+	} else {
+		client, err = tdx.Dial(addr, tdx.WithRedial())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to TDX server: %w", err)
+	}
+	return newTDX(client, opts), nil
+}
+
+func newTDX(client tdxClient, opts *TDXOptions) *TDX {
+	if opts == nil {
+		opts = &DefaultTDXOptions
+	}
+	return &TDX{client: client, opts: *opts}
+}
+
+// Close closes the connection
+func (s *TDX) Close() error {
+	return s.client.Close()
+}
+
+// Bars fetches the bars of a stock, fund or index. Indexes (sh000xxx, sz399xxx) carry the number
+// of advancing and declining stocks.
+func (s *TDX) Bars(symbol string, period types.Period, from, to time.Time) ([]*types.MarketData, error) {
+	kind, ok := tdxKlineTypes[period]
+	if !ok {
+		return nil, fmt.Errorf("unsupported period %s", period)
+	}
+	symbol = strings.ToLower(symbol)
+	fetch := s.client.GetKlineUntil
+	if isTDXIndex(symbol) {
+		fetch = s.client.GetIndexUntil
+	}
+
+	// Pages are fetched from the latest bar backwards until one is before from
+	stop := func(k *protocol.Kline) bool {
+		return !from.IsZero() && k.Time.Before(from)
+	}
+	var resp *protocol.KlineResp
+	err := s.retry(func() error {
+		var err error
+		resp, err = fetch(kind, symbol, stop)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch %s bars of %s: %w", period, symbol, err)
+	}
+
+	bars := make([]*types.MarketData, len(resp.List))
+	for i, kline := range resp.List {
+		bars[i] = klineToMarketData(kline)
+	}
+	return inRange(bars, from, to), nil
+}
+
+// Symbols lists the stocks of the Shanghai, Shenzhen and Beijing exchanges
+func (s *TDX) Symbols() ([]string, error) {
+	var symbols []string
+	err := s.retry(func() error {
+		var err error
+		symbols, err = s.client.GetStockAll()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list symbols: %w", err)
+	}
+	return symbols, nil
+}
+
+// retry calls fn until it succeeds or the retries are used up, returning the last error
+func (s *TDX) retry(fn func() error) error {
+	delay := s.opts.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= s.opts.Retries {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// isTDXIndex reports whether a symbol is an index, which TDX encodes differently from stocks
+func isTDXIndex(symbol string) bool {
+	return strings.HasPrefix(symbol, "sh000") || strings.HasPrefix(symbol, "sh880") ||
+		strings.HasPrefix(symbol, "sh999") || strings.HasPrefix(symbol, "sz399")
+}
+
+// klineToMarketData converts a TDX kline; prices and amount are in thousandths of a yuan
+func klineToMarketData(kline *protocol.Kline) *types.MarketData {
+	return &types.MarketData{
+		Time:    kline.Time,
+		Open:    kline.Open.Float64(),
+		High:    kline.High.Float64(),
+		Low:     kline.Low.Float64(),
+		Close:   kline.Close.Float64(),
+		Volume:  float64(kline.Volume),
+		Amount:  kline.Amount.Float64(),
+		Advance: float64(kline.UpCount),
+		Decline: float64(kline.DownCount),
+	}
+}
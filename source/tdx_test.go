@@ -0,0 +1,118 @@
+package source
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/injoyai/tdx/protocol"
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// fakeTDXClient serves fixed klines, failing the first requests
+type fakeTDXClient struct {
+	klines   []*protocol.Kline
+	failures int
+	calls    int
+	kinds    []uint8
+	index    bool
+}
+
+func (c *fakeTDXClient) fetch(kind uint8, stop func(k *protocol.Kline) bool) (*protocol.KlineResp, error) {
+	c.calls++
+	c.kinds = append(c.kinds, kind)
+	if c.calls <= c.failures {
+		return nil, errors.New("connection reset")
+	}
+	for i := len(c.klines) - 1; i >= 0; i-- {
+		if stop(c.klines[i]) {
+			return &protocol.KlineResp{List: c.klines[i:]}, nil
+		}
+	}
+	return &protocol.KlineResp{List: c.klines}, nil
+}
+
+func (c *fakeTDXClient) GetKlineUntil(kind uint8, code string, stop func(k *protocol.Kline) bool) (*protocol.KlineResp, error) {
+	return c.fetch(kind, stop)
+}
+
+func (c *fakeTDXClient) GetIndexUntil(kind uint8, code string, stop func(k *protocol.Kline) bool) (*protocol.KlineResp, error) {
+	c.index = true
+	return c.fetch(kind, stop)
+}
+
+func (c *fakeTDXClient) GetStockAll() ([]string, error) {
+	return []string{"sh600000", "sz000001"}, nil
+}
+
+func (c *fakeTDXClient) Close() error {
+	return nil
+}
+
+func createKlines(n int) []*protocol.Kline {
+	klines := make([]*protocol.Kline, n)
+	for i := range klines {
+		price := protocol.Price(10500 + 100*i) // 10.5 yuan and up, in thousandths
+		klines[i] = &protocol.Kline{
+			Time:   time.Date(2024, 1, 2+i, 15, 0, 0, 0, time.UTC),
+			Open:   price,
+			High:   price + 200,
+			Low:    price - 200,
+			Close:  price,
+			Volume: 1000,
+			Amount: 10500000,
+		}
+	}
+	return klines
+}
+
+func TestTDXBars(t *testing.T) {
+	client := &fakeTDXClient{klines: createKlines(5)}
+	s := newTDX(client, nil)
+
+	from := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 5, 23, 0, 0, 0, time.UTC)
+	bars, err := s.Bars("SZ000001", types.PeriodDay, from, to)
+	if err != nil {
+		t.Fatalf("Bars error: %v", err)
+	}
+	if len(bars) != 3 {
+		t.Fatalf("Expected 3 bars, got %d", len(bars))
+	}
+	if bars[0].Open != 10.6 || bars[0].High != 10.8 || bars[0].Amount != 10500 {
+		t.Errorf("Expected prices in yuan, got %+v", bars[0])
+	}
+	if client.index {
+		t.Error("Expected stock request for sz000001")
+	}
+
+	if _, err := s.Bars("sh000001", types.PeriodWeek, time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("Bars error: %v", err)
+	}
+	if !client.index || client.kinds[1] != protocol.TypeKlineWeek {
+		t.Errorf("Expected weekly index request, got index=%v kinds=%v", client.index, client.kinds)
+	}
+
+	if _, err := s.Bars("sz000001", types.Period("TICK"), time.Time{}, time.Time{}); err == nil {
+		t.Error("Expected error for unsupported period")
+	}
+}
+
+func TestTDXRetries(t *testing.T) {
+	client := &fakeTDXClient{klines: createKlines(2), failures: 2}
+	s := newTDX(client, &TDXOptions{Retries: 2, RetryDelay: time.Millisecond})
+	bars, err := s.Bars("sz000001", types.PeriodMin5, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if len(bars) != 2 || client.calls != 3 {
+		t.Errorf("Expected 2 bars after 3 calls, got %d bars after %d calls", len(bars), client.calls)
+	}
+
+	client = &fakeTDXClient{klines: createKlines(2), failures: 3}
+	s = newTDX(client, &TDXOptions{Retries: 2, RetryDelay: time.Millisecond})
+	if _, err := s.Bars("sz000001", types.PeriodMin1, time.Time{}, time.Time{}); err == nil {
+		t.Error("Expected error when retries are used up")
+	}
+}
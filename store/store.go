@@ -0,0 +1,365 @@
+// Package store persists market data bars in a SQLite database for offline screening and backtests
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	_ "github.com/glebarez/go-sqlite" // Pure Go SQLite driver, the one the tdx library registers
+
+	"github.com/DTrader-store/formula-go/types"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS bars (
+	symbol        TEXT    NOT NULL,
+	period        TEXT    NOT NULL,
+	time          INTEGER NOT NULL,
+	open          REAL    NOT NULL,
+	high          REAL    NOT NULL,
+	low           REAL    NOT NULL,
+	close         REAL    NOT NULL,
+	volume        REAL    NOT NULL,
+	amount        REAL    NOT NULL,
+	open_interest REAL    NOT NULL,
+	settle        REAL    NOT NULL,
+	advance       REAL    NOT NULL DEFAULT 0,
+	decline       REAL    NOT NULL DEFAULT 0,
+	extra         TEXT,
+	zone          TEXT    NOT NULL DEFAULT '',
+	utc_offset    INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (symbol, period, time)
+) WITHOUT ROWID`
+
+// addedColumns are the columns added after the first schema, created on databases that lack them
+var addedColumns = []struct{ name, definition string }{
+	{"advance", "REAL NOT NULL DEFAULT 0"},
+	{"decline", "REAL NOT NULL DEFAULT 0"},
+	{"extra", "TEXT"},
+	{"zone", "TEXT NOT NULL DEFAULT ''"},
+	{"utc_offset", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+const upsert = `
+INSERT INTO bars (symbol, period, time, open, high, low, close, volume, amount, open_interest, settle,
+	advance, decline, extra, zone, utc_offset)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (symbol, period, time) DO UPDATE SET
+	open = excluded.open, high = excluded.high, low = excluded.low, close = excluded.close,
+	volume = excluded.volume, amount = excluded.amount,
+	open_interest = excluded.open_interest, settle = excluded.settle,
+	advance = excluded.advance, decline = excluded.decline, extra = excluded.extra,
+	zone = excluded.zone, utc_offset = excluded.utc_offset`
+
+const columns = "time, open, high, low, close, volume, amount, open_interest, settle, advance, decline, extra, zone, utc_offset"
+
+// Store keeps bars per symbol and period in SQLite. Symbols are stored upper-cased and bar times
+// with millisecond precision; loaded bars carry times in the zone they were saved in, and extra
+// columns are kept as JSON. A Store is safe for concurrent use.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens or creates the database at path; ":memory:" gives a private in-memory database
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("store: %w", err)
+	}
+	if path == ":memory:" {
+		// Every connection would get its own empty database
+		db.SetMaxOpenConns(1)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("store: create schema: %w", err)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("store: migrate schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// migrate adds the columns a database created by an earlier version lacks
+func migrate(db *sql.DB) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info('bars')")
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range addedColumns {
+		if existing[column.name] {
+			continue
+		}
+		if _, err := db.Exec("ALTER TABLE bars ADD COLUMN " + column.name + " " + column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save inserts the bars of a symbol in one transaction. A bar at a time already stored replaces the
+// old one, so re-saving the latest, still forming bar updates it in place. Every bar must carry a Time.
+func (s *Store) Save(symbol string, period types.Period, bars []*types.MarketData) error {
+	for i, bar := range bars {
+		if bar.Time.IsZero() {
+			return fmt.Errorf("store: bar %d has no time", i)
+		}
+		if err := bar.Validate(); err != nil {
+			return fmt.Errorf("store: bar %d: %w", i, err)
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(upsert)
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	defer stmt.Close()
+
+	symbol = strings.ToUpper(symbol)
+	for i, bar := range bars {
+		extra, err := encodeExtra(bar.Extra)
+		if err != nil {
+			return fmt.Errorf("store: bar %d: %w", i, err)
+		}
+		_, offset := bar.Time.Zone()
+		_, err = stmt.Exec(symbol, string(period), bar.Time.UnixMilli(),
+			bar.Open, bar.High, bar.Low, bar.Close, bar.Volume, bar.Amount, bar.OpenInterest, bar.Settle,
+			bar.Advance, bar.Decline, extra, bar.Time.Location().String(), offset)
+		if err != nil {
+			return fmt.Errorf("store: save %s %s: %w", symbol, period, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	return nil
+}
+
+// Load returns the bars of a symbol within [from, to] in ascending time order.
+// A zero from or to leaves that end of the range open.
+func (s *Store) Load(symbol string, period types.Period, from, to time.Time) ([]*types.MarketData, error) {
+	query := "SELECT " + columns + " FROM bars WHERE symbol = ? AND period = ?"
+	args := []any{strings.ToUpper(symbol), string(period)}
+	if !from.IsZero() {
+		query += " AND time >= ?"
+		args = append(args, from.UnixMilli())
+	}
+	if !to.IsZero() {
+		query += " AND time <= ?"
+		args = append(args, to.UnixMilli())
+	}
+	return s.query(query+" ORDER BY time", args...)
+}
+
+// Latest returns the last bar of a symbol, or nil when none is stored
+func (s *Store) Latest(symbol string, period types.Period) (*types.MarketData, error) {
+	bars, err := s.query("SELECT "+columns+" FROM bars WHERE symbol = ? AND period = ? ORDER BY time DESC LIMIT 1",
+		strings.ToUpper(symbol), string(period))
+	if err != nil || len(bars) == 0 {
+		return nil, err
+	}
+	return bars[0], nil
+}
+
+// Symbols lists the symbols with bars of the given period, sorted
+func (s *Store) Symbols(period types.Period) ([]string, error) {
+	rows, err := s.db.Query("SELECT DISTINCT symbol FROM bars WHERE period = ? ORDER BY symbol", string(period))
+	if err != nil {
+		return nil, fmt.Errorf("store: %w", err)
+	}
+	defer rows.Close()
+
+	var symbols []string
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			return nil, fmt.Errorf("store: %w", err)
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, rows.Err()
+}
+
+// Delete removes the bars of a symbol within [from, to], see Load for the range
+func (s *Store) Delete(symbol string, period types.Period, from, to time.Time) error {
+	query := "DELETE FROM bars WHERE symbol = ? AND period = ?"
+	args := []any{strings.ToUpper(symbol), string(period)}
+	if !from.IsZero() {
+		query += " AND time >= ?"
+		args = append(args, from.UnixMilli())
+	}
+	if !to.IsZero() {
+		query += " AND time <= ?"
+		args = append(args, to.UnixMilli())
+	}
+	if _, err := s.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) query(query string, args ...any) ([]*types.MarketData, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: %w", err)
+	}
+	defer rows.Close()
+
+	var bars []*types.MarketData
+	zones := make(map[zoneKey]*time.Location)
+	for rows.Next() {
+		var millis int64
+		var extra sql.NullString
+		var zone zoneKey
+		bar := &types.MarketData{}
+		err := rows.Scan(&millis, &bar.Open, &bar.High, &bar.Low, &bar.Close,
+			&bar.Volume, &bar.Amount, &bar.OpenInterest, &bar.Settle,
+			&bar.Advance, &bar.Decline, &extra, &zone.name, &zone.offset)
+		if err != nil {
+			return nil, fmt.Errorf("store: %w", err)
+		}
+		if extra.Valid {
+			if bar.Extra, err = decodeExtra(extra.String); err != nil {
+				return nil, fmt.Errorf("store: extra columns at %d: %w", millis, err)
+			}
+		}
+		loc, ok := zones[zone]
+		if !ok {
+			loc = zone.location(millis)
+			zones[zone] = loc
+		}
+		bar.Time = time.UnixMilli(millis).In(loc)
+		bars = append(bars, bar)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: %w", err)
+	}
+	return bars, nil
+}
+
+// encodeExtra encodes extra columns as a JSON object, with null for NaN; no columns are stored as NULL
+func encodeExtra(extra map[string]float64) (any, error) {
+	if len(extra) == 0 {
+		return nil, nil
+	}
+	values := make(map[string]*float64, len(extra))
+	for name, v := range extra {
+		if math.IsInf(v, 0) {
+			return nil, fmt.Errorf("extra column %s is infinite", name)
+		}
+		values[name] = nil
+		if !math.IsNaN(v) {
+			values[name] = &v
+		}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// decodeExtra decodes the extra columns written by encodeExtra
+func decodeExtra(data string) (map[string]float64, error) {
+	var values map[string]*float64
+	if err := json.Unmarshal([]byte(data), &values); err != nil {
+		return nil, err
+	}
+	extra := make(map[string]float64, len(values))
+	for name, v := range values {
+		if v == nil {
+			extra[name] = math.NaN()
+		} else {
+			extra[name] = *v
+		}
+	}
+	return extra, nil
+}
+
+// zoneKey is the zone a bar time was saved in: the location name and its offset at that time
+type zoneKey struct {
+	name   string
+	offset int
+}
+
+// location returns the saved location when it is known here with the same offset, otherwise a fixed
+// zone with the saved offset. Rows saved before zones were stored have no name and load in time.Local.
+func (z zoneKey) location(millis int64) *time.Location {
+	switch z.name {
+	case "":
+		return time.Local
+	case "UTC":
+		return time.UTC
+	case "Local":
+		if _, offset := time.UnixMilli(millis).Zone(); offset == z.offset {
+			return time.Local
+		}
+	default:
+		if loc, err := time.LoadLocation(z.name); err == nil {
+			if _, offset := time.UnixMilli(millis).In(loc).Zone(); offset == z.offset {
+				return loc
+			}
+		}
+	}
+	return time.FixedZone(z.name, z.offset)
+}
+
+// Provider returns a data provider serving the stored bars of one period, for
+// FormulaEngine.SetDataProvider
+func (s *Store) Provider(period types.Period) *Provider {
+	return &Provider{store: s, period: period}
+}
+
+// Provider serves the bars of one period from a Store
+type Provider struct {
+	store  *Store
+	period types.Period
+}
+
+// LoadMarketData returns the stored bars of code within [from, to]; a symbol without bars is an error
+func (p *Provider) LoadMarketData(code string, from, to time.Time) ([]*types.MarketData, error) {
+	bars, err := p.store.Load(code, p.period, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(bars) == 0 {
+		latest, err := p.store.Latest(code, p.period)
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil {
+			return nil, fmt.Errorf("unknown symbol %s", code)
+		}
+	}
+	return bars, nil
+}
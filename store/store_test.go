@@ -0,0 +1,189 @@
+package store
+
+import (
+	"database/sql"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DTrader-store/formula-go/engine"
+	"github.com/DTrader-store/formula-go/types"
+)
+
+// createBars creates n daily bars from 2024-01-02 with close = start, start+1, ...
+func createBars(n int, start float64) []*types.MarketData {
+	bars := make([]*types.MarketData, n)
+	for i := range bars {
+		price := start + float64(i)
+		bars[i] = &types.MarketData{
+			Time:   time.Date(2024, 1, 2+i, 15, 0, 0, 0, time.Local),
+			Open:   price,
+			High:   price + 0.5,
+			Low:    price - 0.5,
+			Close:  price,
+			Volume: 1000,
+			Amount: price * 1000,
+		}
+	}
+	return bars
+}
+
+func openStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "bars.db"))
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStoreSaveAndLoad(t *testing.T) {
+	s := openStore(t)
+	bars := createBars(5, 10)
+	if err := s.Save("sh600000", types.PeriodDay, bars); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	if err := s.Save("sz000001", types.PeriodDay, createBars(2, 5)); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	loaded, err := s.Load("SH600000", types.PeriodDay, bars[1].Time, bars[3].Time)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if len(loaded) != 3 || loaded[0].Close != 11 || !loaded[2].Time.Equal(bars[3].Time) {
+		t.Errorf("Unexpected range: %+v", loaded)
+	}
+
+	all, err := s.Load("sh600000", types.PeriodDay, time.Time{}, time.Time{})
+	if err != nil || len(all) != 5 {
+		t.Errorf("Expected 5 bars, got %d (%v)", len(all), err)
+	}
+	if weekly, _ := s.Load("sh600000", types.PeriodWeek, time.Time{}, time.Time{}); len(weekly) != 0 {
+		t.Errorf("Expected no weekly bars, got %d", len(weekly))
+	}
+
+	symbols, err := s.Symbols(types.PeriodDay)
+	if err != nil || len(symbols) != 2 || symbols[0] != "SH600000" {
+		t.Errorf("Unexpected symbols %v (%v)", symbols, err)
+	}
+}
+
+func TestStoreUpsertLatest(t *testing.T) {
+	s := openStore(t)
+	bars := createBars(3, 10)
+	if err := s.Save("sh600000", types.PeriodDay, bars); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	update := *bars[2]
+	update.Close, update.High, update.Volume = 13, 13, 2500
+	if err := s.Save("sh600000", types.PeriodDay, []*types.MarketData{&update}); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	latest, err := s.Latest("sh600000", types.PeriodDay)
+	if err != nil {
+		t.Fatalf("Latest error: %v", err)
+	}
+	if latest.Close != 13 || latest.Volume != 2500 {
+		t.Errorf("Expected updated latest bar, got %+v", latest)
+	}
+	if all, _ := s.Load("sh600000", types.PeriodDay, time.Time{}, time.Time{}); len(all) != 3 {
+		t.Errorf("Expected upsert to keep 3 bars, got %d", len(all))
+	}
+
+	if missing, err := s.Latest("sh600001", types.PeriodDay); missing != nil || err != nil {
+		t.Errorf("Expected nil latest bar for unknown symbol, got %+v (%v)", missing, err)
+	}
+	if err := s.Save("sh600000", types.PeriodDay, []*types.MarketData{{Close: 1}}); err == nil {
+		t.Error("Expected error for bar without time")
+	}
+}
+
+func TestStoreProvider(t *testing.T) {
+	s := openStore(t)
+	if err := s.Save("SH000001", types.PeriodDay, createBars(5, 100)); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	e := engine.NewFormulaEngine()
+	e.SetDataProvider(s.Provider(types.PeriodDay))
+	result, err := e.Run("R := C / INDEXC", createBars(5, 10))
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if got := result.Outputs[0].Data[4]; math.Abs(got-14.0/104) > 1e-12 {
+		t.Errorf("Expected 14/104, got %v", got)
+	}
+
+	if _, err := s.Provider(types.PeriodDay).LoadMarketData("SZ399001", time.Time{}, time.Now()); err == nil {
+		t.Error("Expected error for unknown symbol")
+	}
+}
+
+func TestStoreKeepsExtendedFieldsAndZones(t *testing.T) {
+	s := openStore(t)
+	shanghai := time.FixedZone("CST", 8*3600)
+	bars := createBars(2, 10)
+	bars[0].Time = time.Date(2024, 1, 2, 15, 0, 0, 0, shanghai)
+	bars[0].Advance, bars[0].Decline = 1200, 800
+	bars[0].Extra = map[string]float64{"PE": 12.5, "NORTH": math.NaN()}
+	bars[1].Time = time.Date(2024, 1, 3, 7, 0, 0, 0, time.UTC)
+	if err := s.Save("sh000001", types.PeriodDay, bars); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	loaded, err := s.Load("sh000001", types.PeriodDay, time.Time{}, time.Time{})
+	if err != nil || len(loaded) != 2 {
+		t.Fatalf("Expected 2 bars, got %d (%v)", len(loaded), err)
+	}
+	first := loaded[0]
+	if first.Advance != 1200 || first.Decline != 800 || first.Extra["PE"] != 12.5 || !math.IsNaN(first.Extra["NORTH"]) {
+		t.Errorf("Expected extended fields kept, got %+v", first)
+	}
+	if name, offset := first.Time.Zone(); name != "CST" || offset != 8*3600 || first.Time.Hour() != 15 {
+		t.Errorf("Expected the saved zone, got %v", first.Time)
+	}
+	if loaded[1].Time.Location() != time.UTC || loaded[1].Extra != nil {
+		t.Errorf("Expected a UTC time without extra columns, got %v %v", loaded[1].Time, loaded[1].Extra)
+	}
+
+	bars[0].Extra = map[string]float64{"PE": math.Inf(1)}
+	if err := s.Save("sh000001", types.PeriodDay, bars[:1]); err == nil {
+		t.Error("Expected error for an infinite extra column")
+	}
+}
+
+func TestStoreMigratesOldSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE bars (
+		symbol TEXT NOT NULL, period TEXT NOT NULL, time INTEGER NOT NULL,
+		open REAL NOT NULL, high REAL NOT NULL, low REAL NOT NULL, close REAL NOT NULL,
+		volume REAL NOT NULL, amount REAL NOT NULL, open_interest REAL NOT NULL, settle REAL NOT NULL,
+		PRIMARY KEY (symbol, period, time)) WITHOUT ROWID;
+		INSERT INTO bars VALUES ('SH600000', 'DAY', 1704178800000, 1, 1, 1, 1, 1, 1, 0, 0)`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer s.Close()
+	loaded, err := s.Load("sh600000", types.PeriodDay, time.Time{}, time.Time{})
+	if err != nil || len(loaded) != 1 || loaded[0].Time.Location() != time.Local {
+		t.Fatalf("Expected the old bar in time.Local, got %v (%v)", loaded, err)
+	}
+	if err := s.Save("sh600000", types.PeriodDay, createBars(1, 10)); err != nil {
+		t.Errorf("Expected saving into the migrated table to work, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// CorporateAction is an ex-rights/ex-dividend event (除权除息) of a stock
+type CorporateAction struct {
+	Date        time.Time // Ex-date; bars on or after this date trade ex-rights
+	Cash        float64   // Cash dividend per share (派息, 元/股)
+	Bonus       float64   // Bonus and converted shares per share (送股+转增, 股/股)
+	Rights      float64   // Rights shares offered per share (配股, 股/股)
+	RightsPrice float64   // Rights issue price (配股价, 元)
+}
+
+// ExRightsPrice returns the reference price after the action given the close before the ex-date
+func (a CorporateAction) ExRightsPrice(prevClose float64) float64 {
+	return (prevClose - a.Cash + a.RightsPrice*a.Rights) / (1 + a.Bonus + a.Rights)
+}
+
+// Adjustment selects how prices are adjusted for corporate actions
+type Adjustment int
+
+const (
+	// AdjustNone keeps raw prices
+	AdjustNone Adjustment = iota
+	// AdjustForward (前复权) keeps the latest prices and scales earlier bars down
+	AdjustForward
+	// AdjustBackward (后复权) keeps the earliest prices and scales later bars up
+	AdjustBackward
+)
+
+// ShareRatio returns the number of shares one share becomes after the action
+func (a CorporateAction) ShareRatio() float64 {
+	return 1 + a.Bonus + a.Rights
+}
+
+// AdjustFactors returns, for each bar, the factor its prices are multiplied by under mode.
+// Actions dated before the second bar or after the last bar cannot be priced from the data and are ignored.
+func AdjustFactors(data []*MarketData, actions []CorporateAction, mode Adjustment) ([]float64, error) {
+	factors, _, err := adjustFactors(data, actions, mode)
+	return factors, err
+}
+
+// adjustFactors returns the price factors of AdjustFactors and, for each bar, the factor its volume is
+// multiplied by so that volumes before and after a split count the same shares
+func adjustFactors(data []*MarketData, actions []CorporateAction, mode Adjustment) ([]float64, []float64, error) {
+	factors := make([]float64, len(data))
+	volumes := make([]float64, len(data))
+	for i := range factors {
+		factors[i], volumes[i] = 1, 1
+	}
+	switch mode {
+	case AdjustNone:
+		return factors, volumes, nil
+	case AdjustForward, AdjustBackward:
+	default:
+		return nil, nil, fmt.Errorf("unknown adjustment mode %d", mode)
+	}
+
+	for _, action := range actions {
+		k := 0
+		for k < len(data) && data[k].Time.Before(action.Date) {
+			if data[k].Time.IsZero() {
+				return nil, nil, fmt.Errorf("bar %d has no time, cannot adjust prices", k)
+			}
+			k++
+		}
+		if k == 0 || k == len(data) {
+			continue
+		}
+
+		prevClose := data[k-1].Close
+		ratio := action.ExRightsPrice(prevClose) / prevClose
+		if ratio <= 0 || math.IsNaN(ratio) || math.IsInf(ratio, 0) {
+			return nil, nil, fmt.Errorf("corporate action on %s gives invalid ex-rights price for close %f",
+				action.Date.Format("2006-01-02"), prevClose)
+		}
+
+		shares := action.ShareRatio()
+		if mode == AdjustForward {
+			for i := 0; i < k; i++ {
+				factors[i] *= ratio
+				volumes[i] *= shares
+			}
+		} else {
+			for i := k; i < len(data); i++ {
+				factors[i] /= ratio
+				volumes[i] /= shares
+			}
+		}
+	}
+	return factors, volumes, nil
+}
+
+// Adjust returns copies of the bars with open, high, low and close adjusted for corporate actions.
+// Volume is scaled by the bonus and rights shares so it stays comparable across splits; amount is
+// left unchanged. Bars must carry times in ascending order.
+func Adjust(data []*MarketData, actions []CorporateAction, mode Adjustment) ([]*MarketData, error) {
+	factors, volumes, err := adjustFactors(data, actions, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*MarketData, len(data))
+	for i, bar := range data {
+		adjusted := *bar
+		adjusted.Open *= factors[i]
+		adjusted.High *= factors[i]
+		adjusted.Low *= factors[i]
+		adjusted.Close *= factors[i]
+		adjusted.Volume *= volumes[i]
+		result[i] = &adjusted
+	}
+	return result, nil
+}
@@ -0,0 +1,91 @@
+package types
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func adjustTestData() []*MarketData {
+	closes := []float64{10, 10, 6, 6}
+	data := make([]*MarketData, len(closes))
+	for i, c := range closes {
+		data[i] = NewMarketData(c, c, c, c, 100, 100*c)
+		data[i].Time = time.Date(2024, 6, 3+i, 15, 0, 0, 0, time.UTC)
+	}
+	return data
+}
+
+func TestCorporateActionExRightsPrice(t *testing.T) {
+	// 10 送 5 派 2 元, 10 配 2 at 5 元: (10 - 0.2 + 5*0.2) / (1 + 0.5 + 0.2)
+	action := CorporateAction{Cash: 0.2, Bonus: 0.5, Rights: 0.2, RightsPrice: 5}
+	expected := 10.8 / 1.7
+	if got := action.ExRightsPrice(10); math.Abs(got-expected) > 1e-12 {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestAdjust(t *testing.T) {
+	data := adjustTestData()
+	// 10 派 10 元 then 10 转 6: (10 - 1) / 1.6 = 5.625 on the ex-date 2024-06-05
+	actions := []CorporateAction{{Date: time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC), Cash: 1, Bonus: 0.6}}
+	ratio := 5.625 / 10
+
+	forward, err := Adjust(data, actions, AdjustForward)
+	if err != nil {
+		t.Fatalf("Adjust error: %v", err)
+	}
+	if math.Abs(forward[0].Close-10*ratio) > 1e-12 || forward[2].Close != 6 {
+		t.Errorf("Unexpected forward-adjusted closes: %v, %v", forward[0].Close, forward[2].Close)
+	}
+	if forward[0].Volume != 160 || forward[2].Volume != 100 || forward[0].Amount != 1000 || data[0].Close != 10 {
+		t.Errorf("Expected earlier volume scaled by the 10 转 6, amount unchanged and input bars untouched, got volume %v amount %v",
+			forward[0].Volume, forward[0].Amount)
+	}
+
+	backward, err := Adjust(data, actions, AdjustBackward)
+	if err != nil {
+		t.Fatalf("Adjust error: %v", err)
+	}
+	if backward[1].Close != 10 || math.Abs(backward[3].Close-6/ratio) > 1e-12 {
+		t.Errorf("Unexpected backward-adjusted closes: %v, %v", backward[1].Close, backward[3].Close)
+	}
+	if backward[1].Volume != 100 || backward[3].Volume != 100/1.6 {
+		t.Errorf("Expected later volume scaled down by the 10 转 6, got %v, %v", backward[1].Volume, backward[3].Volume)
+	}
+
+	none, _ := Adjust(data, actions, AdjustNone)
+	if none[0].Close != 10 {
+		t.Errorf("Expected raw prices, got %v", none[0].Close)
+	}
+}
+
+func TestAdjustIgnoresActionsOutsideData(t *testing.T) {
+	data := adjustTestData()
+	actions := []CorporateAction{
+		{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Cash: 1},
+		{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Cash: 1},
+	}
+
+	factors, err := AdjustFactors(data, actions, AdjustForward)
+	if err != nil {
+		t.Fatalf("AdjustFactors error: %v", err)
+	}
+	for i, f := range factors {
+		if f != 1 {
+			t.Errorf("Factor %d: expected 1, got %v", i, f)
+		}
+	}
+}
+
+func TestAdjustErrors(t *testing.T) {
+	data := adjustTestData()
+	exDate := time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC)
+
+	if _, err := Adjust(data, []CorporateAction{{Date: exDate, Cash: 20}}, AdjustForward); err == nil {
+		t.Error("Expected error for a dividend larger than the price")
+	}
+	if _, err := Adjust(data, nil, Adjustment(9)); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
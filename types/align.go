@@ -0,0 +1,88 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// FillPolicy decides what an aligned series holds on bars the other symbol has no data for,
+// e.g. suspended trading days
+type FillPolicy int
+
+const (
+	// FillForward treats a missing bar as suspended: prices repeat the previous close, volume and amount are 0
+	FillForward FillPolicy = iota
+	// FillNaN leaves missing bars as NaN
+	FillNaN
+)
+
+// Align maps the bars of another symbol onto the given bar times. Intraday bars are matched by equal
+// time; when no two times fall on the same day, bars are matched by trading date instead, since sources
+// stamp daily bars differently (TDX at the 15:00 close, CSV files at midnight). Times without a match are
+// filled according to policy. Bars before the other symbol's first bar are NaN.
+// Both the times and the bars must be in ascending order, and every bar must carry a Time.
+func Align(times []time.Time, data []*MarketData, policy FillPolicy) (*Series, error) {
+	for i, bar := range data {
+		if bar.Time.IsZero() {
+			return nil, fmt.Errorf("bar %d has no time, cannot align", i)
+		}
+		if i > 0 && bar.Time.Before(data[i-1].Time) {
+			return nil, fmt.Errorf("bar %d is earlier than bar %d, cannot align", i, i-1)
+		}
+	}
+
+	key := func(t time.Time) int64 { return t.UnixNano() }
+	if !intraday(times) {
+		key = PeriodDay.bucket
+	}
+
+	n := len(times)
+	s := NewSeries(n)
+	s.Time = times
+
+	j := 0
+	for i, t := range times {
+		for j < len(data) && key(data[j].Time) <= key(t) {
+			j++
+		}
+
+		var bar *MarketData
+		if j > 0 {
+			bar = data[j-1]
+		}
+		switch {
+		case bar != nil && key(bar.Time) == key(t):
+			s.Open[i], s.Close[i], s.High[i], s.Low[i] = bar.Open, bar.Close, bar.High, bar.Low
+			s.Volume[i], s.Amount[i] = bar.Volume, bar.Amount
+		case bar != nil && policy == FillForward:
+			s.Open[i], s.Close[i], s.High[i], s.Low[i] = bar.Close, bar.Close, bar.Close, bar.Close
+		default:
+			nan := math.NaN()
+			s.Open[i], s.Close[i], s.High[i], s.Low[i] = nan, nan, nan, nan
+			s.Volume[i], s.Amount[i] = nan, nan
+		}
+	}
+	return s, nil
+}
+
+// AlignEnd returns the latest bar time Align matches onto times: the last time for intraday bars, and the
+// end of the last day otherwise, so that a daily bar stamped later in the day than times is still included
+func AlignEnd(times []time.Time) time.Time {
+	last := times[len(times)-1]
+	if intraday(times) {
+		return last
+	}
+	year, month, day := last.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, last.Location()).Add(-time.Nanosecond)
+}
+
+// intraday reports whether two consecutive times fall on the same day
+func intraday(times []time.Time) bool {
+	for i := 1; i < len(times); i++ {
+		if PeriodDay.bucket(times[i-1]) == PeriodDay.bucket(times[i]) {
+			return true
+		}
+	}
+	return false
+}
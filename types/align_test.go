@@ -0,0 +1,80 @@
+package types
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAlign(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 15, 0, 0, 0, time.UTC) }
+	times := []time.Time{day(2), day(3), day(4), day(5)}
+	other := []*MarketData{
+		{Time: day(3), Open: 10, Close: 11, High: 12, Low: 9, Volume: 100, Amount: 1000},
+		{Time: day(5), Open: 11, Close: 13, High: 14, Low: 10, Volume: 200, Amount: 2000},
+	}
+
+	forward, err := Align(times, other, FillForward)
+	if err != nil {
+		t.Fatalf("Align error: %v", err)
+	}
+	if !math.IsNaN(forward.Close[0]) {
+		t.Errorf("Expected NaN before the first bar, got %v", forward.Close[0])
+	}
+	if forward.Close[1] != 11 || forward.Close[3] != 13 {
+		t.Errorf("Expected matched closes 11 and 13, got %v", forward.Close)
+	}
+	if forward.Open[2] != 11 || forward.High[2] != 11 || forward.Volume[2] != 0 {
+		t.Errorf("Expected suspended bar at previous close with no volume, got open %v high %v volume %v",
+			forward.Open[2], forward.High[2], forward.Volume[2])
+	}
+
+	missing, err := Align(times, other, FillNaN)
+	if err != nil {
+		t.Fatalf("Align error: %v", err)
+	}
+	if !math.IsNaN(missing.Close[2]) || !math.IsNaN(missing.Volume[2]) {
+		t.Errorf("Expected NaN for the missing bar, got close %v volume %v", missing.Close[2], missing.Volume[2])
+	}
+
+	if _, err := Align(times, []*MarketData{NewMarketData(1, 1, 1, 1, 1, 1)}, FillForward); err == nil {
+		t.Error("Expected error for bars without time")
+	}
+}
+
+func TestAlignMatchesTradingDate(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	times := []time.Time{
+		time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+	}
+	other := []*MarketData{
+		{Time: time.Date(2024, 1, 2, 15, 0, 0, 0, shanghai), Close: 11},
+		{Time: time.Date(2024, 1, 3, 15, 0, 0, 0, shanghai), Close: 12, Volume: 100},
+	}
+
+	aligned, err := Align(times, other, FillForward)
+	if err != nil {
+		t.Fatalf("Align error: %v", err)
+	}
+	if aligned.Close[0] != 11 || aligned.Close[1] != 12 || aligned.Volume[1] != 100 {
+		t.Errorf("Expected daily bars matched by date, got close %v volume %v", aligned.Close, aligned.Volume)
+	}
+
+	if end := AlignEnd(times); end.Day() != 3 || end.Hour() != 23 {
+		t.Errorf("Expected daily times to end at the end of the last day, got %v", end)
+	}
+
+	// Intraday bars still need equal times
+	minutes := []time.Time{times[0].Add(10 * time.Hour), times[0].Add(11 * time.Hour)}
+	aligned, err = Align(minutes, []*MarketData{{Time: minutes[0].Add(time.Minute), Close: 11}}, FillNaN)
+	if err != nil {
+		t.Fatalf("Align error: %v", err)
+	}
+	if !math.IsNaN(aligned.Close[0]) || !math.IsNaN(aligned.Close[1]) {
+		t.Errorf("Expected no intraday match, got %v", aligned.Close)
+	}
+	if end := AlignEnd(minutes); !end.Equal(minutes[1]) {
+		t.Errorf("Expected intraday times to end at the last time, got %v", end)
+	}
+}
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// attributeNames are the named TDX drawing attributes that may follow an output declaration
+var attributeNames = map[string]bool{
+	"COLORRED":     true,
+	"COLORGREEN":   true,
+	"COLORBLUE":    true,
+	"COLORBLACK":   true,
+	"COLORWHITE":   true,
+	"COLORGRAY":    true,
+	"COLORYELLOW":  true,
+	"COLORCYAN":    true,
+	"COLORMAGENTA": true,
+	"COLORBROWN":   true,
+	"COLORLIGRAY":  true,
+	"COLORLIRED":   true,
+	"COLORLIGREEN": true,
+	"COLORLIBLUE":  true,
+	"DOTLINE":      true,
+	"STICK":        true,
+	"COLORSTICK":   true,
+	"VOLSTICK":     true,
+	"LINESTICK":    true,
+	"CROSSDOT":     true,
+	"CIRCLEDOT":    true,
+	"POINTDOT":     true,
+	"NODRAW":       true,
+}
+
+// IsAttribute reports whether attr (upper case) is a TDX drawing attribute: a named attribute such as
+// COLORRED or DOTLINE, COLOR followed by six hex digits in TDX's BBGGRR order, or LINETHICK0 to LINETHICK9
+func IsAttribute(attr string) bool {
+	if attributeNames[attr] {
+		return true
+	}
+	if hex, ok := strings.CutPrefix(attr, "COLOR"); ok && len(hex) == 6 {
+		_, err := strconv.ParseUint(hex, 16, 32)
+		return err == nil
+	}
+	if width, ok := strings.CutPrefix(attr, "LINETHICK"); ok {
+		return len(width) == 1 && width[0] >= '0' && width[0] <= '9'
+	}
+	return false
+}
+
+// AttributeNames lists the named drawing attributes, sorted, e.g. for editor completion.
+// Hex colors such as COLOR00FFFF are accepted as well but not listed.
+func AttributeNames() []string {
+	names := make([]string, 0, len(attributeNames)+10)
+	for name := range attributeNames {
+		names = append(names, name)
+	}
+	for width := 0; width <= 9; width++ {
+		names = append(names, "LINETHICK"+strconv.Itoa(width))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,23 @@
+package types
+
+import "testing"
+
+func TestIsAttribute(t *testing.T) {
+	for _, attr := range []string{"COLORRED", "LINETHICK2", "DOTLINE", "COLOR0080FF", "COLORSTICK"} {
+		if !IsAttribute(attr) {
+			t.Errorf("Expected %s to be known", attr)
+		}
+	}
+
+	for _, attr := range []string{"COLORPINK", "COLOR12345G", "LINETHICK10", "BOLD", "colorred"} {
+		if IsAttribute(attr) {
+			t.Errorf("Expected %s to be unknown", attr)
+		}
+	}
+
+	for _, name := range AttributeNames() {
+		if !IsAttribute(name) {
+			t.Errorf("Listed attribute %s is not accepted", name)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// TDXDate encodes a date the way TDX's DATE function does: (year-1900)*10000 + month*100 + day,
+// so 2024-01-05 becomes 1240105 and 1999-12-31 becomes 991231
+func TDXDate(t time.Time) float64 {
+	year, month, day := t.Date()
+	return float64((year-1900)*10000 + int(month)*100 + day)
+}
+
+// TDXTime encodes a time of day the way TDX's TIME function does: HHMMSS, e.g. 093500
+func TDXTime(t time.Time) float64 {
+	return float64(t.Hour()*10000 + t.Minute()*100 + t.Second())
+}
+
+// ParseTDXDate decodes a TDX DATE value into midnight of that day in loc
+func ParseTDXDate(value float64, loc *time.Location) (time.Time, error) {
+	v := int(value)
+	if float64(v) != value || v < 101 {
+		return time.Time{}, fmt.Errorf("invalid TDX date: %v", value)
+	}
+
+	year, month, day := v/10000+1900, time.Month(v/100%100), v%100
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if t.Month() != month || t.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid TDX date: %v", value)
+	}
+	return t, nil
+}
+
+// ParseTDXDateTime decodes TDX DATE and TIME values into a time in loc
+func ParseTDXDateTime(date, clock float64, loc *time.Location) (time.Time, error) {
+	day, err := ParseTDXDate(date, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	c := int(clock)
+	hour, minute, second := c/10000, c/100%100, c%100
+	if float64(c) != clock || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, fmt.Errorf("invalid TDX time: %v", clock)
+	}
+	return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second), nil
+}
+
+// DaysBetween returns the number of calendar days from a to b, ignoring the time of day
+func DaysBetween(a, b time.Time) int {
+	ya, ma, da := a.Date()
+	yb, mb, db := b.Date()
+	start := time.Date(ya, ma, da, 0, 0, 0, 0, time.UTC)
+	end := time.Date(yb, mb, db, 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours() / 24)
+}
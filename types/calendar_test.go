@@ -0,0 +1,57 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTDXDateTime(t *testing.T) {
+	tests := []struct {
+		time time.Time
+		date float64
+		hhmm float64
+	}{
+		{time.Date(2024, 1, 5, 9, 35, 0, 0, time.UTC), 1240105, 93500},
+		{time.Date(1999, 12, 31, 15, 0, 0, 0, time.UTC), 991231, 150000},
+		{time.Date(2000, 3, 1, 13, 1, 30, 0, time.UTC), 1000301, 130130},
+	}
+
+	for _, tt := range tests {
+		if got := TDXDate(tt.time); got != tt.date {
+			t.Errorf("TDXDate(%v): expected %v, got %v", tt.time, tt.date, got)
+		}
+		if got := TDXTime(tt.time); got != tt.hhmm {
+			t.Errorf("TDXTime(%v): expected %v, got %v", tt.time, tt.hhmm, got)
+		}
+
+		parsed, err := ParseTDXDateTime(tt.date, tt.hhmm, time.UTC)
+		if err != nil {
+			t.Fatalf("ParseTDXDateTime(%v, %v): %v", tt.date, tt.hhmm, err)
+		}
+		if !parsed.Equal(tt.time) {
+			t.Errorf("ParseTDXDateTime: expected %v, got %v", tt.time, parsed)
+		}
+	}
+}
+
+func TestParseTDXDateInvalid(t *testing.T) {
+	for _, value := range []float64{0, 1240230, 1241301, 1240105.5} {
+		if _, err := ParseTDXDate(value, time.UTC); err == nil {
+			t.Errorf("ParseTDXDate(%v): expected error, got nil", value)
+		}
+	}
+	if _, err := ParseTDXDateTime(1240105, 246000, time.UTC); err == nil {
+		t.Error("Expected error for invalid time")
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	a := time.Date(2024, 2, 28, 23, 0, 0, 0, time.UTC)
+	b := time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC)
+	if days := DaysBetween(a, b); days != 2 {
+		t.Errorf("Expected 2 days, got %d", days)
+	}
+	if days := DaysBetween(b, a); days != -2 {
+		t.Errorf("Expected -2 days, got %d", days)
+	}
+}
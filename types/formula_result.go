@@ -1,23 +1,27 @@
 package types
 
+import "time"
+
 // LineStyle represents line style configuration for output visualization
 type LineStyle struct {
-	Color     string // Color of the line (e.g., '#FF0000', 'red')
-	LineWidth int    // Width of the line in pixels
-	LineStyle string // Style of the line ('solid', 'dashed', 'dotted', etc.)
+	Color     string `json:"color,omitempty"`     // Color of the line (e.g., '#FF0000', 'red')
+	LineWidth int    `json:"lineWidth,omitempty"` // Width of the line in pixels
+	LineStyle string `json:"lineStyle,omitempty"` // Style of the line ('solid', 'dashed', 'dotted', etc.)
 }
 
 // OutputLine represents a single output line representing calculated data
 type OutputLine struct {
-	Name  string      // Name/identifier of the output line
-	Data  []float64   // Data points for the output line
-	Style *LineStyle  // Optional style configuration for visualization
+	Name  string     // Name/identifier of the output line
+	Data  []float64  // Data points for the output line
+	Style *LineStyle // Optional style configuration for visualization
+	Kind  ValueKind  // What the values mean, e.g. KindBool for a signal
 }
 
 // FormulaResult represents the result of formula calculation containing outputs and variables
 type FormulaResult struct {
-	Outputs   []*OutputLine       // Array of output lines from the formula calculation
-	Variables map[string]float64  // Calculated variables and their values
+	Outputs   []*OutputLine      // Array of output lines from the formula calculation
+	Variables map[string]float64 // Calculated variables and their values
+	Times     []time.Time        // Bar times, one per output value; nil when the input had no times
 }
 
 // NewFormulaResult creates a new FormulaResult instance
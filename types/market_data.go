@@ -1,16 +1,27 @@
 // Package types provides common types for market data and formula results
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // MarketData represents a single market data record with OHLCV data
 type MarketData struct {
-	Open   float64 // Opening price
-	Close  float64 // Closing price
-	High   float64 // Highest price in the period
-	Low    float64 // Lowest price in the period
-	Volume float64 // Trading volume (number of shares/units traded)
-	Amount float64 // Trading amount (volume * price, optional)
+	Time   time.Time // Bar time (close time for intraday bars); zero when unknown
+	Open   float64   // Opening price
+	Close  float64   // Closing price
+	High   float64   // Highest price in the period
+	Low    float64   // Lowest price in the period
+	Volume float64   // Trading volume (number of shares/units traded)
+	Amount float64   // Trading amount (volume * price, optional)
+
+	// Optional extended fields
+	OpenInterest float64            // Open interest for futures and options (OPI)
+	Settle       float64            // Settlement price for futures (SETTLE)
+	Advance      float64            // Number of advancing constituents, for indices (ADVANCE)
+	Decline      float64            // Number of declining constituents, for indices (DECLINE)
+	Extra        map[string]float64 // Custom columns, exposed to formulas as variables of the same name
 }
 
 // NewMarketData creates a new MarketData instance
@@ -32,6 +43,7 @@ func NewMarketData(open, close, high, low, volume, amount float64) *MarketData {
 // - open, close, high, low must be numbers
 // - volume must be a non-negative number
 // - amount must be a non-negative number
+// - open interest must be a non-negative number
 // - high must be >= low
 func (m *MarketData) Validate() error {
 	// Validate logical constraints
@@ -49,9 +61,52 @@ func (m *MarketData) Validate() error {
 		return fmt.Errorf("amount must be non-negative, got %f", m.Amount)
 	}
 
+	// Open interest must be non-negative
+	if m.OpenInterest < 0 {
+		return fmt.Errorf("open interest must be non-negative, got %f", m.OpenInterest)
+	}
+
 	return nil
 }
 
+// ValidateSeries validates every bar and the series as a whole:
+// - each bar must pass Validate
+// - timestamps must be set on all bars or on none
+// - when set, timestamps must be strictly increasing
+func ValidateSeries(data []*MarketData) error {
+	for i, bar := range data {
+		if bar == nil {
+			return fmt.Errorf("bar %d is nil", i)
+		}
+		if err := bar.Validate(); err != nil {
+			return fmt.Errorf("bar %d: %w", i, err)
+		}
+		if i == 0 {
+			continue
+		}
+
+		prev := data[i-1]
+		if prev.Time.IsZero() != bar.Time.IsZero() {
+			return fmt.Errorf("bar %d: timestamps must be set on all bars or none", i)
+		}
+		if !bar.Time.IsZero() && !bar.Time.After(prev.Time) {
+			return fmt.Errorf("bar %d: time %s is not after previous bar time %s",
+				i, bar.Time.Format(time.RFC3339), prev.Time.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// HasTime reports whether every bar carries a timestamp
+func HasTime(data []*MarketData) bool {
+	for _, bar := range data {
+		if bar.Time.IsZero() {
+			return false
+		}
+	}
+	return len(data) > 0
+}
+
 // GetMarketDataLength returns the length of a MarketData slice
 func GetMarketDataLength(data []*MarketData) int {
 	return len(data)
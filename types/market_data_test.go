@@ -1,6 +1,9 @@
 package types
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestNewMarketData(t *testing.T) {
 	data := NewMarketData(100.0, 102.0, 105.0, 98.0, 10000, 1000000)
@@ -88,3 +91,67 @@ func TestGetMarketDataLength(t *testing.T) {
 		t.Errorf("Expected length 0, got %d", emptyLength)
 	}
 }
+
+func TestMarketDataValidateOpenInterest(t *testing.T) {
+	data := NewMarketData(100.0, 102.0, 105.0, 98.0, 10000, 1000000)
+	data.OpenInterest = -1
+	if err := data.Validate(); err == nil {
+		t.Error("Expected error for negative open interest")
+	}
+}
+
+func TestValidateSeries(t *testing.T) {
+	at := func(day int, bar *MarketData) *MarketData {
+		bar.Time = time.Date(2024, 1, day, 15, 0, 0, 0, time.UTC)
+		return bar
+	}
+
+	tests := []struct {
+		name    string
+		data    []*MarketData
+		isValid bool
+	}{
+		{
+			name:    "without timestamps",
+			data:    []*MarketData{NewMarketData(1, 2, 3, 1, 10, 10), NewMarketData(2, 3, 4, 2, 10, 10)},
+			isValid: true,
+		},
+		{
+			name:    "increasing timestamps",
+			data:    []*MarketData{at(2, NewMarketData(1, 2, 3, 1, 10, 10)), at(3, NewMarketData(2, 3, 4, 2, 10, 10))},
+			isValid: true,
+		},
+		{
+			name:    "duplicate timestamps",
+			data:    []*MarketData{at(2, NewMarketData(1, 2, 3, 1, 10, 10)), at(2, NewMarketData(2, 3, 4, 2, 10, 10))},
+			isValid: false,
+		},
+		{
+			name:    "decreasing timestamps",
+			data:    []*MarketData{at(3, NewMarketData(1, 2, 3, 1, 10, 10)), at(2, NewMarketData(2, 3, 4, 2, 10, 10))},
+			isValid: false,
+		},
+		{
+			name:    "mixed timestamps",
+			data:    []*MarketData{at(2, NewMarketData(1, 2, 3, 1, 10, 10)), NewMarketData(2, 3, 4, 2, 10, 10)},
+			isValid: false,
+		},
+		{
+			name:    "invalid bar",
+			data:    []*MarketData{NewMarketData(1, 2, 3, 1, 10, 10), NewMarketData(2, 3, 1, 2, 10, 10)},
+			isValid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSeries(tt.data)
+			if tt.isValid && err != nil {
+				t.Errorf("Expected valid, got error: %v", err)
+			}
+			if !tt.isValid && err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Period identifies a bar timeframe
+type Period string
+
+// Supported periods
+const (
+	PeriodMin1    Period = "MIN1"
+	PeriodMin5    Period = "MIN5"
+	PeriodMin15   Period = "MIN15"
+	PeriodMin30   Period = "MIN30"
+	PeriodMin60   Period = "MIN60"
+	PeriodDay     Period = "DAY"
+	PeriodWeek    Period = "WEEK"
+	PeriodMonth   Period = "MONTH"
+	PeriodQuarter Period = "QUARTER"
+	PeriodYear    Period = "YEAR"
+)
+
+// periodAliases maps accepted spellings to periods
+var periodAliases = map[string]Period{
+	"MIN1":    PeriodMin1,
+	"MIN5":    PeriodMin5,
+	"MIN15":   PeriodMin15,
+	"MIN30":   PeriodMin30,
+	"MIN60":   PeriodMin60,
+	"HOUR":    PeriodMin60,
+	"DAY":     PeriodDay,
+	"WEEK":    PeriodWeek,
+	"MONTH":   PeriodMonth,
+	"QUARTER": PeriodQuarter,
+	"SEASON":  PeriodQuarter,
+	"YEAR":    PeriodYear,
+}
+
+// ParsePeriod parses a period name such as "WEEK" or "MIN15" (case-insensitive)
+func ParsePeriod(name string) (Period, error) {
+	period, ok := periodAliases[strings.ToUpper(name)]
+	if !ok {
+		return "", fmt.Errorf("unknown period: %s", name)
+	}
+	return period, nil
+}
+
+// minutes returns the length of an intraday period in minutes, or 0 for daily and longer periods
+func (p Period) minutes() int {
+	switch p {
+	case PeriodMin1:
+		return 1
+	case PeriodMin5:
+		return 5
+	case PeriodMin15:
+		return 15
+	case PeriodMin30:
+		return 30
+	case PeriodMin60:
+		return 60
+	}
+	return 0
+}
+
+// A-share trading sessions in minutes from midnight: 09:30-11:30 and 13:00-15:00
+const (
+	morningOpen    = 9*60 + 30
+	morningClose   = 11*60 + 30
+	afternoonOpen  = 13 * 60
+	morningMinutes = morningClose - morningOpen
+)
+
+// tradingMinute returns the trading minutes from the 09:30 open to minute (counted from midnight).
+// The 11:30-13:00 lunch break does not count, so 13:01 directly follows 11:30.
+func tradingMinute(minute int) int {
+	switch {
+	case minute <= morningClose:
+		return minute - morningOpen
+	case minute <= afternoonOpen:
+		return morningMinutes
+	}
+	return morningMinutes + minute - afternoonOpen
+}
+
+// bucket returns a key identifying the bar of this period that t falls into.
+// Intraday bars are assumed to be stamped with their close time, as TDX does, so a 5-minute
+// bucket covers (09:30, 09:35]. Intraday buckets are counted in trading minutes from the session
+// open, so 60-minute bars close at 10:30, 11:30, 14:00 and 15:00.
+func (p Period) bucket(t time.Time) int64 {
+	year, month, day := t.Date()
+	date := int64(year)*10000 + int64(month)*100 + int64(day)
+
+	if n := p.minutes(); n > 0 {
+		minute := t.Hour()*60 + t.Minute()
+		if t.Second() > 0 || t.Nanosecond() > 0 {
+			minute++
+		}
+		return date*10000 + int64((tradingMinute(minute)-1)/n)
+	}
+
+	switch p {
+	case PeriodWeek:
+		y, w := t.ISOWeek()
+		return int64(y)*100 + int64(w)
+	case PeriodMonth:
+		return int64(year)*100 + int64(month)
+	case PeriodQuarter:
+		return int64(year)*10 + int64((month-1)/3)
+	case PeriodYear:
+		return int64(year)
+	}
+	return date
+}
+
+// periodOrder lists the periods from shortest to longest
+var periodOrder = []Period{
+	PeriodMin1, PeriodMin5, PeriodMin15, PeriodMin30, PeriodMin60,
+	PeriodDay, PeriodWeek, PeriodMonth, PeriodQuarter, PeriodYear,
+}
+
+// rank returns the position of the period in periodOrder
+func (p Period) rank() int {
+	for i, period := range periodOrder {
+		if period == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// basePeriod infers the period of bars from their times: the smallest gap between bars of the same
+// day for intraday data, otherwise the shortest period two consecutive bars share a bar of.
+// It reports false when there are too few bars to tell.
+func basePeriod(data []*MarketData) (Period, bool) {
+	gap := time.Duration(0)
+	for i := 1; i < len(data); i++ {
+		prev, cur := data[i-1].Time, data[i].Time
+		if PeriodDay.bucket(prev) == PeriodDay.bucket(cur) && cur.After(prev) && (gap == 0 || cur.Sub(prev) < gap) {
+			gap = cur.Sub(prev)
+		}
+	}
+	if gap > 0 {
+		base := PeriodMin1
+		for _, period := range periodOrder[:PeriodDay.rank()] {
+			if time.Duration(period.minutes())*time.Minute <= gap {
+				base = period
+			}
+		}
+		return base, true
+	}
+
+	for _, period := range periodOrder[PeriodWeek.rank():] {
+		for i := 1; i < len(data); i++ {
+			if period.bucket(data[i-1].Time) == period.bucket(data[i].Time) {
+				return periodOrder[period.rank()-1], true
+			}
+		}
+	}
+	return "", false
+}
+
+// Resample aggregates bars into a longer period. It returns the aggregated bars and, for each input bar,
+// the index of the aggregated bar it belongs to. Every input bar must carry a Time, in ascending order.
+// Aggregated bars take the first open, the last close, the extreme high and low, the summed volume and
+// amount; time, extended fields and extra columns come from their last input bar. The period must be
+// longer than the period of the bars.
+func Resample(data []*MarketData, period Period) ([]*MarketData, []int, error) {
+	result := make([]*MarketData, 0)
+	index := make([]int, len(data))
+
+	var current *MarketData
+	var currentKey int64
+	for i, bar := range data {
+		if bar.Time.IsZero() {
+			return nil, nil, fmt.Errorf("bar %d has no time, cannot resample to %s", i, period)
+		}
+		if i > 0 && bar.Time.Before(data[i-1].Time) {
+			return nil, nil, fmt.Errorf("bar %d is earlier than bar %d, cannot resample to %s", i, i-1, period)
+		}
+
+		key := period.bucket(bar.Time)
+		if current == nil || key != currentKey {
+			copied := *bar
+			current = &copied
+			currentKey = key
+			result = append(result, current)
+		} else {
+			current.Close = bar.Close
+			if bar.High > current.High {
+				current.High = bar.High
+			}
+			if bar.Low < current.Low {
+				current.Low = bar.Low
+			}
+			current.Volume += bar.Volume
+			current.Amount += bar.Amount
+			current.Time = bar.Time
+			current.OpenInterest = bar.OpenInterest
+			current.Settle = bar.Settle
+			current.Advance = bar.Advance
+			current.Decline = bar.Decline
+			current.Extra = bar.Extra
+		}
+		index[i] = len(result) - 1
+	}
+
+	if base, ok := basePeriod(data); ok && period.rank() <= base.rank() {
+		return nil, nil, fmt.Errorf("cannot resample %s bars to %s, which is not a longer period", base, period)
+	}
+	return result, index, nil
+}
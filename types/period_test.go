@@ -0,0 +1,193 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Period
+		isValid  bool
+	}{
+		{"WEEK", PeriodWeek, true},
+		{"week", PeriodWeek, true},
+		{"MIN15", PeriodMin15, true},
+		{"HOUR", PeriodMin60, true},
+		{"SEASON", PeriodQuarter, true},
+		{"FORTNIGHT", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			period, err := ParsePeriod(tt.input)
+			if tt.isValid && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !tt.isValid && err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if period != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, period)
+			}
+		})
+	}
+}
+
+// dailyBars creates one bar per weekday starting on a Monday, with close = 1, 2, 3, ...
+func dailyBars(n int) []*MarketData {
+	data := make([]*MarketData, 0, n)
+	day := time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC) // Monday
+	for len(data) < n {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			price := float64(len(data) + 1)
+			bar := NewMarketData(price, price, price+1, price-1, 100, 1000)
+			bar.Time = day
+			data = append(data, bar)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return data
+}
+
+func TestResampleWeek(t *testing.T) {
+	data := dailyBars(12) // two full weeks and two days of the third
+
+	weeks, index, err := Resample(data, PeriodWeek)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(weeks) != 3 {
+		t.Fatalf("Expected 3 weekly bars, got %d", len(weeks))
+	}
+
+	first := weeks[0]
+	if first.Open != 1 || first.Close != 5 || first.High != 6 || first.Low != 0 {
+		t.Errorf("Unexpected first week OHLC: %v %v %v %v", first.Open, first.Close, first.High, first.Low)
+	}
+	if first.Volume != 500 || first.Amount != 5000 {
+		t.Errorf("Expected summed volume/amount 500/5000, got %v/%v", first.Volume, first.Amount)
+	}
+	if !first.Time.Equal(data[4].Time) {
+		t.Errorf("Expected week time to be its last bar's time, got %v", first.Time)
+	}
+	if weeks[2].Close != 12 {
+		t.Errorf("Expected partial week close 12, got %v", weeks[2].Close)
+	}
+
+	expectedIndex := []int{0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 2, 2}
+	for i, k := range expectedIndex {
+		if index[i] != k {
+			t.Errorf("Bar %d: expected week %d, got %d", i, k, index[i])
+		}
+	}
+
+	// Input bars must not be modified
+	if data[0].Close != 1 {
+		t.Errorf("Resample modified its input")
+	}
+}
+
+func TestResampleMinutes(t *testing.T) {
+	start := time.Date(2024, 1, 2, 9, 31, 0, 0, time.UTC)
+	data := make([]*MarketData, 10)
+	for i := range data {
+		data[i] = NewMarketData(1, 1, 1, 1, 1, 1)
+		data[i].Time = start.Add(time.Duration(i) * time.Minute) // 09:31 .. 09:40
+	}
+
+	bars, index, err := Resample(data, PeriodMin5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(bars) != 2 {
+		t.Fatalf("Expected 2 bars, got %d", len(bars))
+	}
+	if index[4] != 0 || index[5] != 1 {
+		t.Errorf("Expected 09:35 to close the first bar and 09:36 to open the second, got %v", index)
+	}
+	if bars[0].Volume != 5 {
+		t.Errorf("Expected volume 5, got %v", bars[0].Volume)
+	}
+}
+
+func TestResampleErrors(t *testing.T) {
+	if _, _, err := Resample([]*MarketData{NewMarketData(1, 1, 1, 1, 1, 1)}, PeriodWeek); err == nil {
+		t.Error("Expected error for bars without time")
+	}
+
+	data := dailyBars(2)
+	data[0], data[1] = data[1], data[0]
+	if _, _, err := Resample(data, PeriodWeek); err == nil {
+		t.Error("Expected error for bars out of order")
+	}
+}
+
+// sessionBars creates 5-minute bars for one A-share trading day, stamped with their close times
+// 09:35 .. 11:30 and 13:05 .. 15:00
+func sessionBars() []*MarketData {
+	var data []*MarketData
+	for _, session := range [][2]time.Time{
+		{time.Date(2024, 1, 2, 9, 35, 0, 0, time.UTC), time.Date(2024, 1, 2, 11, 30, 0, 0, time.UTC)},
+		{time.Date(2024, 1, 2, 13, 5, 0, 0, time.UTC), time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)},
+	} {
+		for t := session[0]; !t.After(session[1]); t = t.Add(5 * time.Minute) {
+			bar := NewMarketData(1, 1, 1, 1, 1, 1)
+			bar.Time = t
+			data = append(data, bar)
+		}
+	}
+	return data
+}
+
+func TestResampleMin60FollowsSessions(t *testing.T) {
+	bars, _, err := Resample(sessionBars(), PeriodMin60)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	closes := []string{"10:30", "11:30", "14:00", "15:00"}
+	if len(bars) != len(closes) {
+		t.Fatalf("Expected %d hourly bars, got %d", len(closes), len(bars))
+	}
+	for i, want := range closes {
+		if got := bars[i].Time.Format("15:04"); got != want || bars[i].Volume != 12 {
+			t.Errorf("Bar %d: expected 12 bars closing at %s, got %v closing at %s", i, want, bars[i].Volume, got)
+		}
+	}
+
+	half, _, err := Resample(sessionBars(), PeriodMin30)
+	if err != nil || len(half) != 8 || half[4].Time.Format("15:04") != "13:30" {
+		t.Errorf("Expected 8 half-hour bars with the afternoon starting at 13:30, got %d (%v)", len(half), err)
+	}
+}
+
+func TestResampleRequiresLongerPeriod(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []*MarketData
+		period Period
+		valid  bool
+	}{
+		{"daily to minutes", dailyBars(10), PeriodMin5, false},
+		{"daily to daily", dailyBars(10), PeriodDay, false},
+		{"daily to week", dailyBars(10), PeriodWeek, true},
+		{"5 minutes to 1 minute", sessionBars(), PeriodMin1, false},
+		{"5 minutes to 5 minutes", sessionBars(), PeriodMin5, false},
+		{"5 minutes to 15 minutes", sessionBars(), PeriodMin15, true},
+		{"5 minutes to day", sessionBars(), PeriodDay, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Resample(tt.data, tt.period)
+			if tt.valid && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
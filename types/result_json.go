@@ -0,0 +1,132 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ResultSchemaVersion is the version of the JSON form of FormulaResult. It changes whenever a
+// field is renamed or removed; new optional fields keep the version.
+const ResultSchemaVersion = 1
+
+// resultJSON is the JSON form of FormulaResult:
+//
+//	{
+//	  "version": 1,
+//	  "times": ["2024-01-02T15:00:00+08:00", ...],
+//	  "outputs": [{"name": "MA5", "kind": "float", "data": [null, 10.2, ...], "style": {"color": "red"}}],
+//	  "variables": {"N": 5}
+//	}
+//
+// NaN and infinite values, which JSON cannot hold, are written as null and read back as NaN.
+type resultJSON struct {
+	Version   int                  `json:"version"`
+	Times     []time.Time          `json:"times,omitempty"`
+	Outputs   []outputJSON         `json:"outputs"`
+	Variables map[string]jsonFloat `json:"variables"`
+}
+
+type outputJSON struct {
+	Name  string     `json:"name"`
+	Kind  ValueKind  `json:"kind"`
+	Data  jsonFloats `json:"data"`
+	Style *LineStyle `json:"style,omitempty"`
+}
+
+// MarshalJSON encodes the result in the versioned schema, NaN as null
+func (f *FormulaResult) MarshalJSON() ([]byte, error) {
+	out := resultJSON{
+		Version:   ResultSchemaVersion,
+		Times:     f.Times,
+		Outputs:   make([]outputJSON, len(f.Outputs)),
+		Variables: make(map[string]jsonFloat, len(f.Variables)),
+	}
+	for i, line := range f.Outputs {
+		out.Outputs[i] = outputJSON{Name: line.Name, Kind: line.Kind, Data: line.Data, Style: line.Style}
+	}
+	for name, value := range f.Variables {
+		out.Variables[name] = jsonFloat(value)
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a result written by MarshalJSON, null as NaN. Results of a newer schema
+// version are rejected.
+func (f *FormulaResult) UnmarshalJSON(data []byte) error {
+	var in resultJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.Version < 1 || in.Version > ResultSchemaVersion {
+		return fmt.Errorf("unsupported result schema version %d", in.Version)
+	}
+
+	*f = FormulaResult{
+		Outputs:   make([]*OutputLine, len(in.Outputs)),
+		Variables: make(map[string]float64, len(in.Variables)),
+		Times:     in.Times,
+	}
+	for i, line := range in.Outputs {
+		f.Outputs[i] = &OutputLine{Name: line.Name, Data: line.Data, Style: line.Style, Kind: line.Kind}
+	}
+	for name, value := range in.Variables {
+		f.Variables[name] = float64(value)
+	}
+	return nil
+}
+
+// jsonFloat is a float64 encoded as null when it is NaN or infinite
+type jsonFloat float64
+
+func (v jsonFloat) MarshalJSON() ([]byte, error) {
+	return appendFloat(nil, float64(v)), nil
+}
+
+func (v *jsonFloat) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*v = jsonFloat(math.NaN())
+		return nil
+	}
+	return json.Unmarshal(data, (*float64)(v))
+}
+
+// jsonFloats is a float64 slice encoded with NaN and infinite values as null
+type jsonFloats []float64
+
+func (values jsonFloats) MarshalJSON() ([]byte, error) {
+	if values == nil {
+		return []byte("[]"), nil
+	}
+	buf := make([]byte, 0, 2+len(values)*8)
+	buf = append(buf, '[')
+	for i, v := range values {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendFloat(buf, v)
+	}
+	return append(buf, ']'), nil
+}
+
+func (values *jsonFloats) UnmarshalJSON(data []byte) error {
+	var raw []jsonFloat
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*values = make([]float64, len(raw))
+	for i, v := range raw {
+		(*values)[i] = float64(v)
+	}
+	return nil
+}
+
+func appendFloat(buf []byte, v float64) []byte {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return append(buf, "null"...)
+	}
+	return strconv.AppendFloat(buf, v, 'g', -1, 64)
+}
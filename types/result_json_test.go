@@ -0,0 +1,75 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormulaResultJSON(t *testing.T) {
+	result := NewFormulaResult()
+	result.AddOutput("MA2", []float64{math.NaN(), 1.5, 2.5}, &LineStyle{Color: "red", LineWidth: 2})
+	result.AddOutput("SIGNAL", []float64{0, 1, math.Inf(1)}, nil)
+	result.Outputs[1].Kind = KindBool
+	result.SetVariable("N", 2)
+	result.SetVariable("EMPTY", math.NaN())
+	start := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	result.Times = []time.Time{start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2)}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	text := string(data)
+	for _, want := range []string{
+		`"version":1`,
+		`{"name":"MA2","kind":"float","data":[null,1.5,2.5],"style":{"color":"red","lineWidth":2}}`,
+		`{"name":"SIGNAL","kind":"bool","data":[0,1,null]}`,
+		`"EMPTY":null`,
+		`"times":["2024-01-02T15:00:00Z"`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %s in %s", want, text)
+		}
+	}
+
+	var decoded FormulaResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if len(decoded.Outputs) != 2 || !math.IsNaN(decoded.Outputs[0].Data[0]) || decoded.Outputs[0].Data[2] != 2.5 {
+		t.Errorf("Unexpected outputs: %+v", decoded.Outputs[0])
+	}
+	if decoded.Outputs[0].Kind != KindFloat || decoded.Outputs[1].Kind != KindBool {
+		t.Errorf("Unexpected kinds: %v, %v", decoded.Outputs[0].Kind, decoded.Outputs[1].Kind)
+	}
+	if decoded.Outputs[0].Style.Color != "red" || decoded.Outputs[1].Style != nil {
+		t.Errorf("Unexpected styles: %+v, %+v", decoded.Outputs[0].Style, decoded.Outputs[1].Style)
+	}
+	if decoded.Variables["N"] != 2 || !math.IsNaN(decoded.Variables["EMPTY"]) {
+		t.Errorf("Unexpected variables: %v", decoded.Variables)
+	}
+	if len(decoded.Times) != 3 || !decoded.Times[2].Equal(result.Times[2]) {
+		t.Errorf("Unexpected times: %v", decoded.Times)
+	}
+}
+
+func TestFormulaResultJSONVersion(t *testing.T) {
+	var result FormulaResult
+	if err := json.Unmarshal([]byte(`{"version":2,"outputs":[]}`), &result); err == nil {
+		t.Error("Expected error for newer schema version")
+	}
+	if err := json.Unmarshal([]byte(`{"outputs":[]}`), &result); err == nil {
+		t.Error("Expected error for missing schema version")
+	}
+
+	data, err := json.Marshal(NewFormulaResult())
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	if string(data) != `{"version":1,"outputs":[],"variables":{}}` {
+		t.Errorf("Unexpected empty result: %s", data)
+	}
+}
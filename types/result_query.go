@@ -0,0 +1,146 @@
+package types
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ResultRow holds the values of every output at one bar, in the order of Columns
+type ResultRow struct {
+	Index  int       // Bar index
+	Time   time.Time // Bar time; zero when the result has no times
+	Values []float64 // One value per output; NaN where an output has no value
+}
+
+// Output returns the output line with the given name (case-insensitive), or nil
+func (f *FormulaResult) Output(name string) *OutputLine {
+	for _, out := range f.Outputs {
+		if strings.EqualFold(out.Name, name) {
+			return out
+		}
+	}
+	return nil
+}
+
+// Len returns the number of bars: the number of times, or the length of the longest output
+func (f *FormulaResult) Len() int {
+	if f.Times != nil {
+		return len(f.Times)
+	}
+	n := 0
+	for _, out := range f.Outputs {
+		n = max(n, len(out.Data))
+	}
+	return n
+}
+
+// Last returns the value of an output at the last bar
+func (f *FormulaResult) Last(name string) (float64, bool) {
+	out := f.Output(name)
+	if out == nil || len(out.Data) == 0 {
+		return math.NaN(), false
+	}
+	return out.Data[len(out.Data)-1], true
+}
+
+// At returns the value of an output at the last bar at or before t. It needs the result's times.
+func (f *FormulaResult) At(name string, t time.Time) (float64, bool) {
+	out := f.Output(name)
+	i := f.indexAt(t)
+	if out == nil || i < 0 || i >= len(out.Data) {
+		return math.NaN(), false
+	}
+	return out.Data[i], true
+}
+
+// indexAt returns the index of the last bar at or before t, or -1
+func (f *FormulaResult) indexAt(t time.Time) int {
+	return sort.Search(len(f.Times), func(i int) bool { return f.Times[i].After(t) }) - 1
+}
+
+// SignalIndices returns the bars where an output is set: non-zero and not NaN, e.g. the bars
+// where CROSS(...) fires
+func (f *FormulaResult) SignalIndices(name string) []int {
+	out := f.Output(name)
+	if out == nil {
+		return nil
+	}
+	var indices []int
+	for i, v := range out.Data {
+		if v != 0 && !math.IsNaN(v) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// Slice returns the bars [start, end) as a new result. Output data and times share the backing
+// arrays of f; variables are kept.
+func (f *FormulaResult) Slice(start, end int) *FormulaResult {
+	start = min(max(start, 0), f.Len())
+	end = min(max(end, start), f.Len())
+
+	result := NewFormulaResult()
+	for _, out := range f.Outputs {
+		lo, hi := min(start, len(out.Data)), min(end, len(out.Data))
+		result.AddOutput(out.Name, out.Data[lo:hi], out.Style)
+		result.Outputs[len(result.Outputs)-1].Kind = out.Kind
+	}
+	for name, value := range f.Variables {
+		result.Variables[name] = value
+	}
+	if f.Times != nil {
+		result.Times = f.Times[start:end]
+	}
+	return result
+}
+
+// Window returns the bars with times within [from, to] as a new result, see Slice. A zero from
+// or to leaves that end open. A result without times gives an empty window.
+func (f *FormulaResult) Window(from, to time.Time) *FormulaResult {
+	if f.Times == nil {
+		return f.Slice(0, 0)
+	}
+	start := 0
+	if !from.IsZero() {
+		start = sort.Search(len(f.Times), func(i int) bool { return !f.Times[i].Before(from) })
+	}
+	end := len(f.Times)
+	if !to.IsZero() {
+		end = f.indexAt(to) + 1
+	}
+	return f.Slice(start, end)
+}
+
+// Columns returns the output names in the column order of Rows
+func (f *FormulaResult) Columns() []string {
+	names := make([]string, len(f.Outputs))
+	for i, out := range f.Outputs {
+		names[i] = out.Name
+	}
+	return names
+}
+
+// Rows returns one row per bar joining the values of all outputs
+func (f *FormulaResult) Rows() []ResultRow {
+	n := f.Len()
+	rows := make([]ResultRow, n)
+	values := make([]float64, n*len(f.Outputs))
+	for i := range rows {
+		row := &rows[i]
+		row.Index = i
+		if i < len(f.Times) {
+			row.Time = f.Times[i]
+		}
+		row.Values = values[i*len(f.Outputs) : (i+1)*len(f.Outputs)]
+		for j, out := range f.Outputs {
+			row.Values[j] = math.NaN()
+			if i < len(out.Data) {
+				row.Values[j] = out.Data[i]
+			}
+		}
+	}
+	return rows
+}
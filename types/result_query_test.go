@@ -0,0 +1,92 @@
+package types
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// createQueryResult creates a result over 5 daily bars with a moving average and a signal line
+func createQueryResult() *FormulaResult {
+	result := NewFormulaResult()
+	result.AddOutput("MA2", []float64{math.NaN(), 1.5, 2.5, 3.5, 4.5}, nil)
+	result.AddOutput("Cross", []float64{0, 1, 0, math.NaN(), 1}, nil)
+	result.SetVariable("N", 2)
+	start := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		result.Times = append(result.Times, start.AddDate(0, 0, i))
+	}
+	return result
+}
+
+func TestFormulaResultLookup(t *testing.T) {
+	result := createQueryResult()
+
+	if result.Output("ma2") == nil || result.Output("MISSING") != nil {
+		t.Error("Expected case-insensitive lookup of existing outputs only")
+	}
+	if v, ok := result.Last("MA2"); !ok || v != 4.5 {
+		t.Errorf("Expected last 4.5, got %v %v", v, ok)
+	}
+	if _, ok := result.Last("MISSING"); ok {
+		t.Error("Expected no last value for missing output")
+	}
+
+	// Between bars, At reads the last bar at or before the time
+	if v, ok := result.At("MA2", result.Times[2].Add(time.Hour)); !ok || v != 2.5 {
+		t.Errorf("Expected 2.5, got %v %v", v, ok)
+	}
+	if _, ok := result.At("MA2", result.Times[0].Add(-time.Hour)); ok {
+		t.Error("Expected no value before the first bar")
+	}
+
+	indices := result.SignalIndices("CROSS")
+	if len(indices) != 2 || indices[0] != 1 || indices[1] != 4 {
+		t.Errorf("Expected signals at [1 4], got %v", indices)
+	}
+}
+
+func TestFormulaResultWindow(t *testing.T) {
+	result := createQueryResult()
+
+	window := result.Window(result.Times[2], time.Time{})
+	if window.Len() != 3 || window.Output("MA2").Data[0] != 2.5 || !window.Times[0].Equal(result.Times[2]) {
+		t.Errorf("Unexpected window: %v", window.Output("MA2").Data)
+	}
+	if window.Variables["N"] != 2 {
+		t.Error("Expected variables to be kept")
+	}
+
+	if middle := result.Window(result.Times[1], result.Times[2].Add(time.Hour)); middle.Len() != 2 {
+		t.Errorf("Expected 2 bars, got %d", middle.Len())
+	}
+	if empty := result.Window(result.Times[3], result.Times[1]); empty.Len() != 0 {
+		t.Errorf("Expected empty window, got %d bars", empty.Len())
+	}
+
+	if sliced := result.Slice(3, 10); sliced.Len() != 2 || sliced.Output("Cross").Data[1] != 1 {
+		t.Errorf("Unexpected slice: %v", sliced.Output("Cross").Data)
+	}
+}
+
+func TestFormulaResultRows(t *testing.T) {
+	result := createQueryResult()
+	result.AddOutput("SHORT", []float64{7}, nil)
+
+	columns := result.Columns()
+	if len(columns) != 3 || columns[1] != "Cross" {
+		t.Errorf("Unexpected columns: %v", columns)
+	}
+
+	rows := result.Rows()
+	if len(rows) != 5 {
+		t.Fatalf("Expected 5 rows, got %d", len(rows))
+	}
+	row := rows[1]
+	if row.Index != 1 || !row.Time.Equal(result.Times[1]) || row.Values[0] != 1.5 || row.Values[1] != 1 {
+		t.Errorf("Unexpected row: %+v", row)
+	}
+	if rows[0].Values[2] != 7 || !math.IsNaN(rows[1].Values[2]) {
+		t.Errorf("Expected short output padded with NaN, got %v", rows[1].Values)
+	}
+}
@@ -0,0 +1,173 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Series is market data in columnar form: one slice per field, all of the same length.
+// Formula variables are bound directly to these slices, so executing on a Series does not copy the data.
+// Time and the extended columns are optional and may be nil.
+type Series struct {
+	Time   []time.Time // Bar times; nil when unknown
+	Open   []float64
+	Close  []float64
+	High   []float64
+	Low    []float64
+	Volume []float64
+	Amount []float64
+
+	// Optional extended columns
+	OpenInterest []float64
+	Settle       []float64
+	Advance      []float64
+	Decline      []float64
+	Extra        map[string][]float64 // Custom columns; NaN marks a missing value
+}
+
+// NewSeries creates a series with n bars and zeroed OHLCV and amount columns
+func NewSeries(n int) *Series {
+	return &Series{
+		Open:   make([]float64, n),
+		Close:  make([]float64, n),
+		High:   make([]float64, n),
+		Low:    make([]float64, n),
+		Volume: make([]float64, n),
+		Amount: make([]float64, n),
+	}
+}
+
+// SeriesFromMarketData converts bars to columnar form. Time is kept when every bar has one and
+// extended columns are filled from the bars; Extra keys missing on a bar become NaN.
+func SeriesFromMarketData(data []*MarketData) *Series {
+	n := len(data)
+	s := NewSeries(n)
+	s.OpenInterest = make([]float64, n)
+	s.Settle = make([]float64, n)
+	s.Advance = make([]float64, n)
+	s.Decline = make([]float64, n)
+	if HasTime(data) {
+		s.Time = make([]time.Time, n)
+	}
+
+	for i, bar := range data {
+		s.Open[i] = bar.Open
+		s.Close[i] = bar.Close
+		s.High[i] = bar.High
+		s.Low[i] = bar.Low
+		s.Volume[i] = bar.Volume
+		s.Amount[i] = bar.Amount
+		s.OpenInterest[i] = bar.OpenInterest
+		s.Settle[i] = bar.Settle
+		s.Advance[i] = bar.Advance
+		s.Decline[i] = bar.Decline
+		if s.Time != nil {
+			s.Time[i] = bar.Time
+		}
+
+		for name, v := range bar.Extra {
+			column, ok := s.Extra[name]
+			if !ok {
+				if s.Extra == nil {
+					s.Extra = make(map[string][]float64)
+				}
+				column = make([]float64, n)
+				for j := range column {
+					column[j] = math.NaN()
+				}
+				s.Extra[name] = column
+			}
+			column[i] = v
+		}
+	}
+	return s
+}
+
+// Len returns the number of bars
+func (s *Series) Len() int {
+	return len(s.Close)
+}
+
+// HasTime reports whether the series carries bar times
+func (s *Series) HasTime() bool {
+	return s.Len() > 0 && len(s.Time) == s.Len()
+}
+
+// Validate checks that every non-nil column has one value per bar
+func (s *Series) Validate() error {
+	n := s.Len()
+	columns := map[string]int{
+		"open":   len(s.Open),
+		"high":   len(s.High),
+		"low":    len(s.Low),
+		"volume": len(s.Volume),
+		"amount": len(s.Amount),
+	}
+	optional := map[string][]float64{
+		"open interest": s.OpenInterest,
+		"settle":        s.Settle,
+		"advance":       s.Advance,
+		"decline":       s.Decline,
+	}
+	for name, values := range s.Extra {
+		optional[name] = values
+	}
+	for name, values := range optional {
+		if values != nil {
+			columns[name] = len(values)
+		}
+	}
+	if s.Time != nil {
+		columns["time"] = len(s.Time)
+	}
+
+	for name, length := range columns {
+		if length != n {
+			return fmt.Errorf("%s column has %d values for %d bars", name, length, n)
+		}
+	}
+	return nil
+}
+
+// MarketData converts the series back to bars. The bars share one backing array.
+func (s *Series) MarketData() []*MarketData {
+	n := s.Len()
+	bars := make([]MarketData, n)
+	result := make([]*MarketData, n)
+	for i := range bars {
+		bar := &bars[i]
+		bar.Open = s.Open[i]
+		bar.Close = s.Close[i]
+		bar.High = s.High[i]
+		bar.Low = s.Low[i]
+		bar.Volume = s.Volume[i]
+		bar.Amount = s.Amount[i]
+		if s.Time != nil {
+			bar.Time = s.Time[i]
+		}
+		if s.OpenInterest != nil {
+			bar.OpenInterest = s.OpenInterest[i]
+		}
+		if s.Settle != nil {
+			bar.Settle = s.Settle[i]
+		}
+		if s.Advance != nil {
+			bar.Advance = s.Advance[i]
+		}
+		if s.Decline != nil {
+			bar.Decline = s.Decline[i]
+		}
+		for name, column := range s.Extra {
+			if math.IsNaN(column[i]) {
+				continue
+			}
+			if bar.Extra == nil {
+				bar.Extra = make(map[string]float64, len(s.Extra))
+			}
+			bar.Extra[name] = column[i]
+		}
+		result[i] = bar
+	}
+	return result
+}
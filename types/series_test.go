@@ -0,0 +1,65 @@
+package types
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSeriesRoundTrip(t *testing.T) {
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	bars := []*MarketData{
+		{Time: start, Open: 10, Close: 11, High: 12, Low: 9, Volume: 100, Amount: 1100, OpenInterest: 7},
+		{Time: start.AddDate(0, 0, 1), Open: 11, Close: 12, High: 13, Low: 10, Volume: 200, Amount: 2400,
+			Extra: map[string]float64{"PE": 15}},
+	}
+
+	series := SeriesFromMarketData(bars)
+	if series.Len() != 2 || !series.HasTime() {
+		t.Fatalf("Expected 2 timed bars, got %d (time %v)", series.Len(), series.HasTime())
+	}
+	if series.Close[1] != 12 || series.OpenInterest[0] != 7 {
+		t.Errorf("Unexpected columns: close %v, open interest %v", series.Close, series.OpenInterest)
+	}
+	if !math.IsNaN(series.Extra["PE"][0]) || series.Extra["PE"][1] != 15 {
+		t.Errorf("Expected PE column [NaN 15], got %v", series.Extra["PE"])
+	}
+
+	back := series.MarketData()
+	for i := range bars {
+		if back[i].Close != bars[i].Close || !back[i].Time.Equal(bars[i].Time) || back[i].OpenInterest != bars[i].OpenInterest {
+			t.Errorf("Bar %d: expected %+v, got %+v", i, bars[i], back[i])
+		}
+	}
+	if back[0].Extra != nil {
+		t.Errorf("Expected no extra values on bar 0, got %v", back[0].Extra)
+	}
+	if back[1].Extra["PE"] != 15 {
+		t.Errorf("Expected PE 15 on bar 1, got %v", back[1].Extra)
+	}
+}
+
+func TestSeriesWithoutTime(t *testing.T) {
+	series := SeriesFromMarketData([]*MarketData{NewMarketData(1, 2, 3, 1, 10, 20)})
+	if series.HasTime() || series.Time != nil {
+		t.Error("Expected no time column for bars without time")
+	}
+}
+
+func TestSeriesValidate(t *testing.T) {
+	series := NewSeries(3)
+	if err := series.Validate(); err != nil {
+		t.Errorf("Expected valid series, got %v", err)
+	}
+
+	series.Settle = make([]float64, 2)
+	if err := series.Validate(); err == nil {
+		t.Error("Expected error for short settle column")
+	}
+
+	series.Settle = nil
+	series.Extra = map[string][]float64{"PE": make([]float64, 4)}
+	if err := series.Validate(); err == nil {
+		t.Error("Expected error for long extra column")
+	}
+}
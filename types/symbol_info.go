@@ -0,0 +1,35 @@
+package types
+
+// SymbolInfo holds per-symbol constants that formulas can use, such as share capital
+type SymbolInfo struct {
+	Code         string  // Symbol code, e.g. "600000"
+	Name         string  // Display name
+	Capital      float64 // Float shares in lots (手), returned by CAPITAL
+	TotalCapital float64 // Total shares in lots (手), returned by TOTALCAPITAL
+
+	Industry string          // Industry name, e.g. "银行"
+	Blocks   []string        // Sector and concept blocks the symbol belongs to
+	Finance  map[int]float64 // Fundamental fields, returned by FINANCE(n)
+	DynaInfo map[int]float64 // Real-time snapshot fields, returned by DYNAINFO(n)
+
+	Actions []CorporateAction // Ex-rights/ex-dividend history, used for price adjustment
+}
+
+// Common FINANCE field numbers
+const (
+	FinanceTotalCapital = 1 // Total shares (万股)
+	FinanceFloatCapital = 7 // Float shares (万股)
+)
+
+// InBlock reports whether the symbol belongs to the named block; the industry counts as a block
+func (s *SymbolInfo) InBlock(name string) bool {
+	if s.Industry == name {
+		return true
+	}
+	for _, block := range s.Blocks {
+		if block == name {
+			return true
+		}
+	}
+	return false
+}
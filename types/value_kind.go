@@ -0,0 +1,57 @@
+package types
+
+import "fmt"
+
+// ValueKind tells what the values of a formula result mean, so screeners can pick out signals
+// and renderers can choose sticks or lines. All values are still stored as float64.
+type ValueKind int
+
+const (
+	// KindFloat is a real number such as a price or an indicator value
+	KindFloat ValueKind = iota
+	// KindBool is a condition: 1 where it holds, 0 where it does not
+	KindBool
+	// KindInt is a whole number such as a bar count
+	KindInt
+	// KindText is a text value such as a block name; it never becomes an output
+	KindText
+)
+
+var valueKindNames = map[ValueKind]string{
+	KindFloat: "float",
+	KindBool:  "bool",
+	KindInt:   "int",
+	KindText:  "text",
+}
+
+// String returns the kind's name: "float", "bool", "int" or "text"
+func (k ValueKind) String() string {
+	if name, ok := valueKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("ValueKind(%d)", int(k))
+}
+
+// MarshalText encodes the kind by name
+func (k ValueKind) MarshalText() ([]byte, error) {
+	if _, ok := valueKindNames[k]; !ok {
+		return nil, fmt.Errorf("unknown value kind %d", int(k))
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a kind name
+func (k *ValueKind) UnmarshalText(text []byte) error {
+	for kind, name := range valueKindNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown value kind %q", text)
+}
+
+// Whole reports whether values of the kind are whole numbers, i.e. bool or int
+func (k ValueKind) Whole() bool {
+	return k == KindBool || k == KindInt
+}
@@ -0,0 +1,23 @@
+package types
+
+import "testing"
+
+func TestValueKindText(t *testing.T) {
+	for _, kind := range []ValueKind{KindFloat, KindBool, KindInt, KindText} {
+		text, err := kind.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%v) error: %v", kind, err)
+		}
+		var decoded ValueKind
+		if err := decoded.UnmarshalText(text); err != nil || decoded != kind {
+			t.Errorf("Round trip of %s gave %v, %v", text, decoded, err)
+		}
+	}
+	var kind ValueKind
+	if err := kind.UnmarshalText([]byte("complex")); err == nil {
+		t.Error("Expected error for unknown kind name")
+	}
+	if !KindBool.Whole() || !KindInt.Whole() || KindFloat.Whole() {
+		t.Error("Unexpected Whole results")
+	}
+}